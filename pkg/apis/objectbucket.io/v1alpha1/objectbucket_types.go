@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"sort"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -43,6 +45,90 @@ const (
 	StorageClassBucket = "bucketName"
 )
 
+// Optional storage class parameters understood by the reconciler.
+const (
+	// StorageClassObjectLockMode enables object-lock on new buckets with the given retention mode ("governance" or
+	// "compliance")
+	StorageClassObjectLockMode = "objectLockMode"
+	// StorageClassObjectLockRetentionDays is the default object retention period, in days, of object-lock buckets
+	StorageClassObjectLockRetentionDays = "objectLockRetentionDays"
+	// StorageClassConnectionStringTemplate is a Go text/template rendered against the bucket's Endpoint and
+	// credentials. The result is written to the claim's Secret if the template references credentials, otherwise to
+	// its ConfigMap.
+	StorageClassConnectionStringTemplate = "connectionStringTemplate"
+	// StorageClassConnectionStringKey is the key the rendered connection string is written to. Defaults to
+	// BUCKET_CONNECTION_STRING.
+	StorageClassConnectionStringKey = "connectionStringKey"
+	// StorageClassSharedBucket set to "true" marks the existing bucket named by StorageClassBucket as shared: each
+	// claim is granted access to a unique prefix of its own.
+	StorageClassSharedBucket = "sharedBucket"
+	// StorageClassProviderType selects the schema of the claims' ConfigMaps: "s3" (the default), "azure" or "gcs".
+	StorageClassProviderType = "providerType"
+	// StorageClassLifecycleExpirationDays requests that new buckets expire objects the given number of days after
+	// their creation
+	StorageClassLifecycleExpirationDays = "lifecycleExpirationDays"
+	// StorageClassLifecycleNoncurrentVersionExpirationDays requests that new buckets expire noncurrent object
+	// versions the given number of days after they became noncurrent
+	StorageClassLifecycleNoncurrentVersionExpirationDays = "lifecycleNoncurrentVersionExpirationDays"
+	// StorageClassBucketNameTemplate is a Go text/template naming the new buckets of claims which do not set a
+	// bucketName, in place of a random name. It is rendered against the claim's .Namespace, .Name, .Labels,
+	// .Annotations and .GenerateBucketName, and sanitized to a valid bucket name.
+	StorageClassBucketNameTemplate = "bucketNameTemplate"
+	// StorageClassDefaultBucketNamePrefix is the prefix of the generated names of new buckets for claims which set
+	// neither bucketName nor generateBucketName. It overrides the provisioner's default prefix.
+	StorageClassDefaultBucketNamePrefix = "defaultBucketNamePrefix"
+	// StorageClassAccessKeyIDInConfigMap set to "true" additionally writes the access key ID of claims to their
+	// ConfigMap, under AWS_ACCESS_KEY_ID. The secret access key is only ever written to the Secret.
+	StorageClassAccessKeyIDInConfigMap = "accessKeyIdInConfigMap"
+	// StorageClassCredentialRotationDays is the age in days at which the credentials of bound claims are rotated,
+	// provided the provisioner supports it
+	StorageClassCredentialRotationDays = "credentialRotationDays"
+	// StorageClassSSECustomerKeySecretName and StorageClassSSECustomerKeySecretNamespace name a Secret holding, under
+	// SSECustomerKeyField, a customer-provided (SSE-C) key which new buckets are encrypted with
+	StorageClassSSECustomerKeySecretName      = "sseCustomerKeySecretName"
+	StorageClassSSECustomerKeySecretNamespace = "sseCustomerKeySecretNamespace"
+	// StorageClassRegion is the region written to the BUCKET_REGION of claims whose endpoint has none. A region set by
+	// the provisioner takes precedence.
+	StorageClassRegion = "region"
+	// StorageClassStorageTier is the storage tier new buckets are created in, eg. "hot" or "cold", one of those
+	// advertised by the provisioner
+	StorageClassStorageTier = "storageTier"
+	// StorageClassSkipConfigMap set to "true" generates no ConfigMap for claims: the endpoint keys are written to
+	// their Secret instead, which is then generated even if the provisioner returned no credentials
+	StorageClassSkipConfigMap = "skipConfigMap"
+	// StorageClassCORSAllowedOrigins, StorageClassCORSAllowedMethods and StorageClassCORSAllowedHeaders request that
+	// new buckets be created with a CORS rule allowing the given comma-separated origins, HTTP methods and request
+	// headers. Origins and methods are required, headers are optional.
+	StorageClassCORSAllowedOrigins = "corsAllowedOrigins"
+	StorageClassCORSAllowedMethods = "corsAllowedMethods"
+	StorageClassCORSAllowedHeaders = "corsAllowedHeaders"
+	// StorageClassCORSConfigMapName and StorageClassCORSConfigMapNamespace name a ConfigMap holding the CORS rule of
+	// new buckets instead, under the keys of the CORS parameters above, eg. when it is shared by several classes
+	StorageClassCORSConfigMapName      = "corsConfigMapName"
+	StorageClassCORSConfigMapNamespace = "corsConfigMapNamespace"
+	// StorageClassVirtualHostedStyle set to "true" additionally writes the fully-qualified DNS name of claims' buckets
+	// for virtual-hosted-style access, <bucket>.<host>, to their ConfigMap under BUCKET_FQDN
+	StorageClassVirtualHostedStyle = "virtualHostedStyle"
+	// StorageClassReprovisionMissingBucket set to "true" provisions the bucket of a bound claim again, under the same
+	// name, when a drift check finds it deleted from the object store
+	StorageClassReprovisionMissingBucket = "reprovisionMissingBucket"
+	// StorageClassDrainBeforeDelete set to "true" empties the bucket of a deleted claim, through provisioners
+	// implementing BucketDrainer, before it is deleted. It only applies to the "Delete" reclaim policy.
+	StorageClassDrainBeforeDelete = "drainBeforeDelete"
+	// StorageClassSecretKeyProfile selects the names of the access key keys in claims' Secrets, for the SDK consuming
+	// them: "aws" (the default), "minio" or "generic". See KeyNamingProfile.
+	StorageClassSecretKeyProfile = "secretKeyProfile"
+	// StorageClassSecretKeyPrefix names the access key keys in claims' Secrets <prefix>ACCESS_KEY_ID and
+	// <prefix>SECRET_ACCESS_KEY instead, eg. with "MYAPP_". It cannot be combined with StorageClassSecretKeyProfile.
+	StorageClassSecretKeyPrefix = "secretKeyPrefix"
+	// StorageClassEnvFileKey names a key of claims' ConfigMaps to which all their BUCKET_* keys are additionally
+	// written as an env file, one KEY=value line each, for apps to source, eg. "bucket.env"
+	StorageClassEnvFileKey = "envFileKey"
+)
+
+// SSECustomerKeyField is the key of the SSE-C key material in the Secret named by the StorageClass
+const SSECustomerKeyField = "SSE_CUSTOMER_KEY"
+
 // AccessKeys is an Authentication type for passing AWS S3 style key pairs from the provisioner to the reconciler
 type AccessKeys struct {
 	// AccessKeyId is the S3 style access key to be written to a secret
@@ -78,6 +164,66 @@ func (a *Authentication) ToMap() map[string]string {
 	return map[string]string{}
 }
 
+// KeyNamingProfile names the keys the access keys of an Authentication are written to, so that a Secret holding them
+// matches the expectations of the SDK consuming it. Empty names default to those of KeyNamingAWS.
+type KeyNamingProfile struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+var (
+	// KeyNamingAWS names the keys as the AWS SDKs and CLI expect them. This is the default, used by ToMap.
+	KeyNamingAWS = KeyNamingProfile{AccessKeyID: AwsKeyField, SecretAccessKey: AwsSecretField}
+	// KeyNamingMinio names the keys as the MinIO client expects them
+	KeyNamingMinio = KeyNamingProfile{AccessKeyID: "MINIO_ACCESS_KEY", SecretAccessKey: "MINIO_SECRET_KEY"}
+	// KeyNamingGeneric names the keys in lower case, without reference to a vendor
+	KeyNamingGeneric = KeyNamingProfile{AccessKeyID: "access_key_id", SecretAccessKey: "secret_access_key"}
+)
+
+// KeyNamingWithPrefix returns a KeyNamingProfile naming the keys <prefix>ACCESS_KEY_ID and <prefix>SECRET_ACCESS_KEY.
+func KeyNamingWithPrefix(prefix string) KeyNamingProfile {
+	return KeyNamingProfile{AccessKeyID: prefix + "ACCESS_KEY_ID", SecretAccessKey: prefix + "SECRET_ACCESS_KEY"}
+}
+
+// ToMapWithNaming returns the same data as ToMap() with the access keys named by naming.
+func (a *Authentication) ToMapWithNaming(naming KeyNamingProfile) map[string]string {
+	if a == nil || a.AccessKeys == nil {
+		return a.ToMap()
+	}
+	if naming.AccessKeyID == "" {
+		naming.AccessKeyID = AwsKeyField
+	}
+	if naming.SecretAccessKey == "" {
+		naming.SecretAccessKey = AwsSecretField
+	}
+	return map[string]string{
+		naming.AccessKeyID:     a.AccessKeys.AccessKeyID,
+		naming.SecretAccessKey: a.AccessKeys.SecretAccessKey,
+	}
+}
+
+// KeyValue is a single key/value pair of rendered Authentication data.
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// ToSortedPairs returns the same data as ToMap() as a slice of pairs sorted by key.  Use this instead of ToMap() when
+// rendering the data into a format where ordering matters (ini, json, env files) so that the output is byte-stable.
+func (a *Authentication) ToSortedPairs() []KeyValue {
+	m := a.ToMap()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]KeyValue, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, KeyValue{Key: k, Value: m[k]})
+	}
+	return pairs
+}
+
 // Endpoint contains all connection relevant data that an app may require for accessing
 // the bucket
 type Endpoint struct {
@@ -87,6 +233,22 @@ type Endpoint struct {
 	Region               string            `json:"region"`
 	SubRegion            string            `json:"subRegion"`
 	AdditionalConfigData map[string]string `json:"additionalConfig"`
+	// STSEndpoint is the optional address of the object store's STS (assume-role) service, as a URL or a host[:port].
+	STSEndpoint string `json:"stsEndpoint,omitempty"`
+	// BucketPrefix is the prefix the claim is confined to within a shared bucket
+	BucketPrefix string `json:"bucketPrefix,omitempty"`
+	// StorageAccount is the Azure storage account of the container, for the "azure" provider type
+	StorageAccount string `json:"storageAccount,omitempty"`
+	// Project is the Google Cloud project of the bucket, for the "gcs" provider type
+	Project string `json:"project,omitempty"`
+	// ExternalBucketHost is the optional address of the object store from outside the cluster, eg. an ingress, when
+	// BucketHost is the in-cluster service address
+	ExternalBucketHost string `json:"externalBucketHost,omitempty"`
+	// ExternalBucketPort is the port of ExternalBucketHost, implied by its scheme if 0
+	ExternalBucketPort int `json:"externalBucketPort,omitempty"`
+	// BucketHosts optionally lists the hosts equivalent to BucketHost, in order, for clients which load-balance
+	// across them. BucketHost remains the primary host and is usually listed too.
+	BucketHosts []string `json:"bucketHosts,omitempty"`
 }
 
 // Connection encapsulates Endpoint and Authentication data to simplify the expected return values of the Provision()
@@ -104,7 +266,12 @@ type ObjectBucketSpec struct {
 	StorageClassName string                                `json:"storageClassName"`
 	ReclaimPolicy    *corev1.PersistentVolumeReclaimPolicy `json:"reclaimPolicy"`
 	ClaimRef         *corev1.ObjectReference               `json:"claimRef"`
-	*Connection      `json:",inline"`
+	// RequestedBucketName is the bucketName of the claim when it was provisioned, empty if it requested none
+	RequestedBucketName string `json:"requestedBucketName,omitempty"`
+	// RequestedGenerateBucketName is the generateBucketName prefix of the claim when it was provisioned, empty if it
+	// requested none
+	RequestedGenerateBucketName string `json:"requestedGenerateBucketName,omitempty"`
+	*Connection                 `json:",inline"`
 }
 
 // ObjectBucketStatusPhase is set by the controller to save the state of the provisioning process.
@@ -127,7 +294,17 @@ const (
 
 // ObjectBucketStatus defines the observed state of ObjectBucket
 type ObjectBucketStatus struct {
-	Phase      ObjectBucketStatusPhase `json:"phase"`
+	Phase ObjectBucketStatusPhase `json:"phase"`
+	// BucketName is the name of the bucket within the object store, copied from the Endpoint when the OB is bound
+	BucketName string `json:"bucketName,omitempty"`
+	// Region is the region of the bucket, copied from the Endpoint when the OB is bound
+	Region string `json:"region,omitempty"`
+	// ProviderStatus is free-form state of the bucket kept by the provisioner, eg. backend handles. It is set from the
+	// status of the OB returned by Provision and updated by provisioners implementing ProviderStatusReporter. Updates
+	// are merged key by key, so a provisioner only ever writes the keys it reports.
+	ProviderStatus map[string]string `json:"providerStatus,omitempty"`
+	// BucketCreationTimestamp is when Provision created the bucket, unset for existing buckets
+	BucketCreationTimestamp *metav1.Time `json:"bucketCreationTimestamp,omitempty"`
 }
 
 // +genclient
@@ -139,6 +316,7 @@ type ObjectBucketStatus struct {
 // +kubebuilder:printcolumn:name="ClaimName",type="string",JSONPath=".spec.claimRef.name",description="ClaimName"
 // +kubebuilder:printcolumn:name="ReclaimPolicy",type="string",JSONPath=".spec.reclaimPolicy",description="ReclaimPolicy"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Phase"
+// +kubebuilder:printcolumn:name="Bucket",type="string",JSONPath=".status.bucketName",description="Bucket",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ObjectBucket is the Schema for the objectbuckets API
@@ -81,4 +81,61 @@ func TestAuthentication_ToMap(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestAuthentication_ToSortedPairs(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *Authentication
+		want []KeyValue
+	}{
+		{
+			name: "nil authentication",
+			auth: nil,
+			want: []KeyValue{},
+		}, {
+			name: "with access keys",
+			auth: &Authentication{
+				AccessKeys: &AccessKeys{
+					AccessKeyID:     authKey,
+					SecretAccessKey: authSecret,
+				},
+			},
+			want: []KeyValue{
+				{Key: AwsKeyField, Value: authKey},
+				{Key: AwsSecretField, Value: authSecret},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.auth.ToSortedPairs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authentication.ToSortedPairs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthentication_ToMapWithNaming(t *testing.T) {
+	auth := &Authentication{AccessKeys: &AccessKeys{AccessKeyID: authKey, SecretAccessKey: authSecret}}
+	tests := []struct {
+		name   string
+		naming KeyNamingProfile
+		want   map[string]string
+	}{
+		{"zero value", KeyNamingProfile{}, auth.ToMap()},
+		{"aws", KeyNamingAWS, auth.ToMap()},
+		{"minio", KeyNamingMinio, map[string]string{"MINIO_ACCESS_KEY": authKey, "MINIO_SECRET_KEY": authSecret}},
+		{"prefix", KeyNamingWithPrefix("MYAPP_"), map[string]string{"MYAPP_ACCESS_KEY_ID": authKey, "MYAPP_SECRET_ACCESS_KEY": authSecret}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auth.ToMapWithNaming(tt.naming); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authentication.ToMapWithNaming() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	var none *Authentication
+	if got := none.ToMapWithNaming(KeyNamingMinio); len(got) != 0 {
+		t.Errorf("Authentication.ToMapWithNaming() = %v for nil authentication, want empty", got)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -56,12 +57,26 @@ type ObjectBucketClaimSpec struct {
 }
 
 // ObjectBucketClaimStatusPhase is set by the controller to save the state of the provisioning process.
+//
+// The phases of a claim form the following state machine:
+//
+//	""       -> pending   the claim is picked up by its provisioner
+//	pending  -> binding   the bucket was provisioned (or access granted), its ConfigMap, Secret and OB are being created
+//	binding  -> bound     all binding artifacts exist
+//	pending  -> failed    provisioning cannot succeed without intervention, see the claim's conditions
+//	binding  -> pending   creating the artifacts failed, they are cleaned up and provisioning is retried
+//	binding  -> failed    as pending -> failed
+//
+// A bound claim keeps its phase until it is deleted. ClaimPhaseTransitions lists every valid transition.
 type ObjectBucketClaimStatusPhase string
 
 const (
 	// ObjectBucketClaimStatusPhasePending indicates that the provisioner has begun handling the request and that it is
 	// still in process
 	ObjectBucketClaimStatusPhasePending = "pending"
+	// ObjectBucketClaimStatusPhaseBinding indicates that the bucket has been provisioned and that the configMap, secret
+	// and objectBucket binding it to the claim are being created
+	ObjectBucketClaimStatusPhaseBinding = "binding"
 	// ObjectBucketClaimStatusPhaseBound indicates that provisioning has succeeded, the objectBucket is marked bound, and
 	// there is now a configMap and secret containing the appropriate bucket data in the namespace of the claim
 	ObjectBucketClaimStatusPhaseBound = "bound"
@@ -73,9 +88,41 @@ const (
 	ObjectBucketClaimStatusPhaseFailed = "failed"
 )
 
+// ObjectBucketClaimConditionType is a valid value of ObjectBucketClaimCondition.Type
+type ObjectBucketClaimConditionType string
+
+const (
+	// ObjectBucketClaimProvisioned is true once the claim's bucket is provisioned and bound. When false, the reason and
+	// message of the condition describe what is preventing it.
+	ObjectBucketClaimProvisioned ObjectBucketClaimConditionType = "Provisioned"
+	// ObjectBucketClaimSpecValid is false while the spec of a bound claim has changes which cannot be honored, eg. to
+	// its storage class. Such changes are ignored.
+	ObjectBucketClaimSpecValid ObjectBucketClaimConditionType = "SpecValid"
+	// ObjectBucketClaimConnectionVerified reports the outcome of the last periodic re-verification of a bound claim's
+	// connection. It is only set when re-verification is enabled.
+	ObjectBucketClaimConnectionVerified ObjectBucketClaimConditionType = "ConnectionVerified"
+	// ObjectBucketClaimBucketMissing is true when the periodic drift check of a bound claim found its bucket deleted
+	// from the object store, and not provisioned again. It is only set when drift checks are enabled.
+	ObjectBucketClaimBucketMissing ObjectBucketClaimConditionType = "BucketMissing"
+)
+
+// ObjectBucketClaimCondition describes the state of an ObjectBucketClaim at a certain point.
+type ObjectBucketClaimCondition struct {
+	Type               ObjectBucketClaimConditionType `json:"type"`
+	Status             corev1.ConditionStatus         `json:"status"`
+	LastTransitionTime metav1.Time                    `json:"lastTransitionTime,omitempty"`
+	Reason             string                         `json:"reason,omitempty"`
+	Message            string                         `json:"message,omitempty"`
+	// ObservedGeneration is the generation of the claim the condition was set for, 0 if it does not depend on it
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
 // ObjectBucketClaimStatus defines the observed state of ObjectBucketClaim
 type ObjectBucketClaimStatus struct {
-	Phase ObjectBucketClaimStatusPhase `json:"phase,omitempty"`
+	Phase      ObjectBucketClaimStatusPhase `json:"phase,omitempty"`
+	Conditions []ObjectBucketClaimCondition `json:"conditions,omitempty"`
+	// BucketCreationTimestamp mirrors the BucketCreationTimestamp of the claim's ObjectBucket
+	BucketCreationTimestamp *metav1.Time `json:"bucketCreationTimestamp,omitempty"`
 }
 
 // +genclient
@@ -0,0 +1,67 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// ClaimPhaseTransitions are the valid transitions of the phase of an ObjectBucketClaim, by phase. Transitions to the
+// same phase are always valid and are not listed. A claim with no phase yet is picked up by its provisioner.
+var ClaimPhaseTransitions = map[ObjectBucketClaimStatusPhase][]ObjectBucketClaimStatusPhase{
+	"": {ObjectBucketClaimStatusPhasePending, ObjectBucketClaimStatusPhaseBinding, ObjectBucketClaimStatusPhaseFailed},
+	// a provisioned claim whose connection could not be verified yet is bound from pending once it is
+	ObjectBucketClaimStatusPhasePending: {ObjectBucketClaimStatusPhaseBinding, ObjectBucketClaimStatusPhaseBound,
+		ObjectBucketClaimStatusPhaseFailed},
+	ObjectBucketClaimStatusPhaseBinding: {ObjectBucketClaimStatusPhasePending, ObjectBucketClaimStatusPhaseBound,
+		ObjectBucketClaimStatusPhaseFailed},
+	// a bound claim only fails if its deletion timed out
+	ObjectBucketClaimStatusPhaseBound:  {ObjectBucketClaimStatusPhaseReleased, ObjectBucketClaimStatusPhaseFailed},
+	ObjectBucketClaimStatusPhaseFailed: {ObjectBucketClaimStatusPhasePending, ObjectBucketClaimStatusPhaseBinding},
+}
+
+// BucketPhaseTransitions are the valid transitions of the phase of an ObjectBucket, by phase. Transitions to the same
+// phase are always valid and are not listed.
+var BucketPhaseTransitions = map[ObjectBucketStatusPhase][]ObjectBucketStatusPhase{
+	"":                            {ObjectBucketStatusPhaseBound, ObjectBucketStatusPhaseReleased, ObjectBucketStatusPhaseFailed},
+	ObjectBucketStatusPhaseBound:  {ObjectBucketStatusPhaseReleased, ObjectBucketStatusPhaseFailed},
+	ObjectBucketStatusPhaseFailed: {ObjectBucketStatusPhaseBound, ObjectBucketStatusPhaseReleased},
+	// a retained bucket is bound again when a new claim adopts it
+	ObjectBucketStatusPhaseReleased: {ObjectBucketStatusPhaseBound},
+}
+
+// IsValidClaimTransition returns true if an ObjectBucketClaim may go from phase from to phase to.
+func IsValidClaimTransition(from, to ObjectBucketClaimStatusPhase) bool {
+	if from == to {
+		return true
+	}
+	for _, p := range ClaimPhaseTransitions[from] {
+		if p == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidBucketTransition returns true if an ObjectBucket may go from phase from to phase to.
+func IsValidBucketTransition(from, to ObjectBucketStatusPhase) bool {
+	if from == to {
+		return true
+	}
+	for _, p := range BucketPhaseTransitions[from] {
+		if p == to {
+			return true
+		}
+	}
+	return false
+}
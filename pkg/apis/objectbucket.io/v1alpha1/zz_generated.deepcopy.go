@@ -112,6 +112,11 @@ func (in *Endpoint) DeepCopyInto(out *Endpoint) {
 			(*out)[key] = val
 		}
 	}
+	if in.BucketHosts != nil {
+		in, out := &in.BucketHosts, &out.BucketHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -125,13 +130,29 @@ func (in *Endpoint) DeepCopy() *Endpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeyValue) DeepCopyInto(out *KeyValue) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeyValue.
+func (in *KeyValue) DeepCopy() *KeyValue {
+	if in == nil {
+		return nil
+	}
+	out := new(KeyValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucket) DeepCopyInto(out *ObjectBucket) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -159,7 +180,7 @@ func (in *ObjectBucketClaim) DeepCopyInto(out *ObjectBucketClaim) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -181,6 +202,23 @@ func (in *ObjectBucketClaim) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectBucketClaimCondition) DeepCopyInto(out *ObjectBucketClaimCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectBucketClaimCondition.
+func (in *ObjectBucketClaimCondition) DeepCopy() *ObjectBucketClaimCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectBucketClaimCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketClaimList) DeepCopyInto(out *ObjectBucketClaimList) {
 	*out = *in
@@ -240,6 +278,17 @@ func (in *ObjectBucketClaimSpec) DeepCopy() *ObjectBucketClaimSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketClaimStatus) DeepCopyInto(out *ObjectBucketClaimStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ObjectBucketClaimCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BucketCreationTimestamp != nil {
+		in, out := &in.BucketCreationTimestamp, &out.BucketCreationTimestamp
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -320,6 +369,17 @@ func (in *ObjectBucketSpec) DeepCopy() *ObjectBucketSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectBucketStatus) DeepCopyInto(out *ObjectBucketStatus) {
 	*out = *in
+	if in.ProviderStatus != nil {
+		in, out := &in.ProviderStatus, &out.ProviderStatus
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.BucketCreationTimestamp != nil {
+		in, out := &in.BucketCreationTimestamp, &out.BucketCreationTimestamp
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -0,0 +1,96 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+// Annotations recognized by the reconciler on user-created objects.
+const (
+	// SecretKeyMapAnnotation may be set on an OBC to rename the keys of the generated Secret for that claim only. Its
+	// value is a JSON object mapping default key names (eg. AWS_ACCESS_KEY_ID) to the desired key names.
+	SecretKeyMapAnnotation = Domain + "/secret-key-map"
+	// ReconcilePauseAnnotation may be set to "true" on an OBC to pause its provisioning, eg. during maintenance of the
+	// object store. The claim is re-checked periodically. Deletes are not affected.
+	ReconcilePauseAnnotation = Domain + "/reconcile-pause"
+	// DefaultStorageClassAnnotation may be set to "true" on a single StorageClass to make it the class used by OBCs
+	// which do not name one, analogous to the default StorageClass of PVCs.
+	DefaultStorageClassAnnotation = Domain + "/is-default-class"
+	// AdditionalOwnerAnnotation may be set on an OBC to add an owner, besides the claim, to its generated ConfigMap
+	// and Secret, eg. a higher-level resource which the claim belongs to, so that they are garbage collected with
+	// it. Its value is a JSON object with the owner's "apiVersion", "kind", "name" and "uid". The owner must be in the
+	// claim's namespace, or cluster-scoped, and is never referenced as the controller.
+	AdditionalOwnerAnnotation = Domain + "/additional-owner"
+	// ReclaimPolicyAnnotation may be set on an OBC to "Delete" or "Retain" to select the reclaim policy of its bucket,
+	// overriding its storage class. See DefaultReclaimPolicyAnnotation for the precedence of reclaim policies.
+	ReclaimPolicyAnnotation = Domain + "/reclaim-policy"
+	// DefaultReclaimPolicyAnnotation may be set on a Namespace to "Delete" or "Retain" to select the reclaim policy of
+	// the buckets of its claims when neither the claim nor its storage class sets one. The reclaim policy of a bucket is
+	// the first set of: the claim's ReclaimPolicyAnnotation, the storage class's reclaimPolicy, the namespace's
+	// DefaultReclaimPolicyAnnotation, and "Delete".
+	DefaultReclaimPolicyAnnotation = Domain + "/default-reclaim-policy"
+	// GeneratedBucketNameAnnotation is set by the reconciler on OBCs using generateBucketName to record the generated
+	// name before the bucket is provisioned, so that a retried provision uses the same name.
+	GeneratedBucketNameAnnotation = Domain + "/generated-bucket-name"
+	// GeneratedBucketPrefixAnnotation is set by the reconciler on OBCs of a shared bucket to record the claim's
+	// generated prefix within the bucket.
+	GeneratedBucketPrefixAnnotation = Domain + "/generated-bucket-prefix"
+	// RetainedFromClaimAnnotation is set by the reconciler on the Secret and ConfigMaps it keeps after their claim is
+	// deleted, see ArtifactReclaimRetain. Its value is the "namespace/name" of the deleted claim.
+	RetainedFromClaimAnnotation = Domain + "/retained-from-claim"
+	// IdempotencyKeyAnnotation is set by the reconciler on OBs to record the BucketOptions.IdempotencyKey their bucket
+	// was provisioned with, so that a retried provision of the claim reuses it.
+	IdempotencyKeyAnnotation = Domain + "/idempotency-key"
+	// CredentialsIssuedAnnotation is set by the reconciler on the Secrets of claims whose storage class sets a
+	// credential rotation interval, to the RFC 3339 time their credentials were last rotated. Secrets without it are
+	// as old as the Secret.
+	CredentialsIssuedAnnotation = Domain + "/credentials-issued"
+	// ClaimUIDAnnotation is set by the reconciler on the Secret and ConfigMaps it generates to the UID of their claim, so
+	// that those left over by a deleted claim are told apart from the artifacts of a claim re-created with its name.
+	ClaimUIDAnnotation = Domain + "/claim-uid"
+	// ServiceAccountAnnotation is set by the reconciler on OBCs to the name of the ServiceAccount created for the claim
+	// by the provisioner's ServiceAccountBinder, so that it is released when the claim is deleted.
+	ServiceAccountAnnotation = Domain + "/service-account"
+	// AdoptSecretAnnotation may be set to "true" on a Secret pre-created with the name of a claim's generated Secret,
+	// eg. by external-secrets or a mutating webhook, for the reconciler to adopt it rather than fail the claim. The
+	// generated keys are added to the Secret without overwriting the keys it already holds, and the Secret is then
+	// owned by the claim like a generated one.
+	AdoptSecretAnnotation = Domain + "/adopt"
+	// BoundNotifiedAnnotation is set by the reconciler on Bound OBCs to the RFC 3339 time the provisioner's OnBoundHook
+	// was called for the claim, so that it is not called again.
+	BoundNotifiedAnnotation = Domain + "/bound-notified"
+	// DrainStartedAnnotation is set by the reconciler on the OBs of deleted claims to the RFC 3339 time their bucket
+	// was first drained, see BucketDrainer, so that the drain timeout spans requeues and restarts.
+	DrainStartedAnnotation = Domain + "/drain-started"
+	// AwaitingClaimAnnotation is set by ImportBuckets on the OBs of buckets imported without their claim, whose
+	// claimRef names a claim which does not exist yet. Such OBs are not orphans: they are neither swept nor counted as
+	// orphaned. The annotation is removed once a claim adopts the OB.
+	AwaitingClaimAnnotation = Domain + "/awaiting-claim"
+	// PendingRevocationAnnotation is set by the reconciler on OBs to the access key ID of rotated credentials which the
+	// provisioner failed to revoke, see CredentialRotator. The revocation is retried until it succeeds.
+	PendingRevocationAnnotation = Domain + "/pending-revocation"
+)
+
+// Annotations maintained by the reconciler on claims being provisioned, to help triage slow or failing claims. They are
+// only updated by provisioning attempts whose outcome differs from the last recorded one, bound claims and claims
+// waiting on the provisioner are not written to.
+const (
+	// LastReconcileTimeAnnotation is the RFC 3339 time of the claim's last recorded provisioning attempt
+	LastReconcileTimeAnnotation = Domain + "/last-reconcile-time"
+	// ProvisionAttemptsAnnotation is the number of recorded provisioning attempts made for the claim
+	ProvisionAttemptsAnnotation = Domain + "/provision-attempts"
+	// LastErrorAnnotation is the sanitized error of the claim's last provisioning attempt. It is removed once an
+	// attempt succeeds.
+	LastErrorAnnotation = Domain + "/last-error"
+)
@@ -0,0 +1,84 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditOperation is the kind of mutation described by an AuditRecord
+type AuditOperation string
+
+const (
+	// AuditOperationCreate records the creation of an ObjectBucket, Secret or ConfigMap
+	AuditOperationCreate AuditOperation = "create"
+	// AuditOperationAdopt records the binding of a pre-existing ObjectBucket to a claim
+	AuditOperationAdopt AuditOperation = "adopt"
+	// AuditOperationDelete records the deletion of an ObjectBucket
+	AuditOperationDelete AuditOperation = "delete"
+	// AuditOperationRelease records the removal of the library's finalizer from a Secret, ConfigMap or claim
+	AuditOperationRelease AuditOperation = "release"
+	// AuditOperationRetain records that a Secret or ConfigMap was detached from its deleted claim to be kept
+	AuditOperationRetain AuditOperation = "retain"
+)
+
+// AuditRecord describes a single mutation of an API object made by the library. Failed mutations are recorded too,
+// with Error set.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// Actor is the name of the provisioner on whose behalf the mutation was made
+	Actor     string         `json:"actor"`
+	Operation AuditOperation `json:"operation"`
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace,omitempty"`
+	Name      string         `json:"name"`
+	// Claim is the "namespace/name" of the claim the object belongs to, if known
+	Claim string `json:"claim,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// AuditLogger receives a record of every create, adopt, delete and release made by the library. Audit is called
+// synchronously from the reconcile, after the mutation. An error is logged and does not fail the reconcile.
+type AuditLogger interface {
+	Audit(record AuditRecord) error
+}
+
+// NopAuditLogger discards all records. It is the default AuditLogger.
+type NopAuditLogger struct{}
+
+// Audit implements AuditLogger
+func (NopAuditLogger) Audit(AuditRecord) error { return nil }
+
+type jsonAuditLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditLogger returns an AuditLogger writing each record to w as a single line of JSON. Writes are serialized.
+func NewJSONAuditLogger(w io.Writer) AuditLogger {
+	return &jsonAuditLogger{enc: json.NewEncoder(w)}
+}
+
+// Audit implements AuditLogger
+func (l *jsonAuditLogger) Audit(record AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(record)
+}
@@ -18,6 +18,7 @@ package errors
 
 import (
 	"fmt"
+	"time"
 )
 
 // BucketExistsErr SHOULD be returned by the Provision() method when bucket creation fails due a name collision in the
@@ -42,4 +43,145 @@ func NewBucketExistsError(msg string) *BucketExistsErr {
 func IsBucketExists(e error) (is bool) {
 	_, is = e.(BucketExistsErr)
 	return is
-}
\ No newline at end of file
+}
+
+// PermissionErr is returned by the reconciler when the API server forbids one of its requests, typically because the
+// RBAC granted to the provisioner is narrower than what the reconciler requires. Unlike transient API errors it is not
+// retried: the claim is marked as failed with a condition describing the missing permission.
+type PermissionErr struct {
+	// Verb is the forbidden API verb, eg. "create"
+	Verb string
+	// Resource is the plural resource name, eg. "objectbuckets"
+	Resource string
+	// Namespace is empty for cluster-scoped resources
+	Namespace string
+	// Name is the name of the object
+	Name      string
+	errString string
+}
+
+// Error implements the Error interface
+func (e PermissionErr) Error() string {
+	scope := "cluster-scoped"
+	if e.Namespace != "" {
+		scope = "in namespace " + e.Namespace
+	}
+	return fmt.Sprintf("not permitted to %s %s %q (%s): %s", e.Verb, e.Resource, e.Name, scope, e.errString)
+}
+
+// NewPermissionError is a simple constructor for a PermissionErr
+func NewPermissionError(verb, resource, namespace, name string, err error) *PermissionErr {
+	return &PermissionErr{
+		Verb:      verb,
+		Resource:  resource,
+		Namespace: namespace,
+		Name:      name,
+		errString: fmt.Sprintf("%v", err),
+	}
+}
+
+// IsPermission returns true if the error is of type PermissionErr
+func IsPermission(e error) (is bool) {
+	switch e.(type) {
+	case PermissionErr, *PermissionErr:
+		is = true
+	}
+	return is
+}
+
+// InProgressErr MAY be returned by the Provision() and Grant() methods when the object store accepted the request but
+// completes it asynchronously. Rather than blocking, the provisioner returns this error and the reconciler calls the
+// method again, with the same bucket name, after RetryAfter. The provisioner then returns the completed bucket, or
+// another InProgressErr. Artifacts of the pending request are not cleaned up.
+type InProgressErr struct {
+	// RetryAfter is the suggested delay before the call is repeated. The reconciler's default applies if it is not
+	// positive.
+	RetryAfter time.Duration
+	errString  string
+}
+
+// Error implements the Error interface
+func (e InProgressErr) Error() string {
+	return fmt.Sprintf("%v", e.errString)
+}
+
+// NewInProgressError is a simple constructor for an InProgressErr
+func NewInProgressError(retryAfter time.Duration, msg string) *InProgressErr {
+	return &InProgressErr{
+		RetryAfter: retryAfter,
+		errString:  msg,
+	}
+}
+
+// IsInProgress returns true if the error is of type InProgressErr
+func IsInProgress(e error) bool {
+	_, is := RetryAfter(e)
+	return is
+}
+
+// RetryAfter returns the delay suggested by an InProgressErr, and false if the error is not one
+func RetryAfter(e error) (time.Duration, bool) {
+	switch err := e.(type) {
+	case InProgressErr:
+		return err.RetryAfter, true
+	case *InProgressErr:
+		return err.RetryAfter, true
+	}
+	return 0, false
+}
+
+// TerminalErr MAY be returned by the Provision() and Grant() methods for errors which retrying cannot fix, eg. the
+// object store rejecting the bucket name. The claim is marked Failed at once rather than retried with backoff. It is
+// still retried, and returns to Bound if the error is resolved, eg. by an update of the claim.
+type TerminalErr struct {
+	errString string
+}
+
+// Error implements the Error interface
+func (e TerminalErr) Error() string {
+	return fmt.Sprintf("%v", e.errString)
+}
+
+// NewTerminalError is a simple constructor for a TerminalErr
+func NewTerminalError(err error) *TerminalErr {
+	return &TerminalErr{
+		errString: fmt.Sprintf("%v", err),
+	}
+}
+
+// IsTerminal returns true if the error is of type TerminalErr
+func IsTerminal(e error) (is bool) {
+	switch e.(type) {
+	case TerminalErr, *TerminalErr:
+		is = true
+	}
+	return is
+}
+
+// RetryableErr MAY be returned by the Provision() and Grant() methods for errors which are expected to clear, eg. a
+// backend timeout. The claim is retried with backoff and stays Pending however long the error persists, the failure
+// policy does not apply to it.
+type RetryableErr struct {
+	errString string
+}
+
+// Error implements the Error interface
+func (e RetryableErr) Error() string {
+	return fmt.Sprintf("%v", e.errString)
+}
+
+// NewRetryableError is a simple constructor for a RetryableErr
+func NewRetryableError(err error) *RetryableErr {
+	return &RetryableErr{
+		errString: fmt.Sprintf("%v", err),
+	}
+}
+
+// IsRetryable returns true if the error is of type RetryableErr
+func IsRetryable(e error) (is bool) {
+	switch e.(type) {
+	case RetryableErr, *RetryableErr:
+		is = true
+	}
+	return is
+}
@@ -26,9 +26,13 @@ import (
 // methods used to create and delete new buckets, and to grant or revoke access
 // to buckets within the object store.
 type Provisioner interface {
-	// Provision should be implemented to handle bucket creation
+	// Provision should be implemented to handle bucket creation. Finalizers set on the returned ObjectBucket are kept
+	// on the OB, after the library's own. The library never removes them: once the OB's deletion timestamp is set the
+	// provisioner must run its cleanup and remove them, until then the claim is not released. Object stores which
+	// create buckets asynchronously should return an errors.InProgressErr rather than block until the bucket is ready.
 	Provision(options *BucketOptions) (*v1alpha1.ObjectBucket, error)
-	// Grant should be implemented to handle access to existing buckets
+	// Grant should be implemented to handle access to existing buckets. Finalizers and asynchronous completion are
+	// handled as for Provision.
 	Grant(options *BucketOptions) (*v1alpha1.ObjectBucket, error)
 	// Delete should be implemented to handle bucket deletion
 	Delete(ob *v1alpha1.ObjectBucket) error
@@ -43,8 +47,253 @@ type BucketOptions struct {
 	ReclaimPolicy *corev1.PersistentVolumeReclaimPolicy
 	// BucketName is the name of the bucket within the object store
 	BucketName string
+	// BucketPrefix is the prefix the claim is confined to within a shared bucket, empty unless the storage class
+	// marks the bucket as shared. Grant should restrict the credentials to the prefix, and Revoke should clean up the
+	// prefix's data, found in the OB's Endpoint, but never the bucket.
+	BucketPrefix string
+	// SSECustomerKey is the customer-provided (SSE-C) key which the bucket is encrypted with, read from the Secret
+	// referenced by ProvisionOptions.SSECustomerKeySecret. It is key material: it must never be logged, nor written to
+	// the returned ObjectBucket.
+	SSECustomerKey []byte
+	// IdempotencyKey is stable across retries of the claim's provisioning, eg. after a failure following a successful
+	// Provision. Provisioners of object stores which are not idempotent on the bucket name should pass it along, or
+	// record it with the bucket, so that a retry does not create a second bucket.
+	IdempotencyKey string
 	// ObjectBucketClaim is a copy of the reconciler's OBC
 	ObjectBucketClaim *v1alpha1.ObjectBucketClaim
 	// Parameters is a complete copy of the OBC's storage class Parameters field
 	Parameters map[string]string
-}
\ No newline at end of file
+	// ProvisionOptions are the typed options parsed and validated from Parameters
+	ProvisionOptions
+}
+
+// ProvisionOptions holds the typed bucket options parsed and validated from the OBC's storage class Parameters.
+type ProvisionOptions struct {
+	// ObjectLock, if non-nil, requests that the bucket be created with object-lock (WORM) enabled. Object-lock can
+	// only be enabled at bucket creation.
+	ObjectLock *ObjectLockOptions
+	// ConnectionString, if non-nil, requests that a connection string be rendered into the claim's ConfigMap or Secret
+	ConnectionString *ConnectionStringOptions
+	// SharedBucket is true if the existing bucket named by the storage class is shared by its claims, each confined
+	// to a prefix of its own
+	SharedBucket bool
+	// ProviderType selects the schema of the claim's ConfigMap. Empty is ProviderTypeS3.
+	ProviderType ProviderType
+	// Lifecycle, if non-nil, requests that the bucket be created with the given expiration rules
+	Lifecycle *LifecycleRules
+	// BucketNameTemplate, if non-empty, is the Go text/template naming the new buckets of claims which do not set a
+	// bucket name
+	BucketNameTemplate string
+	// DefaultBucketNamePrefix, if non-empty, prefixes the generated bucket names of claims which set neither a bucket
+	// name nor generateBucketName
+	DefaultBucketNamePrefix string
+	// AccessKeyIDInConfigMap is true if the access key ID is written to the claim's ConfigMap as well as its Secret
+	AccessKeyIDInConfigMap bool
+	// CredentialRotationDays, if non-zero, is the age in days at which the credentials of bound claims are rotated
+	CredentialRotationDays int
+	// SSECustomerKeySecret, if non-nil, references the Secret holding the customer-provided key new buckets are
+	// encrypted with
+	SSECustomerKeySecret *corev1.SecretReference
+	// Region is the region published to claims whose endpoint has none
+	Region string
+	// StorageTier, if non-empty, is the storage tier the bucket is created in, one of Capabilities.StorageTiers
+	StorageTier string
+	// SkipConfigMap is true if no ConfigMap is generated for the claim, its endpoint keys being written to the
+	// claim's Secret instead
+	SkipConfigMap bool
+	// CORS, if non-nil, requests that the bucket be created with the given CORS rule. It is read from the storage class
+	// parameters or, before Provision is called, from the ConfigMap referenced by CORSConfigMap.
+	CORS *CORSConfig
+	// CORSConfigMap, if non-nil, references the ConfigMap holding the CORS rule of new buckets
+	CORSConfigMap *corev1.ObjectReference
+	// VirtualHostedStyle is true if the fully-qualified DNS name of the bucket is written to the claim's ConfigMap
+	VirtualHostedStyle bool
+	// ReprovisionMissingBucket is true if the bucket is provisioned again when a drift check finds it missing
+	ReprovisionMissingBucket bool
+	// DrainBeforeDelete is true if the bucket is emptied with BucketDrainer.DrainBucket before Delete is called
+	DrainBeforeDelete bool
+	// SecretKeyNaming names the access key keys of the claim's Secret, the zero value naming them as the AWS SDKs do
+	SecretKeyNaming v1alpha1.KeyNamingProfile
+	// EnvFileKey, if set, is the key of the claim's ConfigMap to which its BUCKET_* keys are written as an env file
+	EnvFileKey string
+}
+
+// DeepCopy returns a copy of o which shares no memory with it.
+func (o *ProvisionOptions) DeepCopy() *ProvisionOptions {
+	if o == nil {
+		return nil
+	}
+	out := *o
+	if o.ObjectLock != nil {
+		objectLock := *o.ObjectLock
+		out.ObjectLock = &objectLock
+	}
+	if o.ConnectionString != nil {
+		connectionString := *o.ConnectionString
+		out.ConnectionString = &connectionString
+	}
+	if o.Lifecycle != nil {
+		lifecycle := *o.Lifecycle
+		out.Lifecycle = &lifecycle
+	}
+	out.SSECustomerKeySecret = o.SSECustomerKeySecret.DeepCopy()
+	out.CORS = o.CORS.DeepCopy()
+	out.CORSConfigMap = o.CORSConfigMap.DeepCopy()
+	return &out
+}
+
+// ProviderType is the kind of object store, which determines the keys of the claim's ConfigMap beyond the common
+// BUCKET_NAME, BUCKET_HOST and BUCKET_PORT
+type ProviderType string
+
+const (
+	// ProviderTypeS3 adds BUCKET_REGION and BUCKET_SUBREGION, and BUCKET_STS_ENDPOINT if the endpoint has one
+	ProviderTypeS3 ProviderType = "s3"
+	// ProviderTypeAzure adds AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_CONTAINER
+	ProviderTypeAzure ProviderType = "azure"
+	// ProviderTypeGCS adds GOOGLE_CLOUD_PROJECT and GCS_LOCATION
+	ProviderTypeGCS ProviderType = "gcs"
+)
+
+// ConnectionStringOptions describes a connection string rendered from the bucket's Endpoint and credentials
+type ConnectionStringOptions struct {
+	// Key is the ConfigMap or Secret key the connection string is written to
+	Key string
+	// Template is the Go text/template source. It is executed against a value exposing .Endpoint (the
+	// v1alpha1.Endpoint) and .Credentials (the Secret data, eg. .Credentials.AWS_ACCESS_KEY_ID).
+	Template string
+}
+
+// ObjectLockMode is the retention mode applied to objects in an object-lock enabled bucket
+type ObjectLockMode string
+
+const (
+	// ObjectLockModeGovernance allows users with special permissions to override retention settings
+	ObjectLockModeGovernance ObjectLockMode = "governance"
+	// ObjectLockModeCompliance prevents any user, including the root user, from overriding retention settings
+	ObjectLockModeCompliance ObjectLockMode = "compliance"
+)
+
+// ObjectLockOptions is the default retention applied to objects of an object-lock enabled bucket
+type ObjectLockOptions struct {
+	Mode          ObjectLockMode
+	RetentionDays int
+}
+
+// LifecycleRules are the expiration rules applied to a new bucket. A zero number of days leaves the corresponding
+// rule unset, at least one rule is set.
+type LifecycleRules struct {
+	// ExpirationDays is the number of days after their creation that objects expire
+	ExpirationDays int
+	// NoncurrentVersionExpirationDays is the number of days after they became noncurrent that object versions expire
+	NoncurrentVersionExpirationDays int
+}
+
+// CORSConfig is the CORS rule applied to a new bucket, allowing browsers to access it from other origins
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to access the bucket, eg. "https://example.com" or "*"
+	AllowedOrigins []string
+	// AllowedMethods are the HTTP methods allowed, among GET, PUT, POST, DELETE and HEAD
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight requests, if any
+	AllowedHeaders []string
+}
+
+// DeepCopy returns a copy of c which shares no memory with it.
+func (c *CORSConfig) DeepCopy() *CORSConfig {
+	if c == nil {
+		return nil
+	}
+	return &CORSConfig{
+		AllowedOrigins: append([]string(nil), c.AllowedOrigins...),
+		AllowedMethods: append([]string(nil), c.AllowedMethods...),
+		AllowedHeaders: append([]string(nil), c.AllowedHeaders...),
+	}
+}
+
+// Capabilities advertises the optional features supported by a provisioner. Requests for a feature which the
+// provisioner does not advertise fail before the provisioner is called.
+type Capabilities struct {
+	// ObjectLock is true if the provisioner can create object-lock enabled buckets
+	ObjectLock bool
+	// Lifecycle is true if the provisioner can apply LifecycleRules to new buckets
+	Lifecycle bool
+	// SSECustomerKey is true if the provisioner can encrypt new buckets with a customer-provided key
+	SSECustomerKey bool
+	// StorageTiers are the storage tiers new buckets can be created in
+	StorageTiers []string
+	// CORS is true if the provisioner can apply a CORSConfig to new buckets
+	CORS bool
+}
+
+// CapabilityAdvertiser MAY be implemented by provisioners to advertise their Capabilities. Provisioners which do not
+// implement it are assumed to support none of the optional features.
+type CapabilityAdvertiser interface {
+	Capabilities() Capabilities
+}
+
+// CredentialsRecoverer MAY be implemented by provisioners to re-issue the credentials of a bound bucket whose generated
+// Secret was deleted. Only the ObjectBucket is available: credentials are not persisted in it, so implementations
+// should rely on the Connection's AdditionalState (eg. a backend user name) to locate or regenerate them.
+type CredentialsRecoverer interface {
+	RecoverCredentials(ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error)
+}
+
+// CredentialRotator MAY be implemented by provisioners to rotate the credentials of bound buckets whose storage class
+// sets a credential rotation interval. RotateCredentials issues new credentials for the bucket; the current ones must
+// keep working until RevokeCredentials is called with them, once the new ones are written to the claim's Secret. If
+// RevokeCredentials fails it is called again on later reconciles, with the access key ID of the old credentials only.
+type CredentialRotator interface {
+	RotateCredentials(ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error)
+	RevokeCredentials(ob *v1alpha1.ObjectBucket, old *v1alpha1.Authentication) error
+}
+
+// ProviderStatusReporter MAY be implemented by provisioners keeping backend-specific state of their buckets in the OB's
+// Status.ProviderStatus. ProviderStatus is called on every reconcile of a bound claim with the OB, whose status holds
+// the state recorded so far, and returns the keys to update: they are merged into the recorded state, an empty value
+// removing its key. Keys which are not returned are left as they are.
+type ProviderStatusReporter interface {
+	ProviderStatus(ob *v1alpha1.ObjectBucket) (map[string]string, error)
+}
+
+// BucketGetter MAY be implemented by provisioners whose Provision is not idempotent. Before provisioning a new bucket
+// the reconciler calls GetBucket. If it returns a non-nil ObjectBucket the bucket is adopted and bound to the claim
+// instead of being provisioned again, eg. when the controller crashed after Provision but before the OB was created.
+// The returned ObjectBucket must be populated as by Provision, including its Authentication. GetBucket should return
+// nil, nil if the bucket named by options.BucketName does not exist, and an error if it exists but was not provisioned
+// for options.ObjectBucketClaim. When drift checks are enabled, GetBucket is also called periodically for bound claims
+// to detect buckets deleted from the object store.
+type BucketGetter interface {
+	GetBucket(options *BucketOptions) (*v1alpha1.ObjectBucket, error)
+}
+
+// ReadinessPoller MAY be implemented by provisioners whose buckets are not usable as soon as Provision or Grant returns,
+// eg. because the backend propagates them asynchronously. BucketReady is called once per reconcile with the returned
+// ObjectBucket, before the claim's Secret and ConfigMap are published. While it returns false the claim is requeued and, as for an errors.InProgressErr, Provision or Grant is called again with the same bucket name.
+// An error fails the provisioning attempt.
+type ReadinessPoller interface {
+	BucketReady(ob *v1alpha1.ObjectBucket) (bool, error)
+}
+
+// BucketDrainer MAY be implemented by provisioners whose backend refuses to delete non-empty buckets. When the storage
+// class sets drainBeforeDelete, DrainBucket is called with the OB of a deleted claim before Delete, and should remove
+// the bucket's objects. Draining a large bucket takes time: DrainBucket should return an errors.InProgressErr while
+// objects remain, and is called again after its RetryAfter delay until it returns nil. Only then are Delete called and
+// the OB deleted. The drain is bounded by the reconciler's drain timeout, measured from the first call.
+type BucketDrainer interface {
+	DrainBucket(ob *v1alpha1.ObjectBucket) error
+}
+
+// NameValidator MAY be implemented by provisioners whose backend constrains bucket names differently from S3, eg.
+// Azure container names. ValidateName is called with the name of every new bucket, as requested by the claim or
+// generated for it, before Provision is called; an error fails the claim. Provisioners which do not implement it are
+// given names which pass the library's S3 bucket naming rules.
+type NameValidator interface {
+	ValidateName(name string) error
+}
+
+// HealthChecker MAY be implemented by provisioners to take part in the readiness of the controller, eg. by checking
+// that the object store is reachable. HealthCheck is called on every readiness probe and should return quickly.
+type HealthChecker interface {
+	HealthCheck() error
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// auditor records the mutations made by a controller, see Provisioner.SetAuditLogger. A nil auditor discards them.
+type auditor struct {
+	logger api.AuditLogger
+	// actor is the name of the provisioner recorded as the actor of each mutation
+	actor string
+}
+
+// audit records op on the named object. claim is the "namespace/name" of the object's claim, if known.
+func (a *auditor) audit(op api.AuditOperation, kind, namespace, name, claim string, err error) {
+	if a == nil {
+		return
+	}
+	record := api.AuditRecord{
+		Time:      time.Now().UTC(),
+		Actor:     a.actor,
+		Operation: op,
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      name,
+		Claim:     claim,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if auditErr := a.logger.Audit(record); auditErr != nil {
+		log.Error(auditErr, "error writing audit record", "operation", op, "kind", kind, "name", name)
+	}
+}
+
+// claimOf returns the "namespace/name" of the claim owning obj, or "" if it has none.
+func claimOf(obj metav1.Object) string {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == v1alpha1.ObjectBucketClaimGVK().Kind {
+			return obj.GetNamespace() + "/" + ref.Name
+		}
+	}
+	return ""
+}
+
+// claimOfObjectBucket returns the "namespace/name" of the claim ob is bound to, or "" if it is unbound.
+func claimOfObjectBucket(ob *v1alpha1.ObjectBucket) string {
+	if ref := ob.Spec.ClaimRef; ref != nil {
+		return ref.Namespace + "/" + ref.Name
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestAuditCreateAndRelease(t *testing.T) {
+	var buf bytes.Buffer
+	a := &auditor{logger: api.NewJSONAuditLogger(&buf), actor: provisionerName}
+
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName}}
+	client := fake.NewSimpleClientset()
+	cm, err := createConfigMap(context.Background(), obc, obc.Name, &v1alpha1.Endpoint{}, nil, nil, nil, client, time.Millisecond, a)
+	if err != nil {
+		t.Fatalf("createConfigMap() unexpected error: %v", err)
+	}
+	if err = releaseConfigMap(cm, client, a); err != nil {
+		t.Fatalf("releaseConfigMap() unexpected error: %v", err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	for _, op := range []api.AuditOperation{api.AuditOperationCreate, api.AuditOperationRelease} {
+		var record api.AuditRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("decoding %s audit record: %v", op, err)
+		}
+		want := api.AuditRecord{
+			Time:      record.Time,
+			Actor:     provisionerName,
+			Operation: op,
+			Kind:      "ConfigMap",
+			Namespace: testNamespace,
+			Name:      testName,
+			Claim:     testNamespace + "/" + testName,
+		}
+		if record != want || record.Time.IsZero() {
+			t.Errorf("audit record = %+v, want %+v", record, want)
+		}
+	}
+	if dec.More() {
+		t.Errorf("unexpected audit records after create and release")
+	}
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// BindingArtifacts are the resources bound to an ObjectBucket. Resources which do not exist are nil.
+type BindingArtifacts struct {
+	Claim     *v1alpha1.ObjectBucketClaim
+	Secret    *corev1.Secret
+	ConfigMap *corev1.ConfigMap
+	// BlobsConfigMap holds the values moved out of ConfigMap because of its size, if any
+	BlobsConfigMap *corev1.ConfigMap
+}
+
+// BindingArtifactsFor returns the claim bound to ob, found through its claimRef, and the Secret and ConfigMap
+// generated for the claim, found by name as derived by naming. If the claim exists, a Secret or ConfigMap which is not owned by it is not
+// one of its artifacts and is not returned. Missing resources are not an error, eg. while a claim is being deleted.
+// It is intended for support tooling answering which resources belong to a bucket.
+func BindingArtifactsFor(ob *v1alpha1.ObjectBucket, naming ArtifactNaming, clientset kubernetes.Interface, libClientset versioned.Interface) (*BindingArtifacts, error) {
+	if ob == nil {
+		return nil, fmt.Errorf("got nil ObjectBucket pointer")
+	}
+	ref := ob.Spec.ClaimRef
+	if ref == nil || ref.Name == "" {
+		return nil, fmt.Errorf("ObjectBucket %q is not bound to a claim", ob.Name)
+	}
+	ns, name := ref.Namespace, ref.Name
+
+	artifacts := &BindingArtifacts{}
+	obc, err := libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(ns).Get(name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		artifacts.Claim = obc
+	case !errors.IsNotFound(err):
+		return nil, fmt.Errorf("error getting claim \"%s/%s\": %v", ns, name, err)
+	}
+	// the claim may have been re-created with the same name, the bucket is only bound to the one it references
+	if artifacts.Claim != nil && ref.UID != "" && artifacts.Claim.UID != ref.UID {
+		artifacts.Claim = nil
+	}
+	claimUID := ref.UID
+	if artifacts.Claim != nil {
+		claimUID = artifacts.Claim.UID
+	}
+
+	artifactName := naming.Name(name)
+	secret, err := clientset.CoreV1().Secrets(ns).Get(artifactName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if ownedBy(secret, claimUID) {
+			artifacts.Secret = secret
+		}
+	case !errors.IsNotFound(err):
+		return nil, fmt.Errorf("error getting secret \"%s/%s\": %v", ns, artifactName, err)
+	}
+
+	cm, err := clientset.CoreV1().ConfigMaps(ns).Get(artifactName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if ownedBy(cm, claimUID) {
+			artifacts.ConfigMap = cm
+		}
+	case !errors.IsNotFound(err):
+		return nil, fmt.Errorf("error getting configMap \"%s/%s\": %v", ns, artifactName, err)
+	}
+
+	if artifacts.ConfigMap != nil && artifacts.ConfigMap.Data[bucketBlobsConfigMap] != "" {
+		blobsName := artifacts.ConfigMap.Data[bucketBlobsConfigMap]
+		blobs, err := clientset.CoreV1().ConfigMaps(ns).Get(blobsName, metav1.GetOptions{})
+		switch {
+		case err == nil:
+			if ownedBy(blobs, claimUID) {
+				artifacts.BlobsConfigMap = blobs
+			}
+		case !errors.IsNotFound(err):
+			return nil, fmt.Errorf("error getting configMap \"%s/%s\": %v", ns, blobsName, err)
+		}
+	}
+	return artifacts, nil
+}
+
+// BindingResult names the objects generated for a bound claim, so that callers embedding the library can reference
+// them rather than derive them from the naming conventions. The names of objects which were not generated, eg. the
+// Secret of a claim whose provisioner returned no credentials, are empty.
+type BindingResult struct {
+	// Namespace is the namespace of the claim and of its ConfigMaps, Secret and ServiceAccount
+	Namespace        string
+	ObjectBucketName string
+	ConfigMapName    string
+	SecretName       string
+	// BlobsConfigMapName holds the values moved out of the ConfigMap because of its size, if any
+	BlobsConfigMapName string
+	// ServiceAccountName is the ServiceAccount created by the ServiceAccountBinder, if any
+	ServiceAccountName string
+}
+
+// BindingResultFor returns the names of the objects generated for the bound claim namespace/name, whose ConfigMap and
+// Secret are named by naming. An error is returned if the claim is not bound or its ObjectBucket does not exist.
+func BindingResultFor(namespace, name string, naming ArtifactNaming, clientset kubernetes.Interface, libClientset versioned.Interface) (*BindingResult, error) {
+	obc, err := libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting claim \"%s/%s\": %v", namespace, name, err)
+	}
+	if obc.Spec.ObjectBucketName == "" {
+		return nil, fmt.Errorf("claim \"%s/%s\" is not bound", namespace, name)
+	}
+	ob, err := libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting ObjectBucket %q: %v", obc.Spec.ObjectBucketName, err)
+	}
+	artifacts, err := BindingArtifactsFor(ob, naming, clientset, libClientset)
+	if err != nil {
+		return nil, err
+	}
+	result := &BindingResult{
+		Namespace:          namespace,
+		ObjectBucketName:   ob.Name,
+		ServiceAccountName: obc.Annotations[api.ServiceAccountAnnotation],
+	}
+	if artifacts.ConfigMap != nil {
+		result.ConfigMapName = artifacts.ConfigMap.Name
+	}
+	if artifacts.Secret != nil {
+		result.SecretName = artifacts.Secret.Name
+	}
+	if artifacts.BlobsConfigMap != nil {
+		result.BlobsConfigMapName = artifacts.BlobsConfigMap.Name
+	}
+	return result, nil
+}
+
+// ownedBy returns true if obj has an owner reference to the given UID, or if the UID is unknown.
+func ownedBy(obj metav1.Object, uid types.UID) bool {
+	if uid == "" {
+		return true
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestBindingArtifactsFor(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc", Namespace: testNamespace, UID: "claim-uid"},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+		Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: makeObjectReference(obc)},
+	}
+	owned := metav1.ObjectMeta{
+		Name:            obc.Name,
+		Namespace:       obc.Namespace,
+		OwnerReferences: []metav1.OwnerReference{makeOwnerReference(obc)},
+	}
+	secret := &corev1.Secret{ObjectMeta: owned}
+	// a user's ConfigMap which happens to have the claim's name
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: obc.Name, Namespace: obc.Namespace}}
+
+	got, err := BindingArtifactsFor(ob, ArtifactNaming{}, fake.NewSimpleClientset(secret, cm), externalFake.NewSimpleClientset(obc))
+	if err != nil {
+		t.Fatalf("BindingArtifactsFor() unexpected error: %v", err)
+	}
+	if got.Claim == nil || got.Claim.UID != obc.UID {
+		t.Errorf("BindingArtifactsFor() claim = %v, want %v", got.Claim, obc)
+	}
+	if got.Secret == nil {
+		t.Errorf("BindingArtifactsFor() secret = nil, want the claim's secret")
+	}
+	if got.ConfigMap != nil {
+		t.Errorf("BindingArtifactsFor() configMap = %v, want nil for a configMap not owned by the claim", got.ConfigMap)
+	}
+
+	// the claim and its artifacts are gone
+	got, err = BindingArtifactsFor(ob, ArtifactNaming{}, fake.NewSimpleClientset(), externalFake.NewSimpleClientset())
+	if err != nil {
+		t.Fatalf("BindingArtifactsFor() unexpected error: %v", err)
+	}
+	if got.Claim != nil || got.Secret != nil || got.ConfigMap != nil || got.BlobsConfigMap != nil {
+		t.Errorf("BindingArtifactsFor() = %+v, want no artifacts", got)
+	}
+
+	if _, err = BindingArtifactsFor(&v1alpha1.ObjectBucket{}, ArtifactNaming{}, fake.NewSimpleClientset(), externalFake.NewSimpleClientset()); err == nil {
+		t.Errorf("BindingArtifactsFor() expected error for an unbound ObjectBucket")
+	}
+}
+
+func TestBindingResultFor(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "obc",
+			Namespace:   testNamespace,
+			UID:         "claim-uid",
+			Annotations: map[string]string{api.ServiceAccountAnnotation: "bucket-user"},
+		},
+		Spec: v1alpha1.ObjectBucketClaimSpec{ObjectBucketName: "obc-" + testNamespace + "-obc"},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: obc.Spec.ObjectBucketName},
+		Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: makeObjectReference(obc)},
+	}
+	naming := ArtifactNaming{Prefix: "obc-"}
+	owned := metav1.ObjectMeta{
+		Name:            naming.Name(obc.Name),
+		Namespace:       obc.Namespace,
+		OwnerReferences: []metav1.OwnerReference{makeOwnerReference(obc)},
+	}
+	// the claim's provisioner returned no credentials, it has no secret
+	cm := &corev1.ConfigMap{ObjectMeta: owned}
+
+	got, err := BindingResultFor(obc.Namespace, obc.Name, naming, fake.NewSimpleClientset(cm), externalFake.NewSimpleClientset(obc, ob))
+	if err != nil {
+		t.Fatalf("BindingResultFor() unexpected error: %v", err)
+	}
+	want := &BindingResult{
+		Namespace:          obc.Namespace,
+		ObjectBucketName:   ob.Name,
+		ConfigMapName:      "obc-obc",
+		ServiceAccountName: "bucket-user",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BindingResultFor() = %+v, want %+v", got, want)
+	}
+
+	unbound := obc.DeepCopy()
+	unbound.Spec.ObjectBucketName = ""
+	if _, err = BindingResultFor(obc.Namespace, obc.Name, naming, fake.NewSimpleClientset(), externalFake.NewSimpleClientset(unbound)); err == nil {
+		t.Errorf("BindingResultFor() expected error for an unbound claim")
+	}
+}
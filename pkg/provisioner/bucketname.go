@@ -0,0 +1,204 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"text/template"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// minBucketNameLen is the shortest bucket name accepted from a bucket name template
+const minBucketNameLen = 3
+
+// BucketNameProfile holds the bucket naming rules of a kind of object store. Generated and templated bucket names are
+// kept within its MaxLength, and dynamically provisioned bucket names are validated against it unless the provisioner
+// implements api.NameValidator.
+type BucketNameProfile struct {
+	// MaxLength is the length of the longest valid bucket name
+	MaxLength int
+	// Separators are the characters allowed in bucket names besides lowercase alphanumerics. Names never begin or end
+	// with a separator. It must include the hyphen, which joins generated names to their suffix.
+	Separators string
+}
+
+// bucketNameProfiles are the default naming rules of each provider type. GCS allows names of up to 222 characters
+// when they contain dots, which provisioners accept by setting a profile of their own.
+var bucketNameProfiles = map[api.ProviderType]BucketNameProfile{
+	api.ProviderTypeS3:    {MaxLength: maxNameLen, Separators: ".-"},
+	api.ProviderTypeAzure: {MaxLength: maxNameLen, Separators: "-"},
+	api.ProviderTypeGCS:   {MaxLength: maxNameLen, Separators: ".-_"},
+}
+
+// validateBucketNameProfile checks that names following the profile can be generated.
+func validateBucketNameProfile(profile BucketNameProfile) error {
+	if profile.MaxLength < minBucketNameLen {
+		return fmt.Errorf("invalid bucket name max length %d, must be at least %d", profile.MaxLength, minBucketNameLen)
+	}
+	if !strings.ContainsRune(profile.Separators, '-') {
+		return fmt.Errorf("invalid bucket name separators %q, must include the hyphen", profile.Separators)
+	}
+	return nil
+}
+
+// bucketNameData is the value bucket name templates are executed against
+type bucketNameData struct {
+	Namespace          string
+	Name               string
+	Labels             map[string]string
+	Annotations        map[string]string
+	GenerateBucketName string
+}
+
+// parseBucketNameTemplate parses text as a bucket name template, named after the storage class parameter like
+// connection string templates.
+func parseBucketNameTemplate(text string) (*template.Template, error) {
+	t, err := template.New(v1alpha1.StorageClassBucketNameTemplate).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %q: %v", v1alpha1.StorageClassBucketNameTemplate, err)
+	}
+	return t, nil
+}
+
+// renderBucketName executes the bucket name template against the claim and sanitizes the result, truncating it to
+// maxLen. Failures cannot be fixed by retrying and are returned as terminal errors.
+func renderBucketName(text string, obc *v1alpha1.ObjectBucketClaim, maxLen int) (string, error) {
+	t, err := parseBucketNameTemplate(text)
+	if err != nil {
+		return "", &terminalError{err}
+	}
+	data := bucketNameData{
+		Namespace:          obc.Namespace,
+		Name:               obc.Name,
+		Labels:             obc.Labels,
+		Annotations:        obc.Annotations,
+		GenerateBucketName: obc.Spec.GenerateBucketName,
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return "", newTerminalError("error rendering %q: %v", v1alpha1.StorageClassBucketNameTemplate, err)
+	}
+	name := sanitizeBucketName(buf.String(), maxLen)
+	if len(name) < minBucketNameLen {
+		return "", newTerminalError("%q rendered %q, which is not a valid bucket name", v1alpha1.StorageClassBucketNameTemplate, buf.String())
+	}
+	return name, nil
+}
+
+// sanitizeBucketName maps s to the lowercase alphanumerics and hyphens allowed in bucket names. Every other run of
+// characters, including dots, becomes a single hyphen; leading and trailing hyphens are dropped and the name is
+// truncated to maxLen.
+func sanitizeBucketName(s string, maxLen int) string {
+	var b strings.Builder
+	sep := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if sep && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			sep = false
+			continue
+		}
+		sep = true
+	}
+	name := b.String()
+	if len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], "-")
+	}
+	return name
+}
+
+// validateBucketName checks name against the provisioner's naming rules if it implements api.NameValidator, and the
+// rules of profile otherwise. Failures cannot be fixed by retrying and are returned as terminal errors.
+func validateBucketName(p api.Provisioner, name string, profile BucketNameProfile) error {
+	validate := profile.validate
+	if v, ok := p.(api.NameValidator); ok {
+		validate = v.ValidateName
+	}
+	if err := validate(name); err != nil {
+		return newTerminalError("invalid bucket name %q: %v", name, err)
+	}
+	return nil
+}
+
+// validateBucketNamePrefix checks that prefix can begin generated bucket names: lowercase alphanumerics and hyphens,
+// beginning with an alphanumeric, short enough to leave room for the shortest suffix.
+func validateBucketNamePrefix(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("bucket name prefix must not be empty")
+	}
+	if maxLen := maxNameLen - MinBucketNameSuffixLength - 1; len(prefix) > maxLen {
+		return fmt.Errorf("bucket name prefix %q must be at most %d characters long", prefix, maxLen)
+	}
+	for _, r := range prefix {
+		if !isBucketNameAlphanumeric(r) && r != '-' {
+			return fmt.Errorf("bucket name prefix %q must consist of lowercase alphanumerics and hyphens", prefix)
+		}
+	}
+	if !isBucketNameAlphanumeric(rune(prefix[0])) {
+		return fmt.Errorf("bucket name prefix %q must begin with a lowercase alphanumeric", prefix)
+	}
+	return nil
+}
+
+// checkGeneratedNameLength returns a terminal error if names generated from prefix with a suffix of suffixLen, a UUID
+// if 0, would exceed maxLen. Unlike the generateBucketName of claims, a default prefix is never truncated: truncated
+// names would no longer be identifiable.
+func checkGeneratedNameLength(prefix string, suffixLen, maxLen int) error {
+	if suffixLen == 0 {
+		suffixLen = uuidSuffixLen
+	}
+	if n := len(prefix) + 1 + suffixLen; n > maxLen {
+		return newTerminalError("default bucket name prefix %q generates %d character names, longer than the limit of %d",
+			prefix, n, maxLen)
+	}
+	return nil
+}
+
+// validate checks name against the profile: 3 to MaxLength lowercase alphanumerics and separators, beginning and
+// ending with an alphanumeric, without adjacent dots and not formatted as an IP address. With the default S3 profile
+// these are the S3 bucket naming rules.
+func (np BucketNameProfile) validate(name string) error {
+	if len(name) < minBucketNameLen || len(name) > np.MaxLength {
+		return fmt.Errorf("must be between %d and %d characters long", minBucketNameLen, np.MaxLength)
+	}
+	for _, r := range name {
+		if !(isBucketNameAlphanumeric(r) || strings.ContainsRune(np.Separators, r)) {
+			return fmt.Errorf("must consist of lowercase alphanumerics and %q", np.Separators)
+		}
+	}
+	if !isBucketNameAlphanumeric(rune(name[0])) || !isBucketNameAlphanumeric(rune(name[len(name)-1])) {
+		return fmt.Errorf("must begin and end with a lowercase alphanumeric")
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("must not contain adjacent dots")
+	}
+	if net.ParseIP(name) != nil {
+		return fmt.Errorf("must not be formatted as an IP address")
+	}
+	return nil
+}
+
+func isBucketNameAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestRenderBucketName(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "Team_A",
+			Name:      "logs.archive",
+			Labels:    map[string]string{"team": "Payments"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "labels namespace and name",
+			template: "{{ .Labels.team }}-{{ .Namespace }}-{{ .Name }}",
+			want:     "payments-team-a-logs-archive",
+		},
+		{
+			name:     "truncated",
+			template: "{{ .Name }}-" + strings.Repeat("x", maxNameLen),
+			want:     "logs-archive-" + strings.Repeat("x", maxNameLen-len("logs-archive-")),
+		},
+		{
+			name:     "missing label",
+			template: "{{ .Labels.owner }}-{{ .Name }}",
+			wantErr:  "error rendering",
+		},
+		{
+			name:     "too short",
+			template: "--{{ .Labels.team | printf \"%.1s\" }}--",
+			wantErr:  "not a valid bucket name",
+		},
+		{
+			name:     "parse error",
+			template: "{{ .Name ",
+			wantErr:  "invalid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderBucketName(tt.template, obc, maxNameLen)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("renderBucketName() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if !isTerminal(err) {
+					t.Errorf("renderBucketName() error = %v, want a terminal error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderBucketName() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderBucketName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeNameValidator accepts Azure style container names, which may not contain dots
+type fakeNameValidator struct {
+	fakeProvisioner
+}
+
+func (p *fakeNameValidator) ValidateName(name string) error {
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("must not contain dots")
+	}
+	return nil
+}
+
+func TestValidateBucketName(t *testing.T) {
+	s3 := bucketNameProfiles[api.ProviderTypeS3]
+	long := BucketNameProfile{MaxLength: 100, Separators: "-"}
+	tests := []struct {
+		name        string
+		provisioner api.Provisioner
+		profile     BucketNameProfile
+		bucket      string
+		wantErr     bool
+	}{
+		{"s3 valid", &fakeProvisioner{}, s3, "logs.archive-1", false},
+		{"s3 too short", &fakeProvisioner{}, s3, "ab", true},
+		{"s3 too long", &fakeProvisioner{}, s3, strings.Repeat("a", maxNameLen+1), true},
+		{"s3 uppercase", &fakeProvisioner{}, s3, "Logs", true},
+		{"s3 trailing hyphen", &fakeProvisioner{}, s3, "logs-", true},
+		{"s3 adjacent dots", &fakeProvisioner{}, s3, "logs..archive", true},
+		{"s3 ip address", &fakeProvisioner{}, s3, "192.168.1.1", true},
+		{"azure dots", &fakeProvisioner{}, bucketNameProfiles[api.ProviderTypeAzure], "logs.archive", true},
+		{"gcs underscore", &fakeProvisioner{}, bucketNameProfiles[api.ProviderTypeGCS], "logs_archive", false},
+		{"custom max length", &fakeProvisioner{}, long, strings.Repeat("a", maxNameLen+1), false},
+		{"custom too long", &fakeProvisioner{}, long, strings.Repeat("a", 101), true},
+		{"provisioner valid", &fakeNameValidator{}, s3, "logs-archive", false},
+		{"provisioner invalid", &fakeNameValidator{}, s3, "logs.archive", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBucketName(tt.provisioner, tt.bucket, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateBucketName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !isTerminal(err) {
+				t.Errorf("validateBucketName() error = %v, want a terminal error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// credentialsField is the name under which credentials are exposed to connection string templates
+const credentialsField = "Credentials"
+
+// connectionStringData is the value connection string templates are executed against
+type connectionStringData struct {
+	Endpoint    v1alpha1.Endpoint
+	Credentials map[string]string
+}
+
+// parseConnectionStringTemplate parses text as a connection string template. The template is named after the storage
+// class parameter so that parse and execution errors point at it, eg.
+// "template: connectionStringTemplate:1:12: ...".
+func parseConnectionStringTemplate(text string) (*template.Template, error) {
+	t, err := template.New(v1alpha1.StorageClassConnectionStringTemplate).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %q: %v", v1alpha1.StorageClassConnectionStringTemplate, err)
+	}
+	return t, nil
+}
+
+// connectionStringIsSensitive returns true if the rendered connection string must be written to the claim's Secret
+// rather than its ConfigMap.
+func connectionStringIsSensitive(opts *api.ConnectionStringOptions) (bool, error) {
+	t, err := parseConnectionStringTemplate(opts.Template)
+	if err != nil {
+		return false, err
+	}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil && referencesCredentials(tt.Tree.Root) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// renderConnectionString executes the connection string template against ep and auth.
+func renderConnectionString(opts *api.ConnectionStringOptions, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication) (string, error) {
+	t, err := parseConnectionStringTemplate(opts.Template)
+	if err != nil {
+		return "", err
+	}
+	data := connectionStringData{Credentials: auth.ToMap()}
+	if ep != nil {
+		data.Endpoint = *ep
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering %q: %v", v1alpha1.StorageClassConnectionStringTemplate, err)
+	}
+	return buf.String(), nil
+}
+
+// referencesCredentials walks a template's parse tree looking for any access to the credentials. A bare dot could
+// expose them indirectly (eg. {{ printf "%v" . }}) so it is conservatively treated as a reference as well.
+func referencesCredentials(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Nodes {
+			if referencesCredentials(c) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return referencesCredentials(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, c := range n.Cmds {
+			if referencesCredentials(c) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			if referencesCredentials(a) {
+				return true
+			}
+		}
+	case *parse.DotNode:
+		return true
+	case *parse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == credentialsField
+	case *parse.VariableNode:
+		// $ is the root data, so $.Credentials is a reference; other variables are covered by their declaration
+		if n.Ident[0] != "$" {
+			return false
+		}
+		return len(n.Ident) == 1 || n.Ident[1] == credentialsField
+	case *parse.ChainNode:
+		return referencesCredentials(n.Node)
+	case *parse.IfNode:
+		return referencesBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return referencesBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return referencesBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return referencesCredentials(n.Pipe)
+	}
+	return false
+}
+
+func referencesBranch(n *parse.BranchNode) bool {
+	return referencesCredentials(n.Pipe) || referencesCredentials(n.List) || referencesCredentials(n.ElseList)
+}
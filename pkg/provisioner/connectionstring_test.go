@@ -0,0 +1,99 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestRenderConnectionString(t *testing.T) {
+	ep := &v1alpha1.Endpoint{
+		BucketHost: "s3.example.com",
+		BucketPort: 443,
+		BucketName: "bucket",
+	}
+	auth := &v1alpha1.Authentication{
+		AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "key", SecretAccessKey: "secret"},
+	}
+
+	tests := []struct {
+		name          string
+		template      string
+		want          string
+		wantSensitive bool
+		wantErr       string
+	}{
+		{
+			name:     "endpoint only",
+			template: "https://{{ .Endpoint.BucketHost }}:{{ .Endpoint.BucketPort }}/{{ .Endpoint.BucketName }}",
+			want:     "https://s3.example.com:443/bucket",
+		},
+		{
+			name:          "with credentials",
+			template:      "s3://{{ .Credentials.AWS_ACCESS_KEY_ID }}:{{ .Credentials.AWS_SECRET_ACCESS_KEY }}@{{ .Endpoint.BucketHost }}/{{ .Endpoint.BucketName }}",
+			want:          "s3://key:secret@s3.example.com/bucket",
+			wantSensitive: true,
+		},
+		{
+			name:          "credentials through a variable",
+			template:      "{{ $c := .Credentials }}{{ with .Endpoint }}{{ .BucketHost }}{{ end }}",
+			want:          "s3.example.com",
+			wantSensitive: true,
+		},
+		{
+			name:          "bare dot",
+			template:      `{{ printf "%v" . }}`,
+			wantSensitive: true,
+		},
+		{
+			name:     "syntax error reports location",
+			template: "{{ .Endpoint.BucketHost ",
+			wantErr:  v1alpha1.StorageClassConnectionStringTemplate + ":1:",
+		},
+		{
+			name:     "unknown field reports location",
+			template: "{{ .Endpoint.Bogus }}",
+			wantErr:  v1alpha1.StorageClassConnectionStringTemplate + ":1:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &api.ConnectionStringOptions{Key: bucketConnectionString, Template: tt.template}
+			sensitive, err := connectionStringIsSensitive(opts)
+			if err == nil {
+				if sensitive != tt.wantSensitive {
+					t.Errorf("connectionStringIsSensitive() = %v, want %v", sensitive, tt.wantSensitive)
+				}
+				var got string
+				got, err = renderConnectionString(opts, ep, auth)
+				if err == nil && tt.want != "" && got != tt.want {
+					t.Errorf("renderConnectionString() = %q, want %q", got, tt.want)
+				}
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,30 +17,159 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
 	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	storageinformers "k8s.io/client-go/informers/storage/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+	libscheme "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/scheme"
 	informers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/informers/externalversions/objectbucket.io/v1alpha1"
 	listers "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/listers/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
 	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
 )
 
+const (
+	// defaultPauseRequeueDelay is how long to wait before re-checking a claim whose provisioning is paused
+	defaultPauseRequeueDelay = time.Second * 30
+	// defaultFinalizerRequeueDelay is how long to wait before re-checking a deleted OB or OBC held by other finalizers
+	defaultFinalizerRequeueDelay = time.Second * 10
+	// defaultInProgressRequeueDelay is how long to wait before calling the provisioner again for a bucket it is
+	// creating asynchronously, if it did not suggest a delay
+	defaultInProgressRequeueDelay = time.Second * 15
+	// defaultVerifyTimeout bounds a call to the connection verifier, if no timeout was set with it
+	defaultVerifyTimeout = time.Second * 10
+	// defaultProvisionSlotRequeueDelay is how long to wait before trying to provision a claim again when the maximum
+	// number of Provision calls is in flight
+	defaultProvisionSlotRequeueDelay = time.Second * 5
+	// defaultVerifyRequeueDelay is how long to wait before verifying the connection of a claim again after it failed
+	defaultVerifyRequeueDelay = time.Second * 15
+	// defaultReadinessRequeueDelay is how long to wait before provisioning a claim again when its bucket did not
+	// become ready within the retry budget
+	defaultReadinessRequeueDelay = time.Second * 15
+	// defaultDrainRequeueDelay is how long to wait before draining a bucket again, if the provisioner did not suggest
+	// a delay
+	defaultDrainRequeueDelay = time.Second * 30
+	// defaultReleaseRequeueDelay is how long to wait before releasing the Secret or ConfigMap of a deleted claim again
+	// after a transient API error
+	defaultReleaseRequeueDelay = time.Second * 5
+	// defaultDrainTimeout bounds the drain of a bucket before its deletion, if no timeout was set
+	defaultDrainTimeout = time.Hour * 24
+
+	// reasons of events recorded on OBCs
+	reasonProvisioningPaused = "ProvisioningPaused"
+	reasonSecretMissing      = "SecretMissing"
+	reasonPermissionDenied   = "PermissionDenied"
+	reasonBound              = "Bound"
+	reasonImmutableField     = "ImmutableFieldChanged"
+	reasonSpecValid          = "SpecValid"
+	reasonSuspiciousEndpoint = "SuspiciousEndpoint"
+	reasonInProgress         = "InProgress"
+	reasonProvisionFailed    = "ProvisionFailed"
+	reasonVerificationFailed = "VerificationFailed"
+	reasonVerified           = "Verified"
+	reasonTimedOut           = "TimedOut"
+	reasonBucketNotReady     = "BucketNotReady"
+	reasonTerminalFailure    = "TerminalFailure"
+)
+
+func init() {
+	// register the OB and OBC types so that events can be recorded against them
+	utilruntime.Must(libscheme.AddToScheme(scheme.Scheme))
+}
+
 type controller interface {
 	Start(<-chan struct{}) error
 	SetLabels(map[string]string)
+	SetLabelSelector(labels.Selector)
+	SetObjectBucketNaming(ObjectBucketNaming)
+	SetProvisioningPaused(bool)
+	SetReconcilesPaused(bool)
+	ReconcilesPaused() bool
+	SetBucketNameSuffixLength(int)
+	SetDefaultBucketNamePrefix(string)
+	SetBucketNameProfile(api.ProviderType, BucketNameProfile)
+	SetConnectionValidation(ConnectionValidation)
+	SetArtifactNaming(ArtifactNaming)
+	SetFailurePolicy(FailurePolicy)
+	SetTimeoutPolicy(TimeoutPolicy)
+	SetErrorClassifier(ErrorClassifier)
+	SetBucketPortDefaulting(bool)
+	SetStaleConnectionUpdate(bool)
+	SetSecretAnnotator(SecretAnnotator)
+	SetServiceAccountBinder(ServiceAccountBinder)
+	SetOnBoundHook(OnBoundHook)
+	SetOnDeletedHook(OnDeletedHook)
+	SetAnnotationPropagation([]string)
+	SetClaimParameterAllowlist([]string)
+	SetAuthenticationPolicy(string, AuthenticationPolicy)
+	SetEndpointTransformer(EndpointTransformer)
+	SetConnectionVerifier(ConnectionVerifier, time.Duration)
+	SetReverificationInterval(time.Duration)
+	SetDriftCheckInterval(time.Duration)
+	SetDrainTimeout(time.Duration)
+	SetOptionsCacheSize(int)
+	ImportBuckets([]BucketDescriptor, *storagev1.StorageClass, string) ([]ImportResult, error)
+	SetArtifactReclaimPolicy(ArtifactReclaimPolicy)
+	SetStaleArtifactPolicy(StaleArtifactPolicy)
+	SetDeletionSequence([]DeletionStep)
+	SetCreationOrder(CreationOrder)
+	SetExternalFinalizers([]string)
+	SetMaxInFlightProvisions(int)
+	SetWorkers(int)
+	SetAuditLogger(api.AuditLogger, string)
+	RegisterClaimCollector(prometheus.Registerer) error
+	RegisterSweepCollector(prometheus.Registerer) error
+	RegisterCleanupCollector(prometheus.Registerer, time.Duration) error
+	SetOrphanSweepPolicy(OrphanSweepPolicy)
+	RegisterProvisioner(string, api.Provisioner) error
+	EnqueueOBC(string, string) error
+	BindingResult(string, string) (*BindingResult, error)
+	Ready() error
+}
+
+// requeueAfterError is returned by the syncHandler to have the key requeued after a fixed delay rather than
+// with the rate limiter's backoff. It signals that the claim is waiting on something, not that it failed.
+type requeueAfterError struct {
+	delay  time.Duration
+	reason string
+}
+
+func (e *requeueAfterError) Error() string {
+	return fmt.Sprintf("%s, requeuing after %v", e.reason, e.delay)
+}
+
+// reconcilesPausedError is returned by the syncHandler while all reconciles are paused. The key is requeued with the
+// rate limiter's backoff, without being reported as a failure.
+type reconcilesPausedError struct{}
+
+func (e *reconcilesPausedError) Error() string {
+	return "reconciles are paused"
 }
 
 // Provisioner is a CRD Controller responsible for executing the Reconcile() function
@@ -53,17 +182,123 @@ type obcController struct {
 	obcInformer  informers.ObjectBucketClaimInformer
 	obcHasSynced cache.InformerSynced
 	obHasSynced  cache.InformerSynced
-	queue        workqueue.RateLimitingInterface
+	// secretLister and configMapLister read the generated secrets and configMaps from the label-scoped informers' caches
+	secretLister    corelisters.SecretLister
+	configMapLister corelisters.ConfigMapLister
+	// secretHasSynced is nil unless generated secrets are watched
+	secretHasSynced cache.InformerSynced
+	// configMapHasSynced is nil unless generated configMaps are watched
+	configMapHasSynced cache.InformerSynced
+	// classLister reads the storage classes of claims from the storage class informer's cache
+	classLister storagelisters.StorageClassLister
+	// classHasSynced is nil unless storage classes are watched
+	classHasSynced cache.InformerSynced
+	queue          workqueue.RateLimitingInterface
 	// static label containing provisioner name and provisioner-specific labels which are all added
 	// to the OB, OBC, configmap and secret
 	provisionerLabels map[string]string
-	provisioner       api.Provisioner
-	provisionerName   string
+	// labelSelector scopes all list operations (e.g. orphan detection) to the OBs and OBCs managed by this
+	// provisioner, so that multiple provisioners can coexist without touching each other's resources
+	labelSelector labels.Selector
+	// obNaming selects how generated OB names are derived
+	obNaming ObjectBucketNaming
+	// bucketNameSuffixLen is the length of the random suffix of generated bucket names, 0 for a UUID
+	bucketNameSuffixLen int
+	// defaultBucketNamePrefix, if set, prefixes the generated bucket names of claims which request no name, unless
+	// their storage class sets a prefix
+	defaultBucketNamePrefix string
+	// bucketNameProfiles override the default bucket naming rules of provider types
+	bucketNameProfiles map[api.ProviderType]BucketNameProfile
+	// connValidation selects how suspicious connections returned by the provisioner are handled
+	connValidation ConnectionValidation
+	// artifactNaming derives the names of generated ConfigMaps and Secrets
+	artifactNaming ArtifactNaming
+	// artifactReclaim selects whether the Secret and ConfigMaps of claims whose bucket is retained are kept
+	artifactReclaim ArtifactReclaimPolicy
+	// staleArtifacts selects whether the Secret and ConfigMaps left over by a deleted claim of the same name are reclaimed
+	staleArtifacts StaleArtifactPolicy
+	// deletionSequence, if set, is the order in which the resources of deleted claims are deleted or released
+	deletionSequence []DeletionStep
+	// creationOrder selects whether the OB of a claim being provisioned is created before or after its artifacts
+	creationOrder CreationOrder
+	// endpointTransformer, if set, rewrites endpoints before they are published to claims
+	endpointTransformer EndpointTransformer
+	// connVerifier, if set, must succeed before a claim is marked Bound. Each call is bounded by verifyTimeout.
+	connVerifier  ConnectionVerifier
+	verifyTimeout time.Duration
+	// secretAnnotator and serviceAccountBinder, if set, enrich claims with the metadata of workload identity
+	// integrations
+	secretAnnotator      SecretAnnotator
+	serviceAccountBinder ServiceAccountBinder
+	// onBound and onDeleted, if set, are notified of claims being bound and torn down
+	onBound   OnBoundHook
+	onDeleted OnDeletedHook
+	// propagatedAnnotationPrefixes are the prefixes of the claims' annotations mirrored onto their OBs
+	propagatedAnnotationPrefixes []string
+	// claimParameterAllowlist, if non-nil, holds the storage class parameters claims may override with their
+	// AdditionalConfig. nil ignores the AdditionalConfig of claims.
+	claimParameterAllowlist map[string]bool
+	// reverifyInterval, if non-zero, is how often the connection of bound claims is verified again. lastVerified
+	// holds the time each claim was last verified, by key.
+	reverifyInterval time.Duration
+	lastVerified     map[string]time.Time
+	lastVerifiedMu   sync.Mutex
+	// driftCheckInterval, if non-zero, is how often provisioners implementing api.BucketGetter are asked whether the
+	// buckets of bound claims still exist. lastDriftCheck holds the time each claim was last checked, by key.
+	driftCheckInterval time.Duration
+	lastDriftCheck     map[string]time.Time
+	lastDriftCheckMu   sync.Mutex
+	// drainTimeout bounds the drain of the buckets of deleted claims, defaultDrainTimeout if 0
+	drainTimeout time.Duration
+	// optionsCache, if set, memoizes the provision options parsed from storage classes
+	optionsCache *optionsCache
+	// externalFinalizers are added to provisioned claims and their OBs, and removed by other controllers
+	externalFinalizers []string
+	// provisionSlots, if set, holds a token for each Provision call in flight, its capacity bounding their number
+	provisionSlots chan struct{}
+	// workers is the number of goroutines processing the queue, 1 if 0
+	workers int
+	// auditor, if set, records each mutation of the claims' objects
+	auditor *auditor
+	// sweeper, if set, periodically deletes orphaned OBs
+	sweeper *orphanSweeper
+	// failurePolicy decides when claims failing to provision are marked Failed
+	failurePolicy FailurePolicy
+	// timeoutPolicy decides how claims are handled once the retry budget of their creates or deletes is spent
+	timeoutPolicy TimeoutPolicy
+	// errorClassifier, if set, classifies the untyped errors of Provision and Grant
+	errorClassifier ErrorClassifier
+	// keepZeroBucketPort publishes a BucketPort of 0 as is rather than defaulting it from the scheme of the host
+	keepZeroBucketPort bool
+	// updateStaleConnection updates the Connection of an OB left by a previous attempt for the claim when it differs
+	// from the provision result
+	updateStaleConnection bool
+	// failures holds the consecutive provisioning failures of claims, by key
+	failures   map[string]provisionFailures
+	failuresMu sync.Mutex
+	// provisioningPaused is non-zero while provisioning of all claims is paused. Deletes still proceed.
+	provisioningPaused int32
+	// reconcilesPaused is non-zero while all reconciles are paused, deletes included
+	reconcilesPaused int32
+	// cachesSynced is non-zero once the informer caches have synced
+	cachesSynced    int32
+	recorder        record.EventRecorder
+	provisioner     api.Provisioner
+	provisionerName string
+	// provisioners holds the implementations registered in addition to provisioner, by provisioner name
+	provisioners map[string]api.Provisioner
+	// authPolicies holds the AuthenticationPolicy of provisioners, by provisioner name. It is also guarded by
+	// provisionersMu.
+	authPolicies   map[string]AuthenticationPolicy
+	provisionersMu sync.RWMutex
 }
 
 var _ controller = &obcController{}
 
 func NewController(provisionerName string, provisioner api.Provisioner, clientset kubernetes.Interface, crdClientSet versioned.Interface, obcInformer informers.ObjectBucketClaimInformer, obInformer informers.ObjectBucketInformer) *obcController {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+
 	ctrl := &obcController{
 		clientset:    clientset,
 		libClientset: crdClientSet,
@@ -76,8 +311,13 @@ func NewController(provisionerName string, provisioner api.Provisioner, clientse
 		provisionerLabels: map[string]string{
 			provisionerLabelKey: labelValue(provisionerName),
 		},
+		labelSelector: labels.SelectorFromSet(labels.Set{
+			provisionerLabelKey: labelValue(provisionerName),
+		}),
+		recorder:        broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: provisionerName}),
 		provisionerName: provisionerName,
 		provisioner:     provisioner,
+		optionsCache:    newOptionsCache(defaultOptionsCacheSize),
 	}
 
 	obcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -112,10 +352,30 @@ func (c *obcController) Start(stopCh <-chan struct{}) error {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
 
-	if !cache.WaitForCacheSync(stopCh, c.obcHasSynced, c.obHasSynced) {
+	hasSynced := []cache.InformerSynced{c.obcHasSynced, c.obHasSynced}
+	if c.secretHasSynced != nil {
+		hasSynced = append(hasSynced, c.secretHasSynced)
+	}
+	if c.configMapHasSynced != nil {
+		hasSynced = append(hasSynced, c.configMapHasSynced)
+	}
+	if c.classHasSynced != nil {
+		hasSynced = append(hasSynced, c.classHasSynced)
+	}
+	if !cache.WaitForCacheSync(stopCh, hasSynced...) {
 		return fmt.Errorf("failed to waith for caches to sync ")
 	}
-	go wait.Until(c.runWorker, time.Second, stopCh)
+	atomic.StoreInt32(&c.cachesSynced, 1)
+	workers := c.workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+	if c.sweeper != nil {
+		go wait.Until(c.sweeper.sweep, c.sweeper.policy.Interval, stopCh)
+	}
 
 	<-stopCh
 	return nil
@@ -128,6 +388,415 @@ func (c *obcController) SetLabels(labels map[string]string) {
 	}
 }
 
+// replace the default provisioner label selector used to scope list operations.
+func (c *obcController) SetLabelSelector(selector labels.Selector) {
+	c.labelSelector = selector
+}
+
+// select how the names of generated OBs are derived.
+func (c *obcController) SetObjectBucketNaming(naming ObjectBucketNaming) {
+	c.obNaming = naming
+}
+
+// select how suspicious connections returned by the provisioner are handled.
+func (c *obcController) SetConnectionValidation(v ConnectionValidation) {
+	c.connValidation = v
+}
+
+// select how the names of generated ConfigMaps and Secrets are derived.
+func (c *obcController) SetArtifactNaming(naming ArtifactNaming) {
+	c.artifactNaming = naming
+}
+
+// select whether the Secret and ConfigMaps of deleted claims whose bucket is retained are kept.
+func (c *obcController) SetArtifactReclaimPolicy(policy ArtifactReclaimPolicy) {
+	c.artifactReclaim = policy
+}
+
+// select whether the Secret and ConfigMaps left over by deleted claims are reclaimed by claims of the same name.
+func (c *obcController) SetStaleArtifactPolicy(policy StaleArtifactPolicy) {
+	c.staleArtifacts = policy
+}
+
+// set the order in which the resources of deleted claims are deleted or released.
+func (c *obcController) SetDeletionSequence(sequence []DeletionStep) {
+	c.deletionSequence = sequence
+}
+
+// select whether the OB of a claim being provisioned is created before or after its Secret and ConfigMap.
+func (c *obcController) SetCreationOrder(order CreationOrder) {
+	c.creationOrder = order
+}
+
+// set the finalizers of other controllers added to provisioned claims and their OBs.
+func (c *obcController) SetExternalFinalizers(finalizers []string) {
+	c.externalFinalizers = finalizers
+}
+
+// set how often the connection of bound claims is verified again, 0 to verify it only when the claim is bound.
+func (c *obcController) SetReverificationInterval(interval time.Duration) {
+	c.reverifyInterval = interval
+}
+
+// set how often the buckets of bound claims are checked for drift, 0 to never check them.
+func (c *obcController) SetDriftCheckInterval(interval time.Duration) {
+	c.driftCheckInterval = interval
+}
+
+// set how long the bucket of a deleted claim may take to drain, 0 for defaultDrainTimeout.
+func (c *obcController) SetDrainTimeout(timeout time.Duration) {
+	c.drainTimeout = timeout
+}
+
+// set the number of storage classes whose parsed parameters are cached, 0 to disable the cache.
+func (c *obcController) SetOptionsCacheSize(size int) {
+	if size <= 0 {
+		c.optionsCache = nil
+		return
+	}
+	c.optionsCache = newOptionsCache(size)
+}
+
+// set the maximum number of Provision calls in flight, 0 for no limit.
+func (c *obcController) SetMaxInFlightProvisions(max int) {
+	if max <= 0 {
+		c.provisionSlots = nil
+		return
+	}
+	c.provisionSlots = make(chan struct{}, max)
+}
+
+// set the number of workers reconciling claims concurrently.
+func (c *obcController) SetWorkers(workers int) {
+	c.workers = workers
+}
+
+// set the logger receiving a record of each mutation, with actor as their actor. nil discards the records.
+func (c *obcController) SetAuditLogger(l api.AuditLogger, actor string) {
+	if l == nil {
+		c.auditor = nil
+		return
+	}
+	c.auditor = &auditor{logger: l, actor: actor}
+}
+
+// set the transformer rewriting endpoints before they are published to claims.
+func (c *obcController) SetEndpointTransformer(t EndpointTransformer) {
+	c.endpointTransformer = t
+}
+
+// set the verifier checking the connection of provisioned claims before they are marked Bound.
+func (c *obcController) SetConnectionVerifier(v ConnectionVerifier, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultVerifyTimeout
+	}
+	c.connVerifier = v
+	c.verifyTimeout = timeout
+}
+
+// select when claims failing to provision are marked Failed.
+func (c *obcController) SetFailurePolicy(policy FailurePolicy) {
+	c.failurePolicy = policy
+}
+
+// select how claims are handled once the retry budget of their creates or deletes is spent.
+func (c *obcController) SetTimeoutPolicy(policy TimeoutPolicy) {
+	c.timeoutPolicy = policy
+}
+
+// set the classifier of untyped errors returned by Provision and Grant.
+func (c *obcController) SetErrorClassifier(classifier ErrorClassifier) {
+	c.errorClassifier = classifier
+}
+
+// set the annotator of claims' generated Secrets.
+func (c *obcController) SetSecretAnnotator(a SecretAnnotator) {
+	c.secretAnnotator = a
+}
+
+// set the binder of the ServiceAccounts created for claims.
+func (c *obcController) SetServiceAccountBinder(b ServiceAccountBinder) {
+	c.serviceAccountBinder = b
+}
+
+// set the hook called once claims are bound.
+func (c *obcController) SetOnBoundHook(h OnBoundHook) {
+	c.onBound = h
+}
+
+// set the hook called once the teardown of deleted claims completed.
+func (c *obcController) SetOnDeletedHook(h OnDeletedHook) {
+	c.onDeleted = h
+}
+
+// set the prefixes of the claims' annotations mirrored onto their OBs.
+func (c *obcController) SetAnnotationPropagation(prefixes []string) {
+	c.propagatedAnnotationPrefixes = prefixes
+}
+
+// set the storage class parameters claims may override with their AdditionalConfig.
+func (c *obcController) SetClaimParameterAllowlist(keys []string) {
+	if keys == nil {
+		c.claimParameterAllowlist = nil
+		return
+	}
+	c.claimParameterAllowlist = make(map[string]bool, len(keys))
+	for _, k := range keys {
+		c.claimParameterAllowlist[k] = true
+	}
+}
+
+// enable or disable the defaulting of a published BucketPort of 0 from the scheme of the host.
+func (c *obcController) SetBucketPortDefaulting(enabled bool) {
+	c.keepZeroBucketPort = !enabled
+}
+
+// enable or disable the update of the Connection of an OB left by a previous attempt when it is stale.
+func (c *obcController) SetStaleConnectionUpdate(enabled bool) {
+	c.updateStaleConnection = enabled
+}
+
+// pause or resume provisioning of all claims. Deletes are not affected.
+func (c *obcController) SetProvisioningPaused(paused bool) {
+	var v int32
+	if paused {
+		v = 1
+	}
+	atomic.StoreInt32(&c.provisioningPaused, v)
+}
+
+// pause or resume all reconciles, deletes included. Claims are requeued while paused and all claims are enqueued again
+// on resume, so that no event is lost.
+func (c *obcController) SetReconcilesPaused(paused bool) {
+	if !paused {
+		if atomic.CompareAndSwapInt32(&c.reconcilesPaused, 1, 0) {
+			c.enqueueAll()
+		}
+		return
+	}
+	atomic.StoreInt32(&c.reconcilesPaused, 1)
+}
+
+// return whether all reconciles are paused.
+func (c *obcController) ReconcilesPaused() bool {
+	return atomic.LoadInt32(&c.reconcilesPaused) != 0
+}
+
+// enqueueAll adds the key of every claim in the informer cache matching the label selector to the work queue.
+func (c *obcController) enqueueAll() {
+	obcs, err := c.obcLister.List(c.labelSelector)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error listing claims to enqueue: %v", err))
+		return
+	}
+	for _, obc := range obcs {
+		c.queue.Add(obc.Namespace + "/" + obc.Name)
+	}
+}
+
+// set the length of the random suffix of generated bucket names.
+func (c *obcController) SetBucketNameSuffixLength(n int) {
+	c.bucketNameSuffixLen = n
+}
+
+// set the prefix of the generated bucket names of claims which request no name, "" for none.
+func (c *obcController) SetDefaultBucketNamePrefix(prefix string) {
+	c.defaultBucketNamePrefix = prefix
+}
+
+// set the bucket naming rules of the provider type, overriding its default profile.
+func (c *obcController) SetBucketNameProfile(providerType api.ProviderType, profile BucketNameProfile) {
+	if c.bucketNameProfiles == nil {
+		c.bucketNameProfiles = make(map[api.ProviderType]BucketNameProfile)
+	}
+	c.bucketNameProfiles[providerType] = profile
+}
+
+// bucketNameProfile returns the bucket naming rules of the provider type, S3 if empty.
+func (c *obcController) bucketNameProfile(providerType api.ProviderType) BucketNameProfile {
+	if providerType == "" {
+		providerType = api.ProviderTypeS3
+	}
+	if profile, ok := c.bucketNameProfiles[providerType]; ok {
+		return profile
+	}
+	return bucketNameProfiles[providerType]
+}
+
+// register an additional provisioner, handling the claims of storage classes naming it.
+func (c *obcController) RegisterProvisioner(name string, p api.Provisioner) error {
+	c.provisionersMu.Lock()
+	defer c.provisionersMu.Unlock()
+	if _, ok := c.provisioners[name]; ok || name == c.provisionerName {
+		return fmt.Errorf("provisioner %q is already registered", name)
+	}
+	if c.provisioners == nil {
+		c.provisioners = make(map[string]api.Provisioner)
+	}
+	c.provisioners[name] = p
+	return nil
+}
+
+// SetAuthenticationPolicy selects what happens when the named provisioner returns no credentials.
+func (c *obcController) SetAuthenticationPolicy(name string, policy AuthenticationPolicy) {
+	c.provisionersMu.Lock()
+	defer c.provisionersMu.Unlock()
+	if c.authPolicies == nil {
+		c.authPolicies = make(map[string]AuthenticationPolicy)
+	}
+	c.authPolicies[name] = policy
+}
+
+// authPolicyFor returns the AuthenticationPolicy of the named provisioner.
+func (c *obcController) authPolicyFor(name string) AuthenticationPolicy {
+	c.provisionersMu.RLock()
+	defer c.provisionersMu.RUnlock()
+	return c.authPolicies[name]
+}
+
+// authPolicyForObjectBucket returns the AuthenticationPolicy of the provisioner named by the ob's storage class, or the
+// reconciler's own provisioner if the class cannot be read.
+func (c *obcController) authPolicyForObjectBucket(ob *v1alpha1.ObjectBucket) AuthenticationPolicy {
+	class, err := storageClassForObjectBucket(ob, c.clientset)
+	if err != nil {
+		return c.authPolicyFor(c.provisionerName)
+	}
+	return c.authPolicyFor(class.Provisioner)
+}
+
+// provisionerFor returns the implementation of the named provisioner, or nil if it is not registered.
+func (c *obcController) provisionerFor(name string) api.Provisioner {
+	if name == c.provisionerName {
+		return c.provisioner
+	}
+	c.provisionersMu.RLock()
+	defer c.provisionersMu.RUnlock()
+	return c.provisioners[name]
+}
+
+// provisionerForObjectBucket returns the implementation of the provisioner named by the ob's storage class. The
+// reconciler's own provisioner is returned if the class cannot be read or names no registered provisioner, so that
+// bound buckets can always be released.
+func (c *obcController) provisionerForObjectBucket(ob *v1alpha1.ObjectBucket) api.Provisioner {
+	class, err := storageClassForObjectBucket(ob, c.clientset)
+	if err != nil {
+		logD.Info("unable to get storage class, using the default provisioner", "ob", ob.Name, "error", err.Error())
+		return c.provisioner
+	}
+	if p := c.provisionerFor(class.Provisioner); p != nil {
+		return p
+	}
+	return c.provisioner
+}
+
+// Ready returns nil once the informer caches have synced and the registered provisioners implementing
+// api.HealthChecker report themselves healthy.
+func (c *obcController) Ready() error {
+	if atomic.LoadInt32(&c.cachesSynced) == 0 {
+		return fmt.Errorf("informer caches not synced")
+	}
+	if c.ReconcilesPaused() {
+		return fmt.Errorf("reconciles are paused")
+	}
+	provisioners := map[string]api.Provisioner{c.provisionerName: c.provisioner}
+	c.provisionersMu.RLock()
+	for name, p := range c.provisioners {
+		provisioners[name] = p
+	}
+	c.provisionersMu.RUnlock()
+	for name, p := range provisioners {
+		if hc, ok := p.(api.HealthChecker); ok {
+			if err := hc.HealthCheck(); err != nil {
+				return fmt.Errorf("provisioner %q health check failed: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// provisioningPausedFor returns true and the reason if provisioning is paused for the claim, either controller-wide or
+// by the claim's ReconcilePauseAnnotation.
+func (c *obcController) provisioningPausedFor(obc *v1alpha1.ObjectBucketClaim) (bool, string) {
+	if atomic.LoadInt32(&c.provisioningPaused) != 0 {
+		return true, "provisioning is paused for all claims by the provisioner"
+	}
+	if obc.Annotations[api.ReconcilePauseAnnotation] == "true" {
+		return true, fmt.Sprintf("provisioning is paused by the %q annotation", api.ReconcilePauseAnnotation)
+	}
+	return false, ""
+}
+
+// watchSecrets requeues the owning claim when a generated secret is deleted so that it can be recreated.
+func (c *obcController) watchSecrets(secretInformer coreinformers.SecretInformer) {
+	c.secretLister = secretInformer.Lister()
+	c.secretHasSynced = secretInformer.Informer().HasSynced
+	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.enqueueSecretOwner,
+	})
+}
+
+// watchConfigMaps caches the generated configMaps, so that reconciles of bound claims read them from the cache.
+func (c *obcController) watchConfigMaps(configMapInformer coreinformers.ConfigMapInformer) {
+	c.configMapLister = configMapInformer.Lister()
+	c.configMapHasSynced = configMapInformer.Informer().HasSynced
+}
+
+func (c *obcController) enqueueSecretOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("expected Secret but got %#v", obj))
+		return
+	}
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == v1alpha1.ObjectBucketClaimKind && ref.APIVersion == v1alpha1.SchemeGroupVersion.String() {
+			c.queue.AddRateLimited(secret.Namespace + "/" + ref.Name)
+		}
+	}
+}
+
+// watchStorageClasses requeues the bound claims of a storage class when its parameters change, so that their
+// ConfigMaps reflect the new parameters. Buckets are not re-provisioned.
+func (c *obcController) watchStorageClasses(classInformer storageinformers.StorageClassInformer) {
+	c.classLister = classInformer.Lister()
+	c.classHasSynced = classInformer.Informer().HasSynced
+	classInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			oldClass, ok := old.(*storagev1.StorageClass)
+			if !ok {
+				return
+			}
+			newClass, ok := new.(*storagev1.StorageClass)
+			if !ok || reflect.DeepEqual(oldClass.Parameters, newClass.Parameters) {
+				return
+			}
+			if c.optionsCache != nil {
+				c.optionsCache.invalidate(newClass.Name)
+			}
+			if !c.servesStorageClass(newClass) {
+				return
+			}
+			c.enqueueClaimsOfClass(newClass.Name)
+		},
+	})
+}
+
+// enqueueClaimsOfClass requeues the bound claims of the named storage class.
+func (c *obcController) enqueueClaimsOfClass(className string) {
+	obcs, err := c.listObjectBucketClaims()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error listing claims of storage class %q: %v", className, err))
+		return
+	}
+	for _, obc := range obcs {
+		if obc.Spec.StorageClassName == className && obc.Spec.ObjectBucketName != "" {
+			c.enqueueOBC(obc)
+		}
+	}
+}
+
 func (c *obcController) enqueueOBC(obj interface{}) {
 	var key string
 	var err error
@@ -138,6 +807,20 @@ func (c *obcController) enqueueOBC(obj interface{}) {
 	c.queue.AddRateLimited(key)
 }
 
+// queue the claim for an immediate reconcile, eg. after an external change it is not watching.
+func (c *obcController) EnqueueOBC(namespace, name string) error {
+	if namespace == "" || name == "" {
+		return fmt.Errorf("namespace and name of the claim are required, got %q and %q", namespace, name)
+	}
+	c.queue.Add(namespace + "/" + name)
+	return nil
+}
+
+// return the names of the objects generated for a bound claim.
+func (c *obcController) BindingResult(namespace, name string) (*BindingResult, error) {
+	return BindingResultFor(namespace, name, c.artifactNaming, c.clientset, c.libClientset)
+}
+
 func (c *obcController) runWorker() {
 	for c.processNextItemInQueue() {
 	}
@@ -176,6 +859,22 @@ func (c *obcController) processNextItemInQueue() bool {
 		// Run the syncHandler, passing it the namespace/name string of the
 		// Foo resource to be synced.
 		if err := c.syncHandler(key); err != nil {
+			if rq, ok := err.(*requeueAfterError); ok {
+				// Not an error, the claim is waiting. Reset its backoff and check back after the requested delay.
+				c.queue.Forget(obj)
+				c.queue.AddAfter(key, rq.delay)
+				return nil
+			}
+			if _, ok := err.(*reconcilesPausedError); ok {
+				// Not an error either, back off until reconciles are resumed, which enqueues the claim again.
+				c.queue.AddRateLimited(key)
+				return nil
+			}
+			if pErr.IsPermission(err) {
+				// Retrying won't help until the RBAC is fixed. The claim is re-checked on the next resync.
+				c.queue.Forget(obj)
+				return fmt.Errorf("error syncing '%s': %s, not requeuing", key, err.Error())
+			}
 			// Put the item back on the workqueue to handle any transient errors.
 			c.queue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
@@ -197,12 +896,25 @@ func (c *obcController) processNextItemInQueue() bool {
 // Note: the obc obtained from the key is not expected to be nil. In other words, this func is
 //   not called when informers detect an object is missing and trigger a formal delete event.
 //   Instead, delete is indicated by the deletionTimestamp being non-nil on an update event.
-func (c *obcController) syncHandler(key string) error {
+func (c *obcController) syncHandler(key string) (err error) {
 
 	setLoggersWithRequest(key)
 	logD.Info("new Reconcile iteration")
 
+	if c.ReconcilesPaused() {
+		logD.Info("reconciles are paused, requeuing")
+		return &reconcilesPausedError{}
+	}
+
 	obc, err := claimForKey(key, c.libClientset)
+	if errors.IsNotFound(err) {
+		// the claim is gone and its generated resources are being garbage collected, nothing to do
+		logD.Info("claim not found, skipping")
+		c.forgetProvisionFailures(key)
+		c.forgetVerification(key)
+		c.forgetDriftCheck(key)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("request key %q: %v", key, err)
 	}
@@ -217,10 +929,16 @@ func (c *obcController) syncHandler(key string) error {
 		// Delete or Revoke Bucket
 		// ***********************
 		log.Info("OBC deleted, proceeding with cleanup")
+		c.forgetProvisionFailures(key)
+		c.forgetVerification(key)
+		c.forgetDriftCheck(key)
 		err = c.handleDeleteClaim(key, obc)
-		if err != nil {
+		if _, waiting := err.(*requeueAfterError); err != nil && !waiting {
 			log.Error(err, "error cleaning up OBC", "name", key)
 		}
+		if isRetryExhausted(err) && c.timeoutPolicy == TimeoutFail {
+			return c.failTimedOutDelete(obc, err)
+		}
 		return err
 	}
 
@@ -229,31 +947,92 @@ func (c *obcController) syncHandler(key string) error {
 	// *******************************************************
 	if !shouldProvision(obc) {
 		log.Info("skipping provision")
+		return c.reconcileBoundClaim(obc)
+	}
+
+	// claims of other provisioners are ignored rather than failed, before anything is written to them
+	class, err := storageClassForClaim(c.classLister, obc)
+	if err == nil && !c.servesStorageClass(class) {
+		log.Info("unsupported provisioner, skipping", "got", class.Provisioner)
 		return nil
 	}
 
-	// update the OBC's status to pending before any provisioning related errors can occur
-	obc, err = updateObjectBucketClaimPhase(
-		c.libClientset,
-		obc,
-		v1alpha1.ObjectBucketClaimStatusPhasePending,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error updating OBC status:", err)
+	// a claim which failed terminally is not retried until its spec changes
+	if deadLettered(obc) {
+		log.Info("claim failed terminally, skipping until its spec changes", "generation", obc.Generation)
+		return nil
 	}
 
-	class, err := storageClassForClaim(c.clientset, obc)
+	if paused, reason := c.provisioningPausedFor(obc); paused {
+		log.Info("skipping provision", "reason", reason)
+		c.recorder.Event(obc, corev1.EventTypeNormal, reasonProvisioningPaused, reason)
+		return &requeueAfterError{delay: defaultPauseRequeueDelay, reason: "provisioning paused"}
+	}
+
+	// status mutations are accumulated and written once the reconcile ends, whatever its outcome
+	status := &statusUpdates{}
+	defer func() {
+		if flushErr := status.flush(c.libClientset, defaultRetryBaseInterval, defaultRetryTimeout); flushErr != nil {
+			log.Error(flushErr, "error flushing status updates")
+			if err == nil {
+				err = flushErr
+			}
+		}
+	}()
+
+	// the OBC's status is pending unless provisioning completes
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhasePending)
 	if err != nil {
 		return err
 	}
-	if !c.supportedProvisioner(class.Provisioner) {
-		log.Info("unsupported provisioner", "got", class.Provisioner)
-		return nil
-	}
 
 	// By now, we should know that the OBC matches our provisioner, lacks an OB, and thus requires provisioning
-	err = c.handleProvisionClaim(key, obc, class)
+	err = c.handleProvisionClaim(key, obc, class, status)
+	// a claim waiting on the provisioner is not failing, and writing to it would requeue it before its delay
+	_, waiting := err.(*requeueAfterError)
+	if !waiting {
+		if recordErr := c.recordProvisionAttempt(key, err, time.Now()); recordErr != nil {
+			log.Error(recordErr, "error recording provisioning attempt")
+		}
+	}
+	switch {
+	case err == nil:
+		c.forgetProvisionFailures(key)
+	case pErr.IsPermission(err):
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonPermissionDenied, err.Error())
+		status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseFailed)
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonPermissionDenied,
+			Message: err.Error(),
+		})
+	case isRetryExhausted(err) && c.timeoutPolicy == TimeoutFail:
+		status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseFailed)
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonTimedOut,
+			Message: err.Error(),
+		})
+	case isRetryExhausted(err) && c.timeoutPolicy == TimeoutRequeue:
+		// the claim stays pending however long it keeps timing out
+	case isRetryable(err):
+		// the provisioner expects the error to clear, the claim stays pending until it does
+	case isTerminal(err):
+		// retrying cannot fix the error, the claim is not requeued until its spec changes
+		c.deadLetter(key, obc, err, status)
+		err = nil
+	case !waiting && c.recordProvisionFailure(key, err, time.Now()):
+		// transient errors leave the claim pending until the failure policy gives up on it
+		status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseFailed)
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonProvisionFailed,
+			Message: err.Error(),
+		})
+	}
 
 	// If handleReconcile() errors, the request will be re-queued.  In the distant future, we will likely want some ignorable error types in order to skip re-queuing
 	return err
@@ -261,12 +1040,18 @@ func (c *obcController) syncHandler(key string) error {
 
 // handleProvision is an extraction of the core provisioning process in order to defer clean up
 // on a provisioning failure
-func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) (err error) {
+func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass, status *statusUpdates) (err error) {
 
 	var (
 		ob        *v1alpha1.ObjectBucket
 		secret    *corev1.Secret
 		configMap *corev1.ConfigMap
+		// preexisting is an unbound OB created by an admin, which is adopted rather than created
+		preexisting *v1alpha1.ObjectBucket
+		// provisionedAt is when Provision created the bucket, nil if it was not called
+		provisionedAt *metav1.Time
+		// serviceAccount is created by the ServiceAccountBinder, if any
+		serviceAccount *corev1.ServiceAccount
 	)
 
 	// set finalizer in OBC so that resources cleaned up is controlled when the obc is deleted
@@ -274,6 +1059,9 @@ func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBuc
 		return err
 	}
 
+	// dispatch to the provisioner named by the claim's class
+	p := c.provisionerFor(class.Provisioner)
+
 	// If the storage class contains the name of the bucket then we create access
 	// to an existing bucket. If the bucket name does not appear in the storage
 	// class then we dynamically provision a new bucket.
@@ -282,27 +1070,95 @@ func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBuc
 	// Following getting the claim, if any provisioning task fails, clean up provisioned artifacts.
 	// It is assumed that if the get claim fails, no resources were generated to begin with.
 	defer func() {
-		if err != nil {
+		// a bucket still being created is not a failure, its request is repeated as is
+		if _, waiting := err.(*requeueAfterError); err != nil && !waiting {
 			log.Error(err, "cleaning up reconcile artifacts")
 			if !pErr.IsBucketExists(err) && ob != nil && isDynamicProvisioning {
 				log.Info("deleting storage artifacts")
-				if err = c.provisioner.Delete(ob); err != nil {
-					log.Error(err, "error deleting storage artifacts")
+				// do not overwrite err, the caller needs the original error
+				if delErr := p.Delete(ob); delErr != nil {
+					log.Error(delErr, "error deleting storage artifacts")
 				}
 			}
-			_ = c.deleteResources(ob, configMap, secret, nil)
+			// an adopted OB was not created by the library and is never deleted, unless an interrupted sync created it
+			obToDelete := ob
+			if preexisting != nil && !isBoundToClaim(preexisting, obc) {
+				obToDelete = nil
+			}
+			if cleanupErr := c.cleanupResources(obToDelete, configMap, secret); cleanupErr != nil {
+				log.Error(cleanupErr, "error cleaning up reconcile artifacts")
+			}
+			if serviceAccount != nil {
+				_ = releaseServiceAccount(serviceAccount.Namespace, serviceAccount.Name, c.clientset, c.auditor)
+			}
 		}
 	}()
 
-	bucketName := class.Parameters[v1alpha1.StorageClassBucket]
+	params, err := c.parametersFor(obc, class)
+	if err != nil {
+		return &terminalError{err}
+	}
+	provisionOptions, err := c.provisionOptionsFor(obc, class, params)
+	if err != nil {
+		return newTerminalError("invalid parameters in StorageClass %q: %v", class.Name, err)
+	}
+	if !isDynamicProvisioning && provisionOptions.ObjectLock != nil {
+		return newTerminalError("object-lock can only be requested for new buckets")
+	}
+	if !isDynamicProvisioning && provisionOptions.Lifecycle != nil {
+		return newTerminalError("lifecycle rules can only be requested for new buckets")
+	}
+	if !isDynamicProvisioning && provisionOptions.SSECustomerKeySecret != nil {
+		return newTerminalError("customer-provided encryption keys can only be requested for new buckets")
+	}
+	if !isDynamicProvisioning && provisionOptions.StorageTier != "" {
+		return newTerminalError("a storage tier can only be requested for new buckets")
+	}
+	if !isDynamicProvisioning && (provisionOptions.CORS != nil || provisionOptions.CORSConfigMap != nil) {
+		return newTerminalError("CORS rules can only be requested for new buckets")
+	}
+	if err = validateCapabilities(p, provisionOptions); err != nil {
+		return &terminalError{err}
+	}
+	if _, err = ownerReferencesFor(obc); err != nil {
+		return &terminalError{err}
+	}
+	reclaimPolicy, err := c.reclaimPolicyFor(obc, class)
+	if err != nil {
+		return err
+	}
+	// the key and CORS rule are read before anything is provisioned, so that a missing secret fails the claim early
+	sseCustomerKey, err := c.resolveReferencedOptions(provisionOptions)
+	if err != nil {
+		return err
+	}
+
+	bucketName := params[v1alpha1.StorageClassBucket]
+	nameProfile := c.bucketNameProfile(provisionOptions.ProviderType)
 	if isDynamicProvisioning {
-		bucketName, err = composeBucketName(obc)
+		bucketName, err = c.reserveBucketName(obc, provisionOptions.BucketNameTemplate, c.bucketNamePrefixFor(provisionOptions), nameProfile)
+		if isTerminal(err) {
+			return err
+		}
 		if err != nil {
-			return fmt.Errorf("error composing bucket name: %v", err)
+			return annotateError(err, "error composing bucket name")
 		}
 	}
 	if len(bucketName) == 0 {
-		return fmt.Errorf("bucket name missing")
+		return newTerminalError("bucket name missing")
+	}
+	if isDynamicProvisioning {
+		if err = validateBucketName(p, bucketName, nameProfile); err != nil {
+			return err
+		}
+	}
+	// claims of a shared bucket are confined to a prefix of their own. Shared buckets are existing buckets, so
+	// deleting the claim calls Revoke and never deletes the bucket.
+	var prefix string
+	if provisionOptions.SharedBucket {
+		if prefix, err = c.reserveBucketPrefix(obc); err != nil {
+			return annotateError(err, "error composing bucket prefix")
+		}
 	}
 
 	// Re-Get the claim in order to shorten the race condition where the claim was deleted after Reconcile() started
@@ -314,11 +1170,22 @@ func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBuc
 		return err
 	}
 
+	// an OB may have been pre-created by an admin, eg. when migrating claims from another cluster. Checked before
+	// provisioning so that a clash with another claim's OB fails without creating anything.
+	preexisting, err = c.adoptableObjectBucket(key, obc, bucketName)
+	if err != nil {
+		return err
+	}
+
 	options := &api.BucketOptions{
-		ReclaimPolicy:     class.ReclaimPolicy,
+		ReclaimPolicy:     reclaimPolicy,
 		BucketName:        bucketName,
+		BucketPrefix:      prefix,
+		SSECustomerKey:    sseCustomerKey,
+		IdempotencyKey:    idempotencyKey(obc, bucketName, preexisting),
 		ObjectBucketClaim: obc.DeepCopy(),
-		Parameters:        class.Parameters,
+		Parameters:        params,
+		ProvisionOptions:  *provisionOptions,
 	}
 
 	verb := "provisioning"
@@ -328,67 +1195,215 @@ func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBuc
 	logD.Info(verb, "bucket", options.BucketName)
 
 	if isDynamicProvisioning {
-		ob, err = c.provisioner.Provision(options)
+		ob, err = c.existingBucket(p, options)
+		if err == nil && ob == nil {
+			release, ok := c.acquireProvisionSlot()
+			if !ok {
+				log.Info("maximum number of provisions in flight, requeuing", "bucket", options.BucketName)
+				return &requeueAfterError{delay: defaultProvisionSlotRequeueDelay, reason: "waiting for a provision slot"}
+			}
+			ob, err = p.Provision(options)
+			release()
+			if err == nil {
+				now := metav1.Now()
+				provisionedAt = &now
+			}
+		}
 	} else {
-		ob, err = c.provisioner.Grant(options)
+		ob, err = p.Grant(options)
+	}
+	if delay, inProgress := pErr.RetryAfter(err); inProgress {
+		if delay <= 0 {
+			delay = defaultInProgressRequeueDelay
+		}
+		log.Info("provisioner is still "+verb+" bucket", "bucket", options.BucketName, "retryAfter", delay)
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonInProgress,
+			Message: err.Error(),
+		})
+		return &requeueAfterError{delay: delay, reason: err.Error()}
 	}
 	if err != nil {
-		return fmt.Errorf("error %s bucket: %v", verb, err)
+		return c.classifyProvisionerError(err, "error "+verb+" bucket")
 	} else if ob == (&v1alpha1.ObjectBucket{}) {
 		return fmt.Errorf("provisioner returned nil/empty object bucket")
 	}
-
-	// create Secret and ConfigMap
-	secret, err = createSecret(
-		obc,
-		ob.Spec.Authentication,
-		c.provisionerLabels,
-		c.clientset,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error creating secret for OBC: %v", err)
+	if err = c.validateConnection(obc, ob.Spec.Connection); err != nil {
+		return err
+	}
+	if err = c.checkBucketReady(p, obc, ob, status); err != nil {
+		return err
 	}
-	configMap, err = createConfigMap(
+	if prefix != "" && ob.Spec.Connection != nil && ob.Spec.Endpoint != nil {
+		ob.Spec.Endpoint.BucketPrefix = prefix
+	}
+
+	// written immediately rather than batched, so that the binding progress is observable. If binding fails the
+	// batched update returns the claim to pending.
+	obc, err = updateObjectBucketClaimPhase(
+		c.libClientset,
 		obc,
-		ob.Spec.Endpoint,
-		c.provisionerLabels,
-		c.clientset,
+		v1alpha1.ObjectBucketClaimStatusPhaseBinding,
+		false,
 		defaultRetryBaseInterval,
 		defaultRetryTimeout)
 	if err != nil {
-		return fmt.Errorf("error creating configmap for OBC: %v", err)
+		return annotateError(err, "error updating OBC status")
 	}
 
-	// Create OB
-	// Note: do not move ob create/update calls before secret or vice versa.
-	//   spec.Authentication is lost after create/update, which break secret creation
-	setObjectBucketName(ob, key)
-	ob.Spec.StorageClassName = obc.Spec.StorageClassName
-	ob.Spec.ClaimRef, err = claimRefForKey(key, c.libClientset)
-	ob.Spec.ReclaimPolicy = options.ReclaimPolicy
-	ob.SetFinalizers([]string{finalizer})
-	ob.SetLabels(c.provisionerLabels)
-
-	ob, err = createObjectBucket(
-		ob,
-		c.libClientset,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
+	// create Secret and ConfigMap
+	ep, err := c.publishedEndpoint(ob.Spec.Endpoint)
 	if err != nil {
-		return fmt.Errorf("error creating OB %q: %v", ob.Name, err)
+		return err
+	}
+	budget, cancel := newRetryBudget()
+	defer cancel()
+	if c.staleArtifacts == StaleArtifactReclaim {
+		if err = reclaimStaleArtifacts(obc, c.artifactNaming.Name(obc.Name), c.clientset); err != nil {
+			return annotateError(err, "error reclaiming stale artifacts for OBC")
+		}
+	}
+	auth := ob.Spec.Authentication
+	skipSecret := false
+	if isEmptyAuthentication(auth) {
+		policy := c.authPolicyFor(class.Provisioner)
+		if policy == AuthenticationSkipSecret && options.SkipConfigMap {
+			// without a ConfigMap the secret is where the endpoint is published
+			policy = AuthenticationCreateEmpty
+		}
+		switch policy {
+		case AuthenticationFail:
+			return fmt.Errorf("provisioner returned no credentials")
+		case AuthenticationSkipSecret:
+			log.Info("provisioner returned no credentials, skipping secret")
+			skipSecret = true
+		default:
+			if auth == nil {
+				auth = &v1alpha1.Authentication{}
+			}
+		}
+	}
+	// the status of the returned OB is not written on create, only the provider's status is kept
+	providerStatus := ob.Status.ProviderStatus
+	// the Secret and ConfigMap are written from auth, as spec.Authentication is lost once the OB is created or updated
+	createArtifacts := func() error {
+		if !skipSecret {
+			secret, err = createSecret(
+				budget,
+				obc,
+				c.artifactNaming.Name(obc.Name),
+				ep,
+				auth,
+				&options.ProvisionOptions,
+				c.provisionerLabels,
+				c.clientset,
+				defaultRetryBaseInterval,
+				c.auditor)
+			if err != nil {
+				return annotateError(err, "error creating secret for OBC")
+			}
+		}
+		if !options.SkipConfigMap {
+			configMap, err = createConfigMap(
+				budget,
+				obc,
+				c.artifactNaming.Name(obc.Name),
+				ep,
+				auth,
+				&options.ProvisionOptions,
+				c.provisionerLabels,
+				c.clientset,
+				defaultRetryBaseInterval,
+				c.auditor)
+			if err != nil {
+				return annotateError(err, "error creating configmap for OBC")
+			}
+		}
+		if secret != nil && c.secretAnnotator != nil {
+			if err = c.annotateClaimSecret(obc, ob, secret); err != nil {
+				return err
+			}
+		}
+		if c.serviceAccountBinder != nil {
+			if serviceAccount, err = c.bindServiceAccount(budget, obc, ob); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	createOB := func() error {
+		setObjectBucketName(ob, key, bucketName, c.obNaming)
+		ob.Spec.StorageClassName = class.Name
+		ob.Spec.ClaimRef, err = claimRefForKey(key, c.libClientset)
+		ob.Spec.ReclaimPolicy = options.ReclaimPolicy
+		// the claim's request is recorded as is, the bucket name it resolved to is in the Endpoint
+		ob.Spec.RequestedBucketName = obc.Spec.BucketName
+		ob.Spec.RequestedGenerateBucketName = obc.Spec.GenerateBucketName
+		ob.SetLabels(c.provisionerLabels)
+		metav1.SetMetaDataAnnotation(&ob.ObjectMeta, api.IdempotencyKeyAnnotation, options.IdempotencyKey)
+		propagateAnnotations(ob, obc, c.propagatedAnnotationPrefixes)
+
+		// finalizers set by the provisioner on the returned OB are kept, after the library's own, followed by the
+		// external finalizers
+		obName := ob.Name
+		obFinalizers := ob.GetFinalizers()
+		for _, f := range c.externalFinalizers {
+			if !hasFinalizer(obFinalizers, f) {
+				obFinalizers = append(obFinalizers, f)
+			}
+		}
+		if preexisting != nil {
+			log.Info("adopting existing ObjectBucket", "ob", obName)
+			ob, err = adoptObjectBucket(
+				budget,
+				preexisting,
+				ob,
+				obFinalizers,
+				c.libClientset,
+				defaultRetryBaseInterval,
+				c.auditor)
+			if err != nil {
+				return annotateError(err, fmt.Sprintf("error adopting OB %q", obName))
+			}
+		} else {
+			ob, err = createObjectBucket(
+				budget,
+				ob,
+				obFinalizers,
+				c.updateStaleConnection,
+				c.libClientset,
+				defaultRetryBaseInterval,
+				c.auditor)
+			if err != nil {
+				return annotateError(err, fmt.Sprintf("error creating OB %q", obName))
+			}
+		}
+		return nil
+	}
+	// either way an interrupted sync is completed by the next one: an OB already bound to the claim is adopted, and
+	// artifacts already generated for it are updated in place
+	if c.creationOrder == CreationOrderObjectBucketFirst {
+		if err = createOB(); err == nil {
+			err = createArtifacts()
+		}
+	} else {
+		if err = createArtifacts(); err == nil {
+			err = createOB()
+		}
 	}
-	ob, err = updateObjectBucketPhase(
-		c.libClientset,
-		ob,
-		v1alpha1.ObjectBucketStatusPhaseBound,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
 	if err != nil {
-		return fmt.Errorf("error updating OB %q's status to %q: %v", ob.Name, v1alpha1.ObjectBucketStatusPhaseBound, err)
+		return err
+	}
+	status.setBucketPhase(ob, v1alpha1.ObjectBucketStatusPhaseBound)
+	status.setProviderStatus(providerStatus)
+	if provisionedAt != nil {
+		status.setBucketCreationTimestamp(*provisionedAt)
 	}
 
-	// update OBC
+	// update OBC, recording the defaults it was provisioned with, eg. the default class
+	ApplyDefaults(obc, class)
 	obc.Spec.ObjectBucketName = ob.Name
 	obc.Spec.BucketName = bucketName
 	obc, err = updateClaim(
@@ -397,29 +1412,37 @@ func (c *obcController) handleProvisionClaim(key string, obc *v1alpha1.ObjectBuc
 		defaultRetryBaseInterval,
 		defaultRetryTimeout)
 	if err != nil {
-		return fmt.Errorf("error updating OBC: %v", err)
+		return annotateError(err, "error updating OBC")
 	}
-	obc, err = updateObjectBucketClaimPhase(
-		c.libClientset,
-		obc,
-		v1alpha1.ObjectBucketClaimStatusPhaseBound,
-		defaultRetryBaseInterval,
-		defaultRetryTimeout)
-	if err != nil {
-		return fmt.Errorf("error updating OBC %q's status to: %v", v1alpha1.ObjectBucketClaimStatusPhaseBound, err)
+	if err = c.verifyConnection(obc, ob); err != nil {
+		// the claim is provisioned, only its phase is held back: the next sync verifies it again as a bound claim
+		log.Info("connection verification failed, not marking the claim Bound", "error", err.Error())
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonVerificationFailed,
+			Message: err.Error(),
+		})
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonVerificationFailed, err.Error())
+		return &requeueAfterError{delay: defaultVerifyRequeueDelay, reason: "connection verification failed"}
 	}
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseBound)
+	status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimProvisioned,
+		Status: corev1.ConditionTrue,
+		Reason: reasonBound,
+	})
 
 	log.Info("provisioning succeeded")
 	return nil
 }
 
 // Delete or Revoke access to bucket defined by passed-in key and obc.
-// TODO each delete should retry a few times to mitigate intermittent errors
 func (c *obcController) handleDeleteClaim(key string, obc *v1alpha1.ObjectBucketClaim) error {
 	// Call `Delete` for new (greenfield) buckets with reclaimPolicy == "Delete".
 	// Call `Revoke` for new buckets with reclaimPolicy != "Delete".
 	// Call `Revoke` for existing (brownfield) buckets regardless of reclaimPolicy.
-	ob, cm, secret, err := c.getResourcesFromKey(key)
+	ob, cm, secret, err := c.getResourcesFromKey(key, obc)
 	if err != nil {
 		return err
 	}
@@ -428,9 +1451,19 @@ func (c *obcController) handleDeleteClaim(key string, obc *v1alpha1.ObjectBucket
 	// and/or cm != nil we can delete them
 	if ob == nil {
 		log.Error(nil, "nil ObjectBucket, assuming it has been deleted")
+		if len(pendingClaimFinalizers(obc)) > 0 {
+			return c.waitForClaimFinalizers(obc)
+		}
 		return c.deleteResources(nil, cm, secret, obc)
 	}
 
+	if ob.DeletionTimestamp != nil && !hasLibraryFinalizer(ob) && len(provisionerFinalizers(ob)) > 0 {
+		// Delete or Revoke was already called and the OB deleted, only the provisioner's finalizers remain
+		return c.waitForObjectBucketFinalizers(ob)
+	}
+	// an OB deleted by someone else, eg. an admin, still carries the library's finalizer: Delete or Revoke was not
+	// called yet and the finalizer is only removed once they succeed
+
 	if ob.Spec.ReclaimPolicy == nil {
 		log.Error(nil, "missing reclaimPolicy", "ob", ob.Name)
 		return nil
@@ -438,45 +1471,728 @@ func (c *obcController) handleDeleteClaim(key string, obc *v1alpha1.ObjectBucket
 
 	// call Delete or Revoke and then delete generated k8s resources
 	// Note: if Delete or Revoke return err then we do not try to delete resources
-	ob, err = updateObjectBucketPhase(c.libClientset, ob, v1alpha1.ObjectBucketClaimStatusPhaseReleased, defaultRetryBaseInterval, defaultRetryTimeout)
+	ob, err = updateObjectBucketPhase(c.libClientset, ob, v1alpha1.ObjectBucketStatusPhaseReleased, false, defaultRetryBaseInterval, defaultRetryTimeout)
 	if err != nil {
 		return err
 	}
 
 	// decide whether Delete or Revoke is called
+	p := c.provisionerForObjectBucket(ob)
 	if isNewBucketByObjectBucket(c.clientset, ob) && *ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimDelete {
-		if err = c.provisioner.Delete(ob); err != nil {
+		if ob, err = c.drainBucket(p, obc, ob, time.Now()); err != nil {
+			return err
+		}
+		if err = p.Delete(ob); err != nil {
 			// Do not proceed to deleting the ObjectBucket if the deprovisioning fails for bookkeeping purposes
 			return fmt.Errorf("provisioner error deleting bucket %v", err)
 		}
 	} else {
-		if err = c.provisioner.Revoke(ob); err != nil {
+		if err = p.Revoke(ob); err != nil {
 			return fmt.Errorf("provisioner error revoking access to bucket %v", err)
 		}
 	}
 
+	if *ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimRetain && c.artifactReclaim == ArtifactReclaimRetain {
+		// detached from the claim, they are neither released nor garbage collected with it
+		if err = retainSecret(secret, obc, c.clientset, c.auditor); err != nil {
+			return err
+		}
+		if err = retainConfigMaps(cm, obc, c.clientset, c.auditor); err != nil {
+			return err
+		}
+		secret, cm = nil, nil
+	}
+
+	if len(provisionerFinalizers(ob)) > 0 || len(pendingClaimFinalizers(obc)) > 0 {
+		// the OBC and its resources are released once other controllers have removed their finalizers from the OB and
+		// the OBC, and the OB is gone
+		if err = deleteObjectBucket(ob, c.libClientset, c.auditor); err != nil {
+			return err
+		}
+		if len(provisionerFinalizers(ob)) > 0 {
+			return c.waitForObjectBucketFinalizers(ob)
+		}
+		return c.waitForClaimFinalizers(obc)
+	}
 	return c.deleteResources(ob, cm, secret, obc)
 }
 
-func (c *obcController) supportedProvisioner(provisioner string) bool {
-	return provisioner == c.provisionerName
+// waitForClaimFinalizers requeues the deleted claim while it still carries finalizers of other controllers.
+func (c *obcController) waitForClaimFinalizers(obc *v1alpha1.ObjectBucketClaim) error {
+	log.Info("waiting for other controllers to remove their finalizers from the claim",
+		"finalizers", pendingClaimFinalizers(obc))
+	return &requeueAfterError{delay: defaultFinalizerRequeueDelay, reason: "waiting for claim finalizers"}
+}
+
+// waitForObjectBucketFinalizers requeues the claim while its deleted OB still carries provisioner finalizers.
+func (c *obcController) waitForObjectBucketFinalizers(ob *v1alpha1.ObjectBucket) error {
+	log.Info("waiting for the provisioner to remove its finalizers from the ObjectBucket", "ob", ob.Name,
+		"finalizers", provisionerFinalizers(ob))
+	return &requeueAfterError{delay: defaultFinalizerRequeueDelay, reason: "waiting for ObjectBucket finalizers"}
+}
+
+// reconcileBoundClaim repairs the generated resources of an already bound claim.
+func (c *obcController) reconcileBoundClaim(obc *v1alpha1.ObjectBucketClaim) error {
+	if !c.labelSelector.Matches(labels.Set(obc.Labels)) {
+		logD.Info("claim is not managed by this provisioner, skipping")
+		return nil
+	}
+	ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(obc.Spec.ObjectBucketName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting ObjectBucket %q: %v", obc.Spec.ObjectBucketName, err)
+	}
+	if err = c.validateBoundClaim(obc, ob); err != nil {
+		return err
+	}
+	if ob, err = c.reconcileObjectBucketAnnotations(obc, ob); err != nil {
+		return err
+	}
+	if ob, err = c.migrateFinalizers(obc, ob); err != nil {
+		return err
+	}
+	if err = c.reconcileSecret(obc, ob); err != nil {
+		return err
+	}
+	if err = c.reconcileConfigMap(obc, ob); err != nil {
+		return err
+	}
+	if err = c.completeBinding(obc, ob); err != nil {
+		return err
+	}
+	c.notifyBound(obc, ob)
+	if err = c.reconcileProviderStatus(ob); err != nil {
+		return err
+	}
+	now := time.Now()
+	return earliestRequeue(c.reverifyConnection(obc, ob, now), c.rotateCredentials(obc, ob, now), c.checkBucketDrift(obc, ob, now))
+}
+
+// reconcileObjectBucketAnnotations updates the annotations of ob propagated from the claim when they changed since the
+// OB was created, and returns the updated OB.
+func (c *obcController) reconcileObjectBucketAnnotations(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	updated := ob.DeepCopy()
+	if !propagateAnnotations(updated, obc, c.propagatedAnnotationPrefixes) {
+		return ob, nil
+	}
+	log.Info("updating annotations propagated to ObjectBucket", "ob", ob.Name)
+	updated, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Update(updated)
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "update", "objectbuckets", "", ob.Name),
+			fmt.Sprintf("error updating annotations of ObjectBucket %q", ob.Name))
+	}
+	return updated, nil
+}
+
+// migrateFinalizers replaces the finalizer shared by all kinds, set by earlier versions of the library on the OB,
+// ConfigMap and Secret of the claim, with the finalizer of their kind. The ConfigMap and Secret are read from the
+// informers' caches, and only read from the API server when they still carry the shared finalizer. It returns the
+// updated OB.
+func (c *obcController) migrateFinalizers(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.ObjectBucket, error) {
+	if updated := ob.DeepCopy(); migrateFinalizer(updated) {
+		log.Info("migrating ObjectBucket finalizer", "ob", ob.Name)
+		result, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Update(updated)
+		if err != nil {
+			return nil, annotateError(asPermissionError(err, "update", "objectbuckets", "", ob.Name),
+				fmt.Sprintf("error migrating finalizer of ObjectBucket %q", ob.Name))
+		}
+		ob = result
+	}
+	name := c.artifactNaming.Name(obc.Name)
+	cached, err := c.configMapLister.ConfigMaps(obc.Namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("error getting configMap \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+	if err == nil && isControlledByClaim(cached, obc) && hasFinalizer(cached.Finalizers, finalizer) {
+		cm, err := c.clientset.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("error getting configMap \"%s/%s\": %v", obc.Namespace, name, err)
+		}
+		if err == nil && migrateFinalizer(cm) {
+			log.Info("migrating configMap finalizer", "configMap", obc.Namespace+"/"+name)
+			if _, err = updateConfigMap(c.clientset, cm, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+				return nil, annotateError(err, "error migrating configMap finalizer")
+			}
+		}
+	}
+	cachedSecret, err := c.secretLister.Secrets(obc.Namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("error getting secret \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+	if err == nil && isControlledByClaim(cachedSecret, obc) && hasFinalizer(cachedSecret.Finalizers, finalizer) {
+		secret, err := c.clientset.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("error getting secret \"%s/%s\": %v", obc.Namespace, name, err)
+		}
+		if err == nil && migrateFinalizer(secret) {
+			log.Info("migrating secret finalizer", "secret", obc.Namespace+"/"+name)
+			if _, err = updateSecret(c.clientset, secret, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+				return nil, annotateError(err, "error migrating secret finalizer")
+			}
+		}
+	}
+	return ob, nil
+}
+
+// earliestRequeue returns the first of errs which is not a requeueAfterError, if any. Otherwise it returns the
+// requeueAfterError with the shortest delay, so that each periodic task of a claim runs on time.
+func earliestRequeue(errs ...error) error {
+	var earliest *requeueAfterError
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		rq, ok := err.(*requeueAfterError)
+		if !ok {
+			return err
+		}
+		if earliest == nil || rq.delay < earliest.delay {
+			earliest = rq
+		}
+	}
+	if earliest == nil {
+		return nil
+	}
+	return earliest
+}
+
+// reverifyConnection verifies the connection of a bound claim again once the reverification interval has passed since
+// it was last verified, and records the outcome in its ConnectionVerified condition. The claim is requeued for its
+// next verification, sooner while verification fails.
+func (c *obcController) reverifyConnection(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, now time.Time) error {
+	if c.connVerifier == nil || c.reverifyInterval == 0 || obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		return nil
+	}
+	key := obc.Namespace + "/" + obc.Name
+	c.lastVerifiedMu.Lock()
+	last, ok := c.lastVerified[key]
+	c.lastVerifiedMu.Unlock()
+	if ok && now.Sub(last) < c.reverifyInterval {
+		return &requeueAfterError{delay: c.reverifyInterval - now.Sub(last), reason: "waiting for the next connection verification"}
+	}
+
+	verr := c.verifyConnection(obc, ob)
+	c.lastVerifiedMu.Lock()
+	if c.lastVerified == nil {
+		c.lastVerified = make(map[string]time.Time)
+	}
+	c.lastVerified[key] = now
+	c.lastVerifiedMu.Unlock()
+
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimConnectionVerified,
+		Status: corev1.ConditionTrue,
+		Reason: reasonVerified,
+	}
+	delay := c.reverifyInterval
+	if verr != nil {
+		log.Info("connection verification of bound claim failed", "error", verr.Error())
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = reasonVerificationFailed
+		cond.Message = verr.Error()
+		if delay > defaultVerifyRequeueDelay {
+			delay = defaultVerifyRequeueDelay
+		}
+	}
+	// the status is only written when the outcome changes
+	if cur := getClaimCondition(&obc.Status, cond.Type); cur == nil || cur.Status != cond.Status || cur.Message != cond.Message {
+		_, err := updateObjectBucketClaimStatus(c.libClientset, obc.DeepCopy(), func(status *v1alpha1.ObjectBucketClaimStatus) {
+			setClaimCondition(status, cond)
+		}, defaultRetryBaseInterval, defaultRetryTimeout)
+		if err != nil {
+			return annotateError(err, "error updating OBC status")
+		}
+		if verr != nil {
+			c.recorder.Event(obc, corev1.EventTypeWarning, reasonVerificationFailed, verr.Error())
+		}
+	}
+	return &requeueAfterError{delay: delay, reason: "waiting for the next connection verification"}
+}
+
+// forgetVerification drops the time the claim was last verified.
+func (c *obcController) forgetVerification(key string) {
+	c.lastVerifiedMu.Lock()
+	defer c.lastVerifiedMu.Unlock()
+	delete(c.lastVerified, key)
+}
+
+// reconcileProviderStatus merges the provider status reported by provisioners implementing api.ProviderStatusReporter
+// into the ob's status. The status is only written if it changed.
+func (c *obcController) reconcileProviderStatus(ob *v1alpha1.ObjectBucket) error {
+	r, ok := c.provisionerForObjectBucket(ob).(api.ProviderStatusReporter)
+	if !ok {
+		return nil
+	}
+	updates, err := r.ProviderStatus(ob.DeepCopy())
+	if err != nil {
+		return fmt.Errorf("error getting provider status of ObjectBucket %q: %v", ob.Name, err)
+	}
+	if !mergeProviderStatus(ob.Status.DeepCopy(), updates) {
+		return nil
+	}
+	_, err = updateObjectBucketStatus(c.libClientset, ob.DeepCopy(), func(status *v1alpha1.ObjectBucketStatus) {
+		mergeProviderStatus(status, updates)
+	}, defaultRetryBaseInterval, defaultRetryTimeout)
+	if err != nil {
+		return annotateError(err, fmt.Sprintf("error updating provider status of ObjectBucket %q", ob.Name))
+	}
+	return nil
+}
+
+// sseCustomerKey reads the customer-provided encryption key from the Secret referenced by ref, nil if ref is nil. The
+// key is never logged, errors only name the Secret.
+func (c *obcController) sseCustomerKey(ref *corev1.SecretReference) ([]byte, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	secret, err := c.clientset.CoreV1().Secrets(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "get", "secrets", ref.Namespace, ref.Name),
+			fmt.Sprintf("error getting SSE-C key secret \"%s/%s\"", ref.Namespace, ref.Name))
+	}
+	key, ok := secret.Data[v1alpha1.SSECustomerKeyField]
+	if !ok {
+		key = []byte(secret.StringData[v1alpha1.SSECustomerKeyField])
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("SSE-C key secret \"%s/%s\" has no %q", ref.Namespace, ref.Name, v1alpha1.SSECustomerKeyField)
+	}
+	return key, nil
+}
+
+// resolveReferencedOptions reads the options held in resources referenced by the storage class: it returns the key of
+// the SSECustomerKeySecret, if any, and sets the CORS rule of provisionOptions from its CORSConfigMap, if any.
+func (c *obcController) resolveReferencedOptions(provisionOptions *api.ProvisionOptions) ([]byte, error) {
+	sseCustomerKey, err := c.sseCustomerKey(provisionOptions.SSECustomerKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	if provisionOptions.CORSConfigMap != nil {
+		if provisionOptions.CORS, err = c.corsConfig(provisionOptions.CORSConfigMap); err != nil {
+			return nil, err
+		}
+	}
+	return sseCustomerKey, nil
+}
+
+// reclaimPolicyFor returns the reclaim policy of the claim's bucket: the claim's api.ReclaimPolicyAnnotation, the
+// class's reclaimPolicy, the api.DefaultReclaimPolicyAnnotation of the claim's namespace, or Delete, whichever is set
+// first. An invalid annotation cannot be fixed by retrying and is returned as a terminal error.
+func (c *obcController) reclaimPolicyFor(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) (*corev1.PersistentVolumeReclaimPolicy, error) {
+	if v, ok := obc.Annotations[api.ReclaimPolicyAnnotation]; ok {
+		policy, err := parseReclaimPolicy(v)
+		if err != nil {
+			return nil, newTerminalError("invalid %q on claim: %v", api.ReclaimPolicyAnnotation, err)
+		}
+		return policy, nil
+	}
+	if class.ReclaimPolicy != nil {
+		return class.ReclaimPolicy, nil
+	}
+	ns, err := c.clientset.CoreV1().Namespaces().Get(obc.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "get", "namespaces", "", obc.Namespace),
+			fmt.Sprintf("error getting namespace %q", obc.Namespace))
+	}
+	if v, ok := ns.Annotations[api.DefaultReclaimPolicyAnnotation]; ok {
+		policy, err := parseReclaimPolicy(v)
+		if err != nil {
+			return nil, newTerminalError("invalid %q on namespace %q: %v", api.DefaultReclaimPolicyAnnotation, ns.Name, err)
+		}
+		return policy, nil
+	}
+	policy := corev1.PersistentVolumeReclaimDelete
+	return &policy, nil
+}
+
+// corsConfig reads the CORS rule from the ConfigMap referenced by ref. A rule which fails validation cannot be fixed by
+// retrying and is returned as a terminal error.
+func (c *obcController) corsConfig(ref *corev1.ObjectReference) (*api.CORSConfig, error) {
+	cm, err := c.clientset.CoreV1().ConfigMaps(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "get", "configmaps", ref.Namespace, ref.Name),
+			fmt.Sprintf("error getting CORS configMap \"%s/%s\"", ref.Namespace, ref.Name))
+	}
+	cors, err := parseCORSConfig(cm.Data)
+	if err != nil {
+		return nil, newTerminalError("invalid CORS configMap \"%s/%s\": %v", ref.Namespace, ref.Name, err)
+	}
+	if cors == nil {
+		return nil, newTerminalError("CORS configMap \"%s/%s\" has no %q", ref.Namespace, ref.Name, v1alpha1.StorageClassCORSAllowedOrigins)
+	}
+	return cors, nil
+}
+
+// acquireProvisionSlot takes one of the slots bounding the number of Provision calls in flight, without waiting for
+// one to be released: if none is free, ok is false and the claim should be requeued rather than block a worker. The
+// returned func releases the slot.
+func (c *obcController) acquireProvisionSlot() (release func(), ok bool) {
+	if c.provisionSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case c.provisionSlots <- struct{}{}:
+		return func() { <-c.provisionSlots }, true
+	default:
+		return nil, false
+	}
+}
+
+// completeBinding marks a provisioned claim which is not Bound yet, because verifying its connection failed, Bound
+// once the verification succeeds.
+func (c *obcController) completeBinding(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	if c.connVerifier == nil || obc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		return nil
+	}
+	verr := c.verifyConnection(obc, ob)
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimProvisioned,
+		Status: corev1.ConditionTrue,
+		Reason: reasonBound,
+	}
+	if verr != nil {
+		log.Info("connection verification failed, not marking the claim Bound", "error", verr.Error())
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = reasonVerificationFailed
+		cond.Message = verr.Error()
+	}
+	_, err := updateObjectBucketClaimStatus(c.libClientset, obc.DeepCopy(), func(status *v1alpha1.ObjectBucketClaimStatus) {
+		if verr == nil {
+			status.Phase = v1alpha1.ObjectBucketClaimStatusPhaseBound
+		}
+		setClaimCondition(status, cond)
+	}, defaultRetryBaseInterval, defaultRetryTimeout)
+	if err != nil {
+		return annotateError(err, "error updating OBC status")
+	}
+	if verr != nil {
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonVerificationFailed, verr.Error())
+		return &requeueAfterError{delay: defaultVerifyRequeueDelay, reason: "connection verification failed"}
+	}
+	log.Info("connection verified, claim bound")
+	return nil
+}
+
+// checkBucketReady asks provisioners implementing api.ReadinessPoller once whether the bucket of ob is ready. A bucket
+// which is not ready yet requeues the claim rather than blocking the worker.
+func (c *obcController) checkBucketReady(p api.Provisioner, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, status *statusUpdates) error {
+	poller, ok := p.(api.ReadinessPoller)
+	if !ok {
+		return nil
+	}
+	ready, err := poller.BucketReady(ob)
+	if err != nil {
+		return fmt.Errorf("error polling bucket readiness: %v", err)
+	}
+	if !ready {
+		log.Info("bucket is not ready yet, requeuing", "ob", ob.Name)
+		status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+			Type:    v1alpha1.ObjectBucketClaimProvisioned,
+			Status:  corev1.ConditionFalse,
+			Reason:  reasonBucketNotReady,
+			Message: "waiting for the bucket to become ready",
+		})
+		return &requeueAfterError{delay: defaultReadinessRequeueDelay, reason: "bucket not ready"}
+	}
+	return nil
+}
+
+// verifyConnection runs the connection verifier, if one is set, against the claim's published endpoint and the data
+// of its Secret.
+func (c *obcController) verifyConnection(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	if c.connVerifier == nil {
+		return nil
+	}
+	ep, err := c.publishedEndpoint(ob.Spec.Endpoint)
+	if err != nil {
+		return err
+	}
+	var credentials map[string]string
+	secret, err := c.clientset.CoreV1().Secrets(obc.Namespace).Get(c.artifactNaming.Name(obc.Name), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		credentials = make(map[string]string, len(secret.Data)+len(secret.StringData))
+		for k, v := range secret.Data {
+			credentials[k] = string(v)
+		}
+		for k, v := range secret.StringData {
+			credentials[k] = v
+		}
+	case !errors.IsNotFound(err):
+		return fmt.Errorf("error getting secret of claim: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.verifyTimeout)
+	defer cancel()
+	if err = c.connVerifier(ctx, ep, credentials); err != nil {
+		return fmt.Errorf("error verifying connection: %v", err)
+	}
+	return nil
+}
+
+// validateBoundClaim checks the claim for changes to spec fields which cannot be honored after provisioning. Such
+// changes are ignored; the claim's SpecValid condition and an event tell the user why.
+func (c *obcController) validateBoundClaim(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimSpecValid,
+		Status: corev1.ConditionTrue,
+		Reason: reasonSpecValid,
+	}
+	isNewBucket := isNewBucketByObjectBucket(c.clientset, ob)
+	if verr := ValidateUpdate(provisionedClaim(obc, ob, isNewBucket), obc); verr != nil {
+		log.Info("ignoring changes to immutable fields of bound claim", "error", verr.Error())
+		cond.Status = corev1.ConditionFalse
+		cond.Reason = reasonImmutableField
+		cond.Message = verr.Error()
+	}
+
+	cur := getClaimCondition(&obc.Status, cond.Type)
+	if cur == nil && cond.Status == corev1.ConditionTrue {
+		// don't add the condition to every bound claim, only clear it once set
+		return nil
+	}
+	if cur != nil && cur.Status == cond.Status && cur.Reason == cond.Reason && cur.Message == cond.Message {
+		return nil
+	}
+	if cond.Status == corev1.ConditionFalse {
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonImmutableField, cond.Message)
+	}
+	_, err := updateObjectBucketClaimStatus(c.libClientset, obc.DeepCopy(), func(status *v1alpha1.ObjectBucketClaimStatus) {
+		setClaimCondition(status, cond)
+	}, defaultRetryBaseInterval, defaultRetryTimeout)
+	if err != nil {
+		return annotateError(err, "error updating OBC status")
+	}
+	return nil
+}
+
+// reconcileSecret recreates the claim's secret if it was deleted after the claim was bound.
+func (c *obcController) reconcileSecret(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	name := c.artifactNaming.Name(obc.Name)
+	_, err := c.clientset.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("error getting secret \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+
+	if c.authPolicyForObjectBucket(ob) == AuthenticationSkipSecret {
+		// the secret may have been skipped by design, it cannot be told apart from a deleted one
+		logD.Info("secret of bound claim is missing, not recreating it", "policy", "skip-secret")
+		return nil
+	}
+	log.Info("secret of bound claim is missing, recreating it")
+	auth, err := c.recoverCredentials(obc, ob)
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, reasonSecretMissing, "unable to recover credentials: %v", err)
+		return fmt.Errorf("error recovering credentials: %v", err)
+	}
+	if auth == nil {
+		// without the provisioner's help there is nothing to regenerate the credentials from
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonSecretMissing,
+			"secret was deleted and the provisioner cannot recover the credentials of this bucket")
+		return nil
+	}
+	// the storage class may ask for a connection string to be rendered into the secret
+	provisionOptions, err := c.provisionOptionsForObjectBucket(obc, ob)
+	if err != nil {
+		return err
+	}
+	var ep *v1alpha1.Endpoint
+	if ob.Spec.Connection != nil {
+		if ep, err = c.publishedEndpoint(ob.Spec.Endpoint); err != nil {
+			return err
+		}
+	}
+	budget, cancel := newRetryBudget()
+	defer cancel()
+	_, err = createSecret(budget, obc, name, ep, auth, provisionOptions, c.provisionerLabels, c.clientset, defaultRetryBaseInterval, c.auditor)
+	if err != nil {
+		return annotateError(err, "error recreating secret")
+	}
+	return nil
+}
+
+// recoverCredentials re-issues the credentials of a bound bucket. Provisioners implementing CredentialsRecoverer are
+// asked to regenerate them from the state retained in the OB. Otherwise, access to existing (brownfield) buckets is
+// granted again. Returns nil credentials if neither is possible.
+func (c *obcController) recoverCredentials(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*v1alpha1.Authentication, error) {
+	p := c.provisionerForObjectBucket(ob)
+	if r, ok := p.(api.CredentialsRecoverer); ok {
+		return r.RecoverCredentials(ob.DeepCopy())
+	}
+	if isNewBucketByObjectBucket(c.clientset, ob) {
+		return nil, nil
+	}
+
+	class, err := storageClassForObjectBucket(ob, c.clientset)
+	if err != nil {
+		return nil, err
+	}
+	params, err := c.parametersFor(obc, class)
+	if err != nil {
+		return nil, err
+	}
+	provisionOptions, err := c.provisionOptionsFor(obc, class, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters in StorageClass %q: %v", class.Name, err)
+	}
+	granted, err := p.Grant(&api.BucketOptions{
+		ReclaimPolicy:     ob.Spec.ReclaimPolicy,
+		BucketName:        params[v1alpha1.StorageClassBucket],
+		ObjectBucketClaim: obc.DeepCopy(),
+		Parameters:        params,
+		ProvisionOptions:  *provisionOptions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error granting access to bucket: %v", err)
+	}
+	if granted == nil || granted.Spec.Connection == nil || granted.Spec.Authentication == nil {
+		return nil, fmt.Errorf("provisioner returned no credentials")
+	}
+	return granted.Spec.Authentication, nil
+}
+
+// reconcileConfigMap detects drift between the endpoint stored in the claim's ObjectBucket and the claim's ConfigMap
+// (eg. after a gateway migration changed the port) and updates the affected keys in place.
+func (c *obcController) reconcileConfigMap(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error {
+	if ob.Spec.Connection == nil || ob.Spec.Endpoint == nil {
+		logD.Info("ObjectBucket has no endpoint, skipping configMap reconcile", "ob", ob.Name)
+		return nil
+	}
+	provisionOptions, err := c.provisionOptionsForObjectBucket(obc, ob)
+	if err != nil {
+		return err
+	}
+	if provisionOptions != nil && provisionOptions.SkipConfigMap {
+		logD.Info("storage class skips the configMap, skipping configMap reconcile", "ob", ob.Name)
+		return nil
+	}
+	name := c.artifactNaming.Name(obc.Name)
+	cm, err := c.clientset.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting configMap \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+
+	ep, err := c.publishedEndpoint(ob.Spec.Endpoint)
+	if err != nil {
+		return err
+	}
+	desired, err := newBucketConfigMap(obc, name, ep, nil, provisionOptions, c.provisionerLabels)
+	if err != nil {
+		return err
+	}
+	// the bucket keeps the properties it was provisioned with whatever changes are made to its class later
+	for _, k := range provisionedConfigKeys {
+		delete(desired.Data, k)
+	}
+	changed := false
+	if blobs := splitConfigMap(desired, maxInlineConfigMapDataSize); blobs != nil {
+		budget, cancel := newRetryBudget()
+		defer cancel()
+		if err = applyBlobsConfigMap(budget, blobs, c.clientset, defaultRetryBaseInterval); err != nil {
+			return annotateError(err, "error applying blobs configMap")
+		}
+		// values now in the blobs configMap must not linger inline
+		for k := range blobs.Data {
+			if _, ok := cm.Data[k]; ok {
+				delete(cm.Data, k)
+				changed = true
+			}
+		}
+	}
+	if !syncConfigMapData(cm, desired) && !changed {
+		return nil
+	}
+	log.Info("endpoint changed, updating configMap", "name", cm.Namespace+"/"+cm.Name)
+	_, err = updateConfigMap(c.clientset, cm, defaultRetryBaseInterval, defaultRetryTimeout)
+	if err != nil {
+		return annotateError(err, "error updating configMap")
+	}
+	return nil
+}
+
+// parametersFor returns the parameters of class overridden by the AdditionalConfig of the claim, which wins. Claims
+// may only override the parameters of the allowlist and fail otherwise, so that tenants cannot set privileged
+// parameters. Without an allowlist the AdditionalConfig is left to the provisioner and the class parameters are
+// returned as is.
+func (c *obcController) parametersFor(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) (map[string]string, error) {
+	if !c.overridesParameters(obc) {
+		return class.Parameters, nil
+	}
+	params := make(map[string]string, len(class.Parameters)+len(obc.Spec.AdditionalConfig))
+	for k, v := range class.Parameters {
+		params[k] = v
+	}
+	var denied []string
+	for k, v := range obc.Spec.AdditionalConfig {
+		if !c.claimParameterAllowlist[k] {
+			denied = append(denied, k)
+			continue
+		}
+		params[k] = v
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return nil, fmt.Errorf("claim may not override the parameters %q of StorageClass %q", denied, class.Name)
+	}
+	return params, nil
+}
+
+// overridesParameters returns true if obc may override the parameters of its storage class.
+func (c *obcController) overridesParameters(obc *v1alpha1.ObjectBucketClaim) bool {
+	return obc != nil && c.claimParameterAllowlist != nil && len(obc.Spec.AdditionalConfig) > 0
+}
+
+// provisionOptionsForObjectBucket returns the provision options of the storage class ob was provisioned from, with the
+// parameters overridden by the claim. If the class cannot be read, eg. because it was deleted, the options are nil and
+// only the default keys are reconciled.
+func (c *obcController) provisionOptionsForObjectBucket(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*api.ProvisionOptions, error) {
+	class, err := storageClassForObjectBucket(ob, c.clientset)
+	if err != nil {
+		log.Error(err, "unable to get storage class, using default provision options", "ob", ob.Name)
+		return nil, nil
+	}
+	params, err := c.parametersFor(obc, class)
+	if err != nil {
+		return nil, err
+	}
+	options, err := c.provisionOptionsFor(obc, class, params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters in StorageClass %q: %v", class.Name, err)
+	}
+	return options, nil
+}
+
+// servesStorageClass returns true if class names the reconciler's own provisioner or one registered with it.
+func (c *obcController) servesStorageClass(class *storagev1.StorageClass) bool {
+	if ShouldProvision(class, c.provisionerName) {
+		return true
+	}
+	c.provisionersMu.RLock()
+	defer c.provisionersMu.RUnlock()
+	for name := range c.provisioners {
+		if ShouldProvision(class, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // Returns the ob, configmap, and secret based on the passed-in key. Only returns non-nil
 // error if unable to get all resources. Some resources may be nil.
-func (c *obcController) getResourcesFromKey(key string) (*v1alpha1.ObjectBucket, *corev1.ConfigMap, *corev1.Secret, error) {
+func (c *obcController) getResourcesFromKey(key string, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucket, *corev1.ConfigMap, *corev1.Secret, error) {
 
-	ob, obErr := c.objectBucketForClaimKey(key)
+	ob, obErr := c.objectBucketForClaim(key, obc)
 	if errors.IsNotFound(obErr) {
 		log.Error(obErr, "objectBucket not found")
 		obErr = nil
 	}
-	cm, cmErr := configMapForClaimKey(key, c.clientset)
+	cm, cmErr := configMapForClaimKey(key, c.artifactNaming, c.clientset)
 	if errors.IsNotFound(cmErr) {
 		log.Error(cmErr, "configMap not found")
 		cmErr = nil
 	}
-	s, sErr := secretForClaimKey(key, c.clientset)
+	s, sErr := secretForClaimKey(key, c.artifactNaming, c.clientset)
 	if errors.IsNotFound(sErr) {
 		log.Error(sErr, "secret not found")
 		sErr = nil
@@ -497,30 +2213,125 @@ func (c *obcController) getResourcesFromKey(key string) (*v1alpha1.ObjectBucket,
 // they will be garbage collected once their finalizers are removed. The OB must be explicitly
 // deleted since it is a global resource and cannot have a namespaced ownerReference. The last step
 // is to remove the finalizer on the OBC so it too will be garbage collected.
-// Returns err if we can't delete one or more of the resources, the final returned error being
-// somewhat arbitrary.
+// The steps are taken in the order of the controller's DeletionSequence, each only once the previous one succeeded,
+// so that other controllers watching these resources see a deterministic teardown. The first error is returned, the
+// steps already taken are skipped on the next attempt since their resources are gone or released.
 func (c *obcController) deleteResources(ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, s *corev1.Secret, obc *v1alpha1.ObjectBucketClaim) (err error) {
+	for _, step := range c.deletionSteps() {
+		if err = c.deletionStep(step, ob, cm, s, obc); err != nil {
+			log.Error(err, "error in deletion step, not proceeding", "step", step)
+			return err
+		}
+	}
+	if obc != nil && c.onDeleted != nil {
+		if hookErr := c.onDeleted(obc, ob); hookErr != nil {
+			log.Error(hookErr, "error in OnDeleted hook")
+		}
+	}
+	if err = releaseOBC(obc, c.libClientset, c.auditor); err != nil {
+		log.Error(err, "error releasing obc")
+	}
+	return err
+}
 
-	if delErr := deleteObjectBucket(ob, c.libClientset); delErr != nil {
-		log.Error(delErr, "error deleting objectBucket", ob.Name)
-		err = delErr
+// cleanupResources deletes the resources generated by a failed provisioning attempt. Unlike deleteResources, no claim
+// is being torn down and the attempt is not repeated: every step is taken even if a previous one failed, and the
+// errors are returned together.
+func (c *obcController) cleanupResources(ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, s *corev1.Secret) error {
+	var errs []error
+	for _, step := range c.deletionSteps() {
+		if err := c.deletionStep(step, ob, cm, s, nil); err != nil {
+			log.Error(err, "error in cleanup step, proceeding", "step", step)
+			errs = append(errs, err)
+		}
 	}
-	if delErr := releaseSecret(s, c.clientset); delErr != nil {
-		log.Error(delErr, "error releasing secret")
-		err = delErr
+	return utilerrors.NewAggregate(errs)
+}
+
+// deletionSteps returns the controller's DeletionSequence, or the DefaultDeletionSequence if none was set.
+func (c *obcController) deletionSteps() []DeletionStep {
+	if c.deletionSequence == nil {
+		return DefaultDeletionSequence
 	}
-	if delErr := releaseConfigMap(cm, c.clientset); delErr != nil {
-		log.Error(delErr, "error releasing configMap")
-		err = delErr
+	return c.deletionSequence
+}
+
+// deletionStep deletes or releases the resource of a single step. The claim's ServiceAccount is only released if the
+// claim is given.
+func (c *obcController) deletionStep(step DeletionStep, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, s *corev1.Secret, obc *v1alpha1.ObjectBucketClaim) error {
+	switch step {
+	case DeletionStepObjectBucket:
+		return deleteObjectBucket(ob, c.libClientset, c.auditor)
+	case DeletionStepSecret:
+		return releaseSecret(s, c.clientset, c.auditor)
+	case DeletionStepConfigMap:
+		return releaseConfigMap(cm, c.clientset, c.auditor)
+	case DeletionStepServiceAccount:
+		if obc != nil {
+			return releaseServiceAccount(obc.Namespace, obc.Annotations[api.ServiceAccountAnnotation], c.clientset, c.auditor)
+		}
+	}
+	return nil
+}
+
+// notifyBound calls the OnBoundHook for a Bound claim it was not called for yet, and records the call in the claim.
+func (c *obcController) notifyBound(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) {
+	if c.onBound == nil || obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		return
 	}
-	if delErr := releaseOBC(obc, c.libClientset); delErr != nil {
-		log.Error(delErr, "error releasing obc")
-		err = delErr
+	if _, ok := obc.Annotations[api.BoundNotifiedAnnotation]; ok {
+		return
 	}
-	return err
+	var cm *corev1.ConfigMap
+	var secret *corev1.Secret
+	artifacts, err := BindingArtifactsFor(ob, c.artifactNaming, c.clientset, c.libClientset)
+	if err != nil {
+		log.Error(err, "error getting binding artifacts for OnBound hook")
+	} else {
+		cm, secret = artifacts.ConfigMap, artifacts.Secret
+	}
+	if err = c.onBound(obc.DeepCopy(), ob.DeepCopy(), cm, secret); err != nil {
+		log.Error(err, "error in OnBound hook")
+	}
+	obc = obc.DeepCopy()
+	metav1.SetMetaDataAnnotation(&obc.ObjectMeta, api.BoundNotifiedAnnotation, time.Now().UTC().Format(time.RFC3339))
+	if _, err = updateClaim(c.libClientset, obc, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+		// the hook is called again on the next sync
+		log.Error(err, "error recording OnBound notification")
+	}
+}
+
+// annotateClaimSecret adds the annotations returned by the SecretAnnotator to the claim's Secret.
+func (c *obcController) annotateClaimSecret(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, secret *corev1.Secret) error {
+	annotations, err := c.secretAnnotator(obc, ob)
+	if err != nil {
+		return fmt.Errorf("error getting annotations of secret for OBC: %v", err)
+	}
+	if _, err = annotateSecret(secret, annotations, c.clientset); err != nil {
+		return annotateError(err, "error annotating secret for OBC")
+	}
+	return nil
+}
+
+// bindServiceAccount creates the ServiceAccount returned by the ServiceAccountBinder, if any, and records its name in
+// the claim, which the caller updates.
+func (c *obcController) bindServiceAccount(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*corev1.ServiceAccount, error) {
+	desired, err := c.serviceAccountBinder(obc, ob)
+	if err != nil {
+		return nil, fmt.Errorf("error binding service account for OBC: %v", err)
+	}
+	if desired == nil {
+		return nil, nil
+	}
+	sa, err := createServiceAccount(ctx, obc, desired, c.provisionerLabels, c.clientset, defaultRetryBaseInterval, c.auditor)
+	if err != nil {
+		return nil, annotateError(err, "error creating service account for OBC")
+	}
+	metav1.SetMetaDataAnnotation(&obc.ObjectMeta, api.ServiceAccountAnnotation, sa.Name)
+	return sa, nil
 }
 
-// Add finalizer and labels to the OBC.
+// Add the library's and external finalizers, and labels to the OBC.
 func (c *obcController) setOBCMetaFields(obc *v1alpha1.ObjectBucketClaim) (err error) {
 	clib := c.libClientset
 
@@ -530,23 +2341,205 @@ func (c *obcController) setOBCMetaFields(obc *v1alpha1.ObjectBucketClaim) (err e
 		return fmt.Errorf("error getting obc: %v", err)
 	}
 
-	obc.SetFinalizers([]string{finalizer})
+	finalizers := obc.GetFinalizers()
+	for _, f := range append([]string{finalizer}, c.externalFinalizers...) {
+		if !hasFinalizer(finalizers, f) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	obc.SetFinalizers(finalizers)
 	obc.SetLabels(c.provisionerLabels)
 
 	logD.Info("updating OBC metadata")
 	obc, err = updateClaim(clib, obc, defaultRetryBaseInterval, defaultRetryTimeout)
 	if err != nil {
-		return fmt.Errorf("error configuring obc metadata: %v", err)
+		return annotateError(err, "error configuring obc metadata")
 	}
 
 	return nil
 }
 
-func (c *obcController) objectBucketForClaimKey(key string) (*v1alpha1.ObjectBucket, error) {
-	logD.Info("getting objectBucket for key", "key", key)
-	name, err := objectBucketNameFromClaimKey(key)
+// validateConnection checks the connection returned by the provisioner before it is published to the claim. Suspicious
+// connections fail the reconcile in strict mode, otherwise they are only reported.
+func (c *obcController) validateConnection(obc *v1alpha1.ObjectBucketClaim, conn *v1alpha1.Connection) error {
+	err := ValidateConnection(conn)
+	if err == nil {
+		return nil
+	}
+	c.recorder.Event(obc, corev1.EventTypeWarning, reasonSuspiciousEndpoint, err.Error())
+	if c.connValidation == ConnectionValidationStrict {
+		return fmt.Errorf("invalid connection: %v", err)
+	}
+	log.Info("publishing suspicious connection", "reason", err.Error())
+	return nil
+}
+
+// reserveBucketName returns the name of the bucket to provision for the claim. A generated name is recorded in the
+// claim before anything is provisioned so that a retry, eg. after a crash between Provision and the creation of the
+// OB, asks for the same bucket again. Claims which do not set a bucket name are named by nameTemplate, if set, rather
+// than generateBucketName; the rendered name is recorded as well, so later changes to the claim's labels do not
+// rename its bucket. Claims which set neither a bucket name nor generateBucketName are named after defaultPrefix, if
+// set. Generated and rendered names are kept within the MaxLength of profile.
+func (c *obcController) reserveBucketName(obc *v1alpha1.ObjectBucketClaim, nameTemplate, defaultPrefix string, profile BucketNameProfile) (string, error) {
+	if nameTemplate != "" && obc.Spec.BucketName == "" {
+		return c.reserveName(obc, api.GeneratedBucketNameAnnotation, func(latest *v1alpha1.ObjectBucketClaim) (string, error) {
+			return renderBucketName(nameTemplate, latest, profile.MaxLength)
+		})
+	}
+	if defaultPrefix != "" && obc.Spec.BucketName == "" && obc.Spec.GenerateBucketName == "" {
+		if err := checkGeneratedNameLength(defaultPrefix, c.bucketNameSuffixLen, profile.MaxLength); err != nil {
+			return "", err
+		}
+		return c.reserveName(obc, api.GeneratedBucketNameAnnotation, func(*v1alpha1.ObjectBucketClaim) (string, error) {
+			return generateBucketName(defaultPrefix, c.bucketNameSuffixLen, profile.MaxLength), nil
+		})
+	}
+	if obc.Spec.GenerateBucketName == "" {
+		return composeBucketName(obc, c.bucketNameSuffixLen, profile.MaxLength)
+	}
+	return c.reserveName(obc, api.GeneratedBucketNameAnnotation, func(latest *v1alpha1.ObjectBucketClaim) (string, error) {
+		return composeBucketName(latest, c.bucketNameSuffixLen, profile.MaxLength)
+	})
+}
+
+// bucketNamePrefixFor returns the prefix of the generated names of claims which request no name: the prefix of the
+// storage class, or else the controller's default.
+func (c *obcController) bucketNamePrefixFor(options *api.ProvisionOptions) string {
+	if options.DefaultBucketNamePrefix != "" {
+		return options.DefaultBucketNamePrefix
+	}
+	return c.defaultBucketNamePrefix
+}
+
+// reserveBucketPrefix returns the unique prefix of the claim within a shared bucket. It is generated from the claim's
+// generateBucketName, or its name, and recorded like generated bucket names.
+func (c *obcController) reserveBucketPrefix(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+	return c.reserveName(obc, api.GeneratedBucketPrefixAnnotation, func(latest *v1alpha1.ObjectBucketClaim) (string, error) {
+		base := latest.Spec.GenerateBucketName
+		if base == "" {
+			base = latest.Name
+		}
+		return generateBucketName(base, c.bucketNameSuffixLen, maxNameLen), nil
+	})
+}
+
+// reserveName returns the name recorded in the claim's annotation, or generates one and records it.
+func (c *obcController) reserveName(obc *v1alpha1.ObjectBucketClaim, annotation string, generate func(*v1alpha1.ObjectBucketClaim) (string, error)) (string, error) {
+	// the lister's copy may predate the annotation, check the latest version
+	clib := c.libClientset
+	obc, err := clib.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("error getting obc: %v", err)
+	}
+	if name := obc.Annotations[annotation]; name != "" {
+		return name, nil
+	}
+	name, err := generate(obc)
+	if err != nil {
+		return "", err
+	}
+	if obc.Annotations == nil {
+		obc.Annotations = make(map[string]string)
+	}
+	obc.Annotations[annotation] = name
+	if _, err = updateClaim(clib, obc, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+		return "", annotateError(err, fmt.Sprintf("error recording %s", annotation))
+	}
+	return name, nil
+}
+
+// existingBucket asks provisioners implementing BucketGetter whether the bucket was already provisioned for the claim.
+// Returns nil if it was not, or if the provisioner cannot tell.
+func (c *obcController) existingBucket(p api.Provisioner, options *api.BucketOptions) (*v1alpha1.ObjectBucket, error) {
+	getter, ok := p.(api.BucketGetter)
+	if !ok {
+		return nil, nil
+	}
+	ob, err := getter.GetBucket(options)
+	if err != nil {
+		return nil, fmt.Errorf("error checking for existing bucket: %v", err)
+	}
+	if ob != nil {
+		log.Info("bucket already provisioned for claim, adopting it", "bucket", options.BucketName)
+	}
+	return ob, nil
+}
+
+// adoptableObjectBucket returns the OB which would be created for the claim if it already exists and is unbound or
+// bound to this claim, eg. by an interrupted reconcile. It returns nil if there is no such OB, and an error if the OB is
+// bound to another claim: it is never overwritten.
+func (c *obcController) adoptableObjectBucket(key string, obc *v1alpha1.ObjectBucketClaim, bucketName string) (*v1alpha1.ObjectBucket, error) {
+	probe := &v1alpha1.ObjectBucket{}
+	setObjectBucketName(probe, key, bucketName, c.obNaming)
+	if probe.Name == "" {
+		return nil, nil
+	}
+	ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(probe.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "get", "objectbuckets", "", probe.Name), "error checking for existing OB")
+	}
+	if ob.DeletionTimestamp != nil {
+		return nil, fmt.Errorf("ObjectBucket %q is being deleted", ob.Name)
+	}
+	// an OB imported without its claim references the claim by name only, see ImportBuckets
+	if ref := ob.Spec.ClaimRef; ref != nil && ref.UID != obc.UID && (ref.UID != "" || ref.Namespace != obc.Namespace || ref.Name != obc.Name) {
+		return nil, newTerminalError("ObjectBucket %q is already bound to claim \"%s/%s\"", ob.Name, ref.Namespace, ref.Name)
+	}
+	return ob, nil
+}
+
+// publishedEndpoint returns ep as published in the claim's ConfigMap and Secret: rewritten by the endpoint transformer,
+// if one is set, and with a BucketPort of 0 defaulted from the scheme of the host unless port defaulting is disabled.
+// ep itself, which is recorded in the OB and handed back to the provisioner, is left unchanged.
+func (c *obcController) publishedEndpoint(ep *v1alpha1.Endpoint) (*v1alpha1.Endpoint, error) {
+	if ep == nil {
+		return ep, nil
+	}
+	published := ep
+	if c.endpointTransformer != nil {
+		var err error
+		published, err = c.endpointTransformer(ep.DeepCopy())
+		if err != nil {
+			return nil, fmt.Errorf("error transforming endpoint: %v", err)
+		}
+		if published == nil {
+			return nil, fmt.Errorf("error transforming endpoint: transformer returned nil")
+		}
+	}
+	if published.BucketPort == 0 && !c.keepZeroBucketPort {
+		if published == ep {
+			published = ep.DeepCopy()
+		}
+		published.BucketPort = defaultBucketPort(published.BucketHost)
+	}
+	return published, nil
+}
+
+// annotateError prefixes err with msg. A PermissionErr is returned as is so that the work queue can recognize it, its
+// message already names the forbidden operation. A spent retry budget is kept recognizable for the TimeoutPolicy.
+func annotateError(err error, msg string) error {
+	if pErr.IsPermission(err) {
+		return err
+	}
+	if err == wait.ErrWaitTimeout || isRetryExhausted(err) {
+		return &retryExhaustedError{fmt.Errorf("%s: %v", msg, err)}
+	}
+	return fmt.Errorf("%s: %v", msg, err)
+}
+
+// objectBucketForClaim returns the OB bound to the claim. The OB name recorded in the claim is authoritative; the
+// name derived from the claim key is only used for claims which were never updated with one.
+func (c *obcController) objectBucketForClaim(key string, obc *v1alpha1.ObjectBucketClaim) (*v1alpha1.ObjectBucket, error) {
+	logD.Info("getting objectBucket for key", "key", key)
+	name := obc.Spec.ObjectBucketName
+	if name == "" {
+		var err error
+		if name, err = objectBucketNameFromClaimKey(key); err != nil {
+			return nil, err
+		}
 	}
 	ob, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(name, metav1.GetOptions{})
 	if err != nil {
@@ -554,3 +2547,37 @@ func (c *obcController) objectBucketForClaimKey(key string) (*v1alpha1.ObjectBuc
 	}
 	return ob, nil
 }
+
+// listObjectBuckets returns the cached OBs matching the controller's label selector.
+func (c *obcController) listObjectBuckets() ([]*v1alpha1.ObjectBucket, error) {
+	return c.obLister.List(c.labelSelector)
+}
+
+// listObjectBucketClaims returns the cached OBCs, in all watched namespaces, matching the controller's label selector.
+func (c *obcController) listObjectBucketClaims() ([]*v1alpha1.ObjectBucketClaim, error) {
+	return c.obcLister.List(c.labelSelector)
+}
+
+// orphanedObjectBuckets returns the OBs matching the controller's label selector whose claimRef points to an OBC
+// which no longer exists. OBs without a claimRef, or imported without their claim (see api.AwaitingClaimAnnotation), are
+// not considered orphans since they may be awaiting a binding.
+func (c *obcController) orphanedObjectBuckets() ([]*v1alpha1.ObjectBucket, error) {
+	obs, err := c.listObjectBuckets()
+	if err != nil {
+		return nil, fmt.Errorf("error listing ObjectBuckets: %v", err)
+	}
+	var orphans []*v1alpha1.ObjectBucket
+	for _, ob := range obs {
+		ref := ob.Spec.ClaimRef
+		if _, awaiting := ob.Annotations[api.AwaitingClaimAnnotation]; ref == nil || awaiting {
+			continue
+		}
+		_, err = c.obcLister.ObjectBucketClaims(ref.Namespace).Get(ref.Name)
+		if errors.IsNotFound(err) {
+			orphans = append(orphans, ob)
+		} else if err != nil {
+			return nil, fmt.Errorf("error getting ObjectBucketClaim \"%s/%s\": %v", ref.Namespace, ref.Name, err)
+		}
+	}
+	return orphans, nil
+}
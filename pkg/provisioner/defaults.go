@@ -0,0 +1,45 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	storagev1 "k8s.io/api/storage/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// ApplyDefaults mutates obc to its defaulted form given class, the storage class it is provisioned from (eg. the
+// default class if the claim names none). The reconciler persists the defaulted spec when the claim is bound, and a
+// mutating admission webhook can call it so that the stored claim reflects the effective configuration from the
+// start. Fields set by the user are never overwritten.
+//
+// Defaults:
+//   - spec.storageClassName is set to the class's name.
+//   - spec.bucketName is set to the existing bucket named by the class, unless the claim sets a bucket name or
+//     generateBucketName.
+func ApplyDefaults(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass) {
+	if obc == nil || class == nil {
+		return
+	}
+	if obc.Spec.StorageClassName == "" {
+		obc.Spec.StorageClassName = class.Name
+	}
+	if bucket := class.Parameters[v1alpha1.StorageClassBucket]; bucket != "" &&
+		obc.Spec.BucketName == "" && obc.Spec.GenerateBucketName == "" {
+		obc.Spec.BucketName = bucket
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	greenfield := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "greenfield"}}
+	brownfield := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "brownfield"},
+		Parameters: map[string]string{v1alpha1.StorageClassBucket: "existing"},
+	}
+	tests := []struct {
+		name  string
+		spec  v1alpha1.ObjectBucketClaimSpec
+		class *storagev1.StorageClass
+		want  v1alpha1.ObjectBucketClaimSpec
+	}{
+		{
+			name:  "default class",
+			spec:  v1alpha1.ObjectBucketClaimSpec{GenerateBucketName: "gen"},
+			class: greenfield,
+			want:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "greenfield", GenerateBucketName: "gen"},
+		}, {
+			name:  "class set by the user",
+			spec:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "mine", BucketName: "bucket"},
+			class: greenfield,
+			want:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "mine", BucketName: "bucket"},
+		}, {
+			name:  "existing bucket",
+			spec:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "brownfield"},
+			class: brownfield,
+			want:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "brownfield", BucketName: "existing"},
+		}, {
+			name:  "existing bucket with generated name",
+			spec:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "brownfield", GenerateBucketName: "gen"},
+			class: brownfield,
+			want:  v1alpha1.ObjectBucketClaimSpec{StorageClassName: "brownfield", GenerateBucketName: "gen"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obc := &v1alpha1.ObjectBucketClaim{Spec: tt.spec}
+			ApplyDefaults(obc, tt.class)
+			if obc.Spec.StorageClassName != tt.want.StorageClassName || obc.Spec.BucketName != tt.want.BucketName ||
+				obc.Spec.GenerateBucketName != tt.want.GenerateBucketName {
+				t.Errorf("ApplyDefaults() spec = %+v, want %+v", obc.Spec, tt.want)
+			}
+		})
+	}
+}
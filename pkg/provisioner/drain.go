@@ -0,0 +1,86 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
+)
+
+const (
+	reasonDraining = "Draining"
+	reasonDrained  = "Drained"
+)
+
+// drainBucket empties the bucket of a deleted claim through provisioners implementing api.BucketDrainer, if its storage
+// class sets drainBeforeDelete, and returns the OB once the drain completed. The time the drain started is recorded in
+// the OB so that the drain timeout spans requeues; while objects remain the claim is requeued, and once the timeout
+// passed the drain fails as a spent retry budget, subject to the TimeoutPolicy.
+func (c *obcController) drainBucket(p api.Provisioner, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, now time.Time) (*v1alpha1.ObjectBucket, error) {
+	options, err := c.provisionOptionsForObjectBucket(obc, ob)
+	if err != nil {
+		return nil, err
+	}
+	if options == nil || !options.DrainBeforeDelete {
+		return ob, nil
+	}
+	drainer, ok := p.(api.BucketDrainer)
+	if !ok {
+		log.Info("drain before delete requested but not supported by the provisioner, deleting", "ob", ob.Name)
+		return ob, nil
+	}
+
+	started, err := time.Parse(time.RFC3339, ob.Annotations[api.DrainStartedAnnotation])
+	if err != nil {
+		started = now
+		updated := ob.DeepCopy()
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, api.DrainStartedAnnotation, started.UTC().Format(time.RFC3339))
+		if ob, err = c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Update(updated); err != nil {
+			return nil, annotateError(asPermissionError(err, "update", "objectbuckets", "", updated.Name),
+				fmt.Sprintf("error recording the drain of ObjectBucket %q", updated.Name))
+		}
+		c.recorder.Event(obc, corev1.EventTypeNormal, reasonDraining, "draining bucket before deleting it")
+	}
+	timeout := c.drainTimeout
+	if timeout == 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	err = drainer.DrainBucket(ob)
+	if elapsed := now.Sub(started); err != nil && elapsed >= timeout {
+		return nil, &retryExhaustedError{fmt.Errorf("bucket not drained after %v: %v", elapsed, err)}
+	}
+	if delay, inProgress := pErr.RetryAfter(err); inProgress {
+		if delay <= 0 {
+			delay = defaultDrainRequeueDelay
+		}
+		log.Info("provisioner is still draining bucket", "ob", ob.Name, "retryAfter", delay)
+		return nil, &requeueAfterError{delay: delay, reason: err.Error()}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("provisioner error draining bucket %v", err)
+	}
+	c.recorder.Event(obc, corev1.EventTypeNormal, reasonDrained, "bucket drained")
+	return ob, nil
+}
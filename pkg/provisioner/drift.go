@@ -0,0 +1,178 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+const (
+	reasonBucketMissing       = "BucketMissing"
+	reasonBucketPresent       = "BucketPresent"
+	reasonBucketReprovisioned = "BucketReprovisioned"
+	reasonReprovisionFailed   = "ReprovisionFailed"
+)
+
+// checkBucketDrift asks provisioners implementing api.BucketGetter whether the bucket of a bound claim still exists,
+// once the drift check interval has passed since it was last checked, and records the outcome in the claim's
+// BucketMissing condition. A missing bucket is provisioned again if the storage class allows it. Only new buckets are
+// checked, the lifecycle of existing buckets is not the library's concern. The claim is requeued for its next check.
+func (c *obcController) checkBucketDrift(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, now time.Time) error {
+	if c.driftCheckInterval == 0 || obc.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound || ob.Spec.Endpoint == nil {
+		return nil
+	}
+	p := c.provisionerForObjectBucket(ob)
+	getter, ok := p.(api.BucketGetter)
+	if !ok {
+		logD.Info("drift check requested but not supported by the provisioner", "ob", ob.Name)
+		return nil
+	}
+	key := obc.Namespace + "/" + obc.Name
+	c.lastDriftCheckMu.Lock()
+	last, ok := c.lastDriftCheck[key]
+	c.lastDriftCheckMu.Unlock()
+	if ok && now.Sub(last) < c.driftCheckInterval {
+		return &requeueAfterError{delay: c.driftCheckInterval - now.Sub(last), reason: "waiting for the next drift check"}
+	}
+	if !isNewBucketByObjectBucket(c.clientset, ob) {
+		return nil
+	}
+
+	class, err := storageClassForObjectBucket(ob, c.clientset)
+	if err != nil {
+		return err
+	}
+	params, err := c.parametersFor(obc, class)
+	if err != nil {
+		return err
+	}
+	provisionOptions, err := c.provisionOptionsFor(obc, class, params)
+	if err != nil {
+		return fmt.Errorf("invalid parameters in StorageClass %q: %v", class.Name, err)
+	}
+	options := &api.BucketOptions{
+		ReclaimPolicy:     ob.Spec.ReclaimPolicy,
+		BucketName:        ob.Spec.Endpoint.BucketName,
+		IdempotencyKey:    ob.Annotations[api.IdempotencyKeyAnnotation],
+		ObjectBucketClaim: obc.DeepCopy(),
+		Parameters:        params,
+		ProvisionOptions:  *provisionOptions,
+	}
+	existing, err := getter.GetBucket(options)
+	if err != nil {
+		// the check is inconclusive, it is not recorded and tried again on the next reconcile
+		return fmt.Errorf("error checking for drift of bucket %q: %v", options.BucketName, err)
+	}
+	c.lastDriftCheckMu.Lock()
+	if c.lastDriftCheck == nil {
+		c.lastDriftCheck = make(map[string]time.Time)
+	}
+	c.lastDriftCheck[key] = now
+	c.lastDriftCheckMu.Unlock()
+
+	cond := v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimBucketMissing,
+		Status: corev1.ConditionFalse,
+		Reason: reasonBucketPresent,
+	}
+	if existing == nil {
+		msg := fmt.Sprintf("bucket %q no longer exists in the object store", options.BucketName)
+		log.Info("bucket of bound claim is missing", "bucket", options.BucketName)
+		c.recorder.Event(obc, corev1.EventTypeWarning, reasonBucketMissing, msg)
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = reasonBucketMissing
+		cond.Message = msg
+		if provisionOptions.ReprovisionMissingBucket {
+			if err = c.reprovisionBucket(p, obc, ob, options, now); err != nil {
+				c.recorder.Event(obc, corev1.EventTypeWarning, reasonReprovisionFailed, err.Error())
+				cond.Message = fmt.Sprintf("%s, provisioning it again failed: %v", msg, err)
+			} else {
+				c.recorder.Event(obc, corev1.EventTypeNormal, reasonBucketReprovisioned, "missing bucket provisioned again")
+				cond.Status = corev1.ConditionFalse
+				cond.Reason = reasonBucketReprovisioned
+				cond.Message = ""
+			}
+		}
+	}
+	// the status is only written when the outcome changes
+	if cur := getClaimCondition(&obc.Status, cond.Type); cur == nil || cur.Status != cond.Status || cur.Reason != cond.Reason || cur.Message != cond.Message {
+		_, err = updateObjectBucketClaimStatus(c.libClientset, obc.DeepCopy(), func(status *v1alpha1.ObjectBucketClaimStatus) {
+			setClaimCondition(status, cond)
+		}, defaultRetryBaseInterval, defaultRetryTimeout)
+		if err != nil {
+			return annotateError(err, "error updating OBC status")
+		}
+	}
+	return &requeueAfterError{delay: c.driftCheckInterval, reason: "waiting for the next drift check"}
+}
+
+// reprovisionBucket provisions the missing bucket of a bound claim again, under the same name and with the SSE-C key
+// and CORS rule it was first provisioned with. The credentials issued by Provision replace those in the claim's Secret,
+// and a changed connection is written to the OB, from which the claim's ConfigMap is synced on the next reconcile.
+func (c *obcController) reprovisionBucket(p api.Provisioner, obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, options *api.BucketOptions, now time.Time) error {
+	log.Info("provisioning missing bucket again", "bucket", options.BucketName)
+	sseCustomerKey, err := c.resolveReferencedOptions(&options.ProvisionOptions)
+	if err != nil {
+		return err
+	}
+	options.SSECustomerKey = sseCustomerKey
+	provisioned, err := p.Provision(options)
+	if err == nil && (provisioned == nil || provisioned.Spec.Connection == nil) {
+		err = fmt.Errorf("provisioner returned no connection")
+	}
+	if err != nil {
+		return fmt.Errorf("error provisioning bucket %q again: %v", options.BucketName, err)
+	}
+	if !sameConnection(ob.Spec.Connection, provisioned.Spec.Connection) {
+		updated := ob.DeepCopy()
+		updated.Spec.Endpoint = provisioned.Spec.Endpoint
+		updated.Spec.AdditionalState = provisioned.Spec.AdditionalState
+		if ob, err = c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Update(updated); err != nil {
+			return annotateError(asPermissionError(err, "update", "objectbuckets", "", updated.Name),
+				fmt.Sprintf("error updating connection of ObjectBucket %q", updated.Name))
+		}
+	}
+	auth := provisioned.Spec.Authentication
+	if auth == nil {
+		return nil
+	}
+	name := c.artifactNaming.Name(obc.Name)
+	secret, err := c.clientset.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// the secret is recreated, with credentials recovered from the provisioner, by reconcileSecret
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting secret \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+	return c.replaceCredentials(obc, ob, secret, auth, &options.ProvisionOptions, now)
+}
+
+// forgetDriftCheck drops the time the claim's bucket was last checked for drift.
+func (c *obcController) forgetDriftCheck(key string) {
+	c.lastDriftCheckMu.Lock()
+	defer c.lastDriftCheckMu.Unlock()
+	delete(c.lastDriftCheck, key)
+}
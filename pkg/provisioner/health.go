@@ -0,0 +1,47 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Ready returns nil once the provisioner's informer caches have synced and the optional api.HealthChecker
+// implemented by the provisioner passes. Otherwise the error describes what is not ready.
+func (p *Provisioner) Ready() error {
+	return p.claimController.Ready()
+}
+
+// Healthz is an http.HandlerFunc for liveness probes. It responds 200 as long as the process can serve requests.
+func (p *Provisioner) Healthz(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "ok")
+}
+
+// Readyz is an http.HandlerFunc for readiness probes. It responds 200 when Ready returns nil and 503 with the reason
+// otherwise, eg.
+//
+//	mux.HandleFunc("/readyz", p.Readyz)
+func (p *Provisioner) Readyz(w http.ResponseWriter, _ *http.Request) {
+	if err := p.Ready(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "ok")
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeHealthChecker struct {
+	fakeProvisioner
+	err error
+}
+
+func (p *fakeHealthChecker) HealthCheck() error {
+	return p.err
+}
+
+func TestReadyz(t *testing.T) {
+	ctrl := newTestController(nil, nil)
+	checker := &fakeHealthChecker{}
+	ctrl.provisioner = checker
+	p := &Provisioner{claimController: ctrl}
+
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		p.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Readyz() before caches synced = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	ctrl.cachesSynced = 1
+	if code := probe(); code != http.StatusOK {
+		t.Errorf("Readyz() = %d, want %d", code, http.StatusOK)
+	}
+	checker.err = fmt.Errorf("object store unreachable")
+	if code := probe(); code != http.StatusServiceUnavailable {
+		t.Errorf("Readyz() with failing health check = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
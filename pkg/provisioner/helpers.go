@@ -17,20 +17,30 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/google/uuid"
 
 	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
 )
 
 func makeObjectReference(claim *v1alpha1.ObjectBucketClaim) *corev1.ObjectReference {
@@ -59,6 +69,86 @@ func makeOwnerReference(claim *v1alpha1.ObjectBucketClaim) metav1.OwnerReference
 	}
 }
 
+// isControlledByClaim returns true if obj's controller is obc, ie. obj was generated for the claim.
+func isControlledByClaim(obj metav1.Object, obc *v1alpha1.ObjectBucketClaim) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.UID == obc.UID && ref.Kind == v1alpha1.ObjectBucketClaimGVK().Kind
+}
+
+// newNameCollisionError returns the error of a generated object whose name is taken by an object which was not
+// generated for the claim.
+func newNameCollisionError(kind, namespace, name string, obc *v1alpha1.ObjectBucketClaim) error {
+	return fmt.Errorf("%s \"%s/%s\" already exists and does not belong to claim \"%s/%s\"", kind, namespace, name, obc.Namespace, obc.Name)
+}
+
+// additionalOwner is the value of the api.AdditionalOwnerAnnotation
+type additionalOwner struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+	// Namespace is optional, if set it must be the claim's
+	Namespace string `json:"namespace,omitempty"`
+	// Controller is optional, if set it must be false
+	Controller *bool `json:"controller,omitempty"`
+}
+
+// ownerReferencesFor returns the owner references of the claim's generated ConfigMap and Secret: the claim, their
+// controller, followed by the owner named by the claim's AdditionalOwnerAnnotation, if set.
+func ownerReferencesFor(obc *v1alpha1.ObjectBucketClaim) ([]metav1.OwnerReference, error) {
+	refs := []metav1.OwnerReference{makeOwnerReference(obc)}
+	raw, ok := obc.Annotations[api.AdditionalOwnerAnnotation]
+	if !ok {
+		return refs, nil
+	}
+	var owner additionalOwner
+	if err := json.Unmarshal([]byte(raw), &owner); err != nil {
+		return nil, fmt.Errorf("invalid annotation %q: %v", api.AdditionalOwnerAnnotation, err)
+	}
+	switch {
+	case owner.APIVersion == "" || owner.Kind == "" || owner.Name == "" || owner.UID == "":
+		return nil, fmt.Errorf("invalid annotation %q: apiVersion, kind, name and uid are required", api.AdditionalOwnerAnnotation)
+	case owner.Namespace != "" && owner.Namespace != obc.Namespace:
+		return nil, fmt.Errorf("invalid annotation %q: owner must be in the claim's namespace %q, got %q",
+			api.AdditionalOwnerAnnotation, obc.Namespace, owner.Namespace)
+	case owner.Controller != nil && *owner.Controller:
+		return nil, fmt.Errorf("invalid annotation %q: the claim is the controller of its ConfigMap and Secret, owner cannot be",
+			api.AdditionalOwnerAnnotation)
+	case owner.UID == obc.UID:
+		return nil, fmt.Errorf("invalid annotation %q: owner is the claim itself", api.AdditionalOwnerAnnotation)
+	}
+	isController := false
+	return append(refs, metav1.OwnerReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Name:       owner.Name,
+		UID:        owner.UID,
+		Controller: &isController,
+	}), nil
+}
+
+// newArtifactObjectMeta returns the metadata shared by the ConfigMap and Secret generated for obc, so that both are
+// garbage collected and released alike: the library's finalizer of their kind, kindFinalizer, guarding against
+// accidental deletion until the claim is released, and the owner references of ownerReferencesFor, the claim being the
+// controller.
+func newArtifactObjectMeta(obc *v1alpha1.ObjectBucketClaim, name, kindFinalizer string, labels map[string]string) (metav1.ObjectMeta, error) {
+	owners, err := ownerReferencesFor(obc)
+	if err != nil {
+		return metav1.ObjectMeta{}, err
+	}
+	meta := metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       obc.Namespace,
+		Finalizers:      []string{kindFinalizer},
+		Labels:          labels,
+		OwnerReferences: owners,
+	}
+	if obc.UID != "" {
+		metav1.SetMetaDataAnnotation(&meta, api.ClaimUIDAnnotation, string(obc.UID))
+	}
+	return meta, nil
+}
+
 func shouldProvision(obc *v1alpha1.ObjectBucketClaim) bool {
 	logD.Info("validating claim for provisioning obc", obc.Name)
 	if obc.Spec.ObjectBucketName != "" {
@@ -102,12 +192,13 @@ func isNewBucketByObjectBucket(c kubernetes.Interface, ob *v1alpha1.ObjectBucket
 	return len(class.Parameters[v1alpha1.StorageClassBucket]) == 0
 }
 
-func configMapForClaimKey(key string, c kubernetes.Interface) (*corev1.ConfigMap, error) {
+func configMapForClaimKey(key string, naming ArtifactNaming, c kubernetes.Interface) (*corev1.ConfigMap, error) {
 	logD.Info("getting configMap for key", "key", key)
 	ns, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		return nil, err
 	}
+	name = naming.Name(name)
 	cm, err := c.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("error getting configmap %q: %v", ns+"/"+name, err)
@@ -115,12 +206,13 @@ func configMapForClaimKey(key string, c kubernetes.Interface) (*corev1.ConfigMap
 	return cm, nil
 }
 
-func secretForClaimKey(key string, c kubernetes.Interface) (sec *corev1.Secret, err error) {
+func secretForClaimKey(key string, naming ArtifactNaming, c kubernetes.Interface) (sec *corev1.Secret, err error) {
 	logD.Info("getting secret for key", "key", key)
 	ns, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
 		return nil, err
 	}
+	name = naming.Name(name)
 	sec, err = c.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("error getting secret %q: %v", ns+"/"+name, err)
@@ -128,7 +220,285 @@ func secretForClaimKey(key string, c kubernetes.Interface) (sec *corev1.Secret,
 	return
 }
 
-func setObjectBucketName(ob *v1alpha1.ObjectBucket, key string) {
+// ObjectBucketNaming selects how the name of a generated ObjectBucket is derived.
+type ObjectBucketNaming int
+
+const (
+	// ObjectBucketNameFromClaim derives the OB name from the claim's namespace and name. This is the default.
+	ObjectBucketNameFromClaim ObjectBucketNaming = iota
+	// ObjectBucketNameFromBucket derives the OB name from the bucket name so that the same bucket maps to the same
+	// OB in every cluster, eg. for static binding or cross-cluster restore.
+	ObjectBucketNameFromBucket
+)
+
+// ArtifactNaming derives the names of the ConfigMap and Secret generated for a claim from the claim's name, eg. to
+// satisfy a policy requiring a fixed prefix. The zero value names them after the claim. Changing the naming does not
+// rename the artifacts of existing claims, which are then no longer found.
+type ArtifactNaming struct {
+	Prefix string
+	Suffix string
+}
+
+// Name returns the name of the artifacts of the named claim.
+func (n ArtifactNaming) Name(claimName string) string {
+	return n.Prefix + claimName + n.Suffix
+}
+
+// Validate returns an error if the prefix or suffix cannot be part of a DNS-1123 subdomain name.
+func (n ArtifactNaming) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(n.Name("a")); len(errs) > 0 {
+		return fmt.Errorf("invalid artifact name prefix %q or suffix %q: %s", n.Prefix, n.Suffix, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// ArtifactReclaimPolicy selects what happens to the Secret and ConfigMaps of a deleted claim whose bucket is retained,
+// ie. whose reclaim policy is Retain.
+type ArtifactReclaimPolicy int
+
+const (
+	// ArtifactReclaimDelete deletes them with the claim, as for any other claim. This is the default.
+	ArtifactReclaimDelete ArtifactReclaimPolicy = iota
+	// ArtifactReclaimRetain keeps them: they are detached from the claim and annotated with
+	// api.RetainedFromClaimAnnotation, so that they can be found when the claim is restored.
+	ArtifactReclaimRetain
+)
+
+// detachFromClaim removes the owner reference to obc and the library's finalizer from obj, and records obc in the
+// RetainedFromClaimAnnotation.
+func detachFromClaim(obj metav1.Object, obc *v1alpha1.ObjectBucketClaim) {
+	var refs []metav1.OwnerReference
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != obc.UID {
+			refs = append(refs, ref)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+	removeFinalizer(obj)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[api.RetainedFromClaimAnnotation] = obc.Namespace + "/" + obc.Name
+	obj.SetAnnotations(annotations)
+}
+
+// StaleArtifactPolicy selects what happens when the Secret or ConfigMap of a claim being provisioned already exists
+// and was generated for a deleted claim of the same name, eg. because releasing it failed when that claim was deleted.
+type StaleArtifactPolicy int
+
+const (
+	// StaleArtifactReclaim transfers them to the new claim, whose provisioning overwrites their data. Only artifacts
+	// recording the UID of their claim in the api.ClaimUIDAnnotation are reclaimed. This is the default.
+	StaleArtifactReclaim StaleArtifactPolicy = iota
+	// StaleArtifactFail leaves them alone and fails the provisioning of the new claim, as for any object of the same
+	// name which was not generated for it.
+	StaleArtifactFail
+)
+
+// CreationOrder selects whether the ObjectBucket of a claim being provisioned is created before or after the claim's
+// Secret, ConfigMap and ServiceAccount, which determines the partial state left behind if the controller stops midway.
+type CreationOrder int
+
+const (
+	// CreationOrderArtifactsFirst creates the Secret, ConfigMap and ServiceAccount, then the ObjectBucket. An interrupted
+	// sync leaves artifacts without an ObjectBucket; the next sync finds the bucket through the provisioner, updates
+	// the artifacts in place and creates the ObjectBucket. This is the default.
+	CreationOrderArtifactsFirst CreationOrder = iota
+	// CreationOrderObjectBucketFirst creates the ObjectBucket, then the Secret, ConfigMap and ServiceAccount. An
+	// interrupted sync leaves an ObjectBucket bound to the claim, the cluster-scoped record of the bucket, without
+	// artifacts; the next sync adopts the ObjectBucket and creates the missing artifacts.
+	CreationOrderObjectBucketFirst
+)
+
+// isBoundToClaim returns true if ob's claimRef records the UID of obc. Such an OB was created by the library for obc,
+// eg. by a sync which was interrupted before the claim recorded it, as a claim's UID is not known beforehand.
+func isBoundToClaim(ob *v1alpha1.ObjectBucket, obc *v1alpha1.ObjectBucketClaim) bool {
+	ref := ob.Spec.ClaimRef
+	return ref != nil && ref.UID != "" && ref.UID == obc.UID
+}
+
+// DeletionStep is a step of the teardown of a deleted claim's resources, once Delete or Revoke was called.
+type DeletionStep string
+
+const (
+	// DeletionStepObjectBucket removes the library's finalizer from the ObjectBucket and deletes it
+	DeletionStepObjectBucket DeletionStep = "ObjectBucket"
+	// DeletionStepSecret removes the library's finalizer from the claim's Secret
+	DeletionStepSecret DeletionStep = "Secret"
+	// DeletionStepConfigMap removes the library's finalizer from the claim's ConfigMap
+	DeletionStepConfigMap DeletionStep = "ConfigMap"
+	// DeletionStepServiceAccount removes the library's finalizer from the ServiceAccount created for the claim
+	DeletionStepServiceAccount DeletionStep = "ServiceAccount"
+)
+
+// DefaultDeletionSequence is the order in which the resources of a deleted claim are deleted or released, unless
+// configured otherwise. The claim's own finalizer is always removed last.
+var DefaultDeletionSequence = []DeletionStep{
+	DeletionStepObjectBucket,
+	DeletionStepSecret,
+	DeletionStepConfigMap,
+	DeletionStepServiceAccount,
+}
+
+// validateDeletionSequence returns an error unless sequence holds each DeletionStep exactly once.
+func validateDeletionSequence(sequence []DeletionStep) error {
+	seen := make(map[DeletionStep]bool, len(sequence))
+	for _, step := range sequence {
+		switch step {
+		case DeletionStepObjectBucket, DeletionStepSecret, DeletionStepConfigMap, DeletionStepServiceAccount:
+		default:
+			return fmt.Errorf("unknown deletion step %q", step)
+		}
+		if seen[step] {
+			return fmt.Errorf("deletion step %q is repeated", step)
+		}
+		seen[step] = true
+	}
+	if len(seen) != len(DefaultDeletionSequence) {
+		return fmt.Errorf("deletion sequence %v must hold each of %v", sequence, DefaultDeletionSequence)
+	}
+	return nil
+}
+
+// isStaleArtifact returns true if obj was generated for a claim other than obc, as recorded by its
+// api.ClaimUIDAnnotation. Artifacts kept by ArtifactReclaimRetain are not stale, they are adopted explicitly.
+func isStaleArtifact(obj metav1.Object, obc *v1alpha1.ObjectBucketClaim) bool {
+	uid, ok := obj.GetAnnotations()[api.ClaimUIDAnnotation]
+	if !ok || uid == string(obc.UID) {
+		return false
+	}
+	_, retained := obj.GetAnnotations()[api.RetainedFromClaimAnnotation]
+	return !retained
+}
+
+// parseReclaimPolicy parses a reclaim policy set by annotation, "Delete" or "Retain" in any case.
+func parseReclaimPolicy(v string) (*corev1.PersistentVolumeReclaimPolicy, error) {
+	for _, policy := range []corev1.PersistentVolumeReclaimPolicy{corev1.PersistentVolumeReclaimDelete, corev1.PersistentVolumeReclaimRetain} {
+		if strings.EqualFold(v, string(policy)) {
+			return &policy, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a reclaim policy, expected %q or %q", v, corev1.PersistentVolumeReclaimDelete,
+		corev1.PersistentVolumeReclaimRetain)
+}
+
+// attachToClaim replaces the owner references to the claim obj was generated for with those of obc, ensures it carries
+// the library's finalizer, and records obc in the api.ClaimUIDAnnotation.
+func attachToClaim(obj metav1.Object, obc *v1alpha1.ObjectBucketClaim) error {
+	owners, err := ownerReferencesFor(obc)
+	if err != nil {
+		return err
+	}
+	obj.SetOwnerReferences(owners)
+	migrateFinalizer(obj)
+	if f := finalizerFor(obj); !hasFinalizer(obj.GetFinalizers(), f) {
+		obj.SetFinalizers(append(obj.GetFinalizers(), f))
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[api.ClaimUIDAnnotation] = string(obc.UID)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// EndpointTransformer rewrites the Endpoint returned by the provisioner before it is published in the claim's ConfigMap
+// and Secret, eg. to replace an internal service host with the host of an external ingress. It is passed a copy and
+// returns the Endpoint to publish. The ObjectBucket keeps the provisioner's Endpoint.
+type EndpointTransformer func(*v1alpha1.Endpoint) (*v1alpha1.Endpoint, error)
+
+// ConnectionVerifier checks that a newly provisioned bucket can be reached with the credentials generated for its
+// claim, eg. with a HEAD or list request against the endpoint. It is passed the endpoint as published to the claim and
+// the data of the claim's Secret, nil if there is none, and must return by the deadline of ctx. An error keeps the
+// claim from being marked Bound; the verification is retried until it succeeds.
+type ConnectionVerifier func(ctx context.Context, ep *v1alpha1.Endpoint, credentials map[string]string) error
+
+// SecretAnnotator returns annotations to add to the Secret generated for a claim, eg. the role ARN or service account
+// email a cloud provider's workload identity integration reads. It is passed the claim and the ObjectBucket returned by
+// Provision or Grant. Other annotations of the Secret are kept.
+type SecretAnnotator func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (map[string]string, error)
+
+// ServiceAccountBinder returns a ServiceAccount to create in the claim's namespace for the pods using the bucket, eg.
+// annotated with the cloud provider identity granted access to it. It is passed the claim and the ObjectBucket returned
+// by Provision or Grant, and returns nil to create none. Only the name, labels and annotations of the ServiceAccount are
+// used. Like the claim's Secret, it is owned by the claim and carries the library's finalizer until the claim is
+// deleted.
+type ServiceAccountBinder func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) (*corev1.ServiceAccount, error)
+
+// OnBoundHook is called once a claim is Bound, eg. to notify a downstream system of the new bucket. It is passed the
+// claim, its ObjectBucket and the ConfigMap and Secret generated for it, either of which may be nil. It is called at
+// least once per claim: the delivery is recorded in the claim's api.BoundNotifiedAnnotation after the hook returns, so a
+// controller restart in between calls it again. An error is logged and does not fail the reconcile nor cause another
+// call.
+type OnBoundHook func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, cm *corev1.ConfigMap, secret *corev1.Secret) error
+
+// OnDeletedHook is called once the teardown of a deleted claim completed, with the claim and its ObjectBucket, nil if
+// it was already gone. It is called before the library's finalizer is removed from the claim, so it may be called
+// again for the same claim if removing the finalizer fails. An error is logged and does not hold back the deletion.
+type OnDeletedHook func(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket) error
+
+// AuthenticationPolicy selects what happens when Provision or Grant returns no credentials, ie. a nil Authentication
+// or one whose values are all empty.
+type AuthenticationPolicy int
+
+const (
+	// AuthenticationCreateEmpty creates the claim's Secret regardless, with empty values. This is the default.
+	AuthenticationCreateEmpty AuthenticationPolicy = iota
+	// AuthenticationSkipSecret creates no Secret for the claim, eg. for provisioners delivering credentials
+	// out-of-band. A missing Secret of a bound claim is not recreated either.
+	AuthenticationSkipSecret
+	// AuthenticationFail fails the claim's provisioning. The bucket is cleaned up as for any other failure.
+	AuthenticationFail
+)
+
+// isEmptyAuthentication returns true if auth holds no credentials.
+func isEmptyAuthentication(auth *v1alpha1.Authentication) bool {
+	for _, v := range auth.ToMap() {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// propagateAnnotations makes the annotations of ob under any of prefixes mirror those of the claim: the claim's are
+// copied and the ones the claim no longer has are removed. Annotations outside of prefixes are left as is. It returns
+// whether ob changed.
+func propagateAnnotations(ob *v1alpha1.ObjectBucket, obc *v1alpha1.ObjectBucketClaim, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+	propagated := func(k string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(k, p) {
+				return true
+			}
+		}
+		return false
+	}
+	changed := false
+	for k := range ob.Annotations {
+		if _, ok := obc.Annotations[k]; !ok && propagated(k) {
+			delete(ob.Annotations, k)
+			changed = true
+		}
+	}
+	for k, v := range obc.Annotations {
+		if cur, ok := ob.Annotations[k]; propagated(k) && (!ok || cur != v) {
+			metav1.SetMetaDataAnnotation(&ob.ObjectMeta, k, v)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func setObjectBucketName(ob *v1alpha1.ObjectBucket, key, bucketName string, naming ObjectBucketNaming) {
+	if naming == ObjectBucketNameFromBucket {
+		ob.Name = objectBucketNameFromBucketName(bucketName)
+		return
+	}
 	obName, err := objectBucketNameFromClaimKey(key)
 	if err != nil {
 		return
@@ -136,6 +506,49 @@ func setObjectBucketName(ob *v1alpha1.ObjectBucket, key string) {
 	ob.Name = obName
 }
 
+// idempotencyKey derives the idempotency key of provisioning the claim's bucket from the claim's UID and the bucket
+// name, or returns the key recorded in the claim's existing OB, if any.
+func idempotencyKey(obc *v1alpha1.ObjectBucketClaim, bucketName string, existing *v1alpha1.ObjectBucket) string {
+	if existing != nil {
+		if key := existing.Annotations[api.IdempotencyKeyAnnotation]; key != "" {
+			return key
+		}
+	}
+	sum := sha256.Sum256([]byte(string(obc.UID) + "/" + bucketName))
+	return hex.EncodeToString(sum[:])
+}
+
+const obNameHashLen = 8
+
+// objectBucketNameFromBucketName returns a valid DNS-1123 subdomain derived from the bucket name. Characters other than
+// lower case alphanumerics are replaced with "-", so a short hash of the unmodified bucket name is appended to keep distinct bucket
+// names from colliding once sanitized.
+func objectBucketNameFromBucketName(bucketName string) string {
+	sum := sha256.Sum256([]byte(bucketName))
+	hash := hex.EncodeToString(sum[:])[:obNameHashLen]
+
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, bucketName)
+
+	// leave room for the "ob-" prefix and "-<hash>" suffix
+	maxLen := validation.DNS1123SubdomainMaxLength - len("ob-") - len(hash) - 1
+	if len(sanitized) > maxLen {
+		sanitized = sanitized[:maxLen]
+	}
+	sanitized = strings.Trim(sanitized, "-")
+	if sanitized == "" {
+		return fmt.Sprintf("ob-%s", hash)
+	}
+	return fmt.Sprintf("ob-%s-%s", sanitized, hash)
+}
+
 func objectBucketNameFromClaimKey(key string) (string, error) {
 	ns, name, err := cache.SplitMetaNamespaceKey(key)
 	if err != nil {
@@ -144,7 +557,7 @@ func objectBucketNameFromClaimKey(key string) (string, error) {
 	return fmt.Sprintf(objectBucketNameFormat, ns, name), nil
 }
 
-func composeBucketName(obc *v1alpha1.ObjectBucketClaim) (string, error) {
+func composeBucketName(obc *v1alpha1.ObjectBucketClaim, suffixLen, maxLen int) (string, error) {
 	if obc.Spec.BucketName == "" && obc.Spec.GenerateBucketName == "" {
 		return "", fmt.Errorf("expected either bucketName or generateBucketName defined")
 	}
@@ -153,33 +566,58 @@ func composeBucketName(obc *v1alpha1.ObjectBucketClaim) (string, error) {
 	}
 	bucketName := obc.Spec.BucketName
 	if bucketName == "" {
-		bucketName = generateBucketName(obc.Spec.GenerateBucketName)
+		bucketName = generateBucketName(obc.Spec.GenerateBucketName, suffixLen, maxLen)
 	}
 	return bucketName, nil
 }
 
 const (
-	maxNameLen     = 63
-	uuidSuffixLen  = 36
-	maxBaseNameLen = maxNameLen - uuidSuffixLen
+	maxNameLen    = 63
+	uuidSuffixLen = 36
+
+	// MinBucketNameSuffixLength is the shortest random suffix which may be configured for generated bucket names
+	MinBucketNameSuffixLength = 5
+	// MaxBucketNameSuffixLength is the longest random suffix which may be configured for generated bucket names. It
+	// leaves room for a one character prefix and the separating hyphen.
+	MaxBucketNameSuffixLength = maxNameLen - 2
 )
 
-func generateBucketName(prefix string) string {
-	if len(prefix) > maxBaseNameLen {
-		prefix = prefix[:maxBaseNameLen-1]
+// generateBucketName appends a random suffix of suffixLen characters to prefix, or a UUID if suffixLen is 0. The
+// prefix is truncated as needed to keep the name within maxLen, and the suffix too if it leaves no room for a one
+// character prefix.
+func generateBucketName(prefix string, suffixLen, maxLen int) string {
+	suffix := utilrand.String(suffixLen)
+	if suffixLen == 0 {
+		suffix = uuid.New().String()
 	}
-	return fmt.Sprintf("%s-%s", prefix, uuid.New())
+	if len(suffix) > maxLen-2 {
+		suffix = suffix[:maxLen-2]
+	}
+	if maxPrefixLen := maxLen - len(suffix) - 1; len(prefix) > maxPrefixLen {
+		prefix = prefix[:maxPrefixLen]
+	}
+	return fmt.Sprintf("%s-%s", prefix, suffix)
 }
 
-func storageClassForClaim(c kubernetes.Interface, obc *v1alpha1.ObjectBucketClaim) (*storagev1.StorageClass, error) {
+// storageClassForClaim returns the StorageClass of obc, or the default StorageClass if it names none, from classes. The
+// returned StorageClass is shared with the informer's cache and must not be modified.
+func storageClassForClaim(classes storagelisters.StorageClassLister, obc *v1alpha1.ObjectBucketClaim) (*storagev1.StorageClass, error) {
 	if obc == nil {
 		return nil, fmt.Errorf("got nil ObjectBucketClaim pointer")
 	}
 	if obc.Spec.StorageClassName == "" {
-		return nil, fmt.Errorf("no StorageClass defined for ObjectBucketClaim \"%s/%s\"", obc.Namespace, obc.Name)
+		class, err := defaultStorageClass(classes)
+		if err != nil {
+			return nil, err
+		}
+		if class == nil {
+			return nil, fmt.Errorf("no StorageClass defined for ObjectBucketClaim \"%s/%s\" and no default StorageClass found", obc.Namespace, obc.Name)
+		}
+		log.Info("using default StorageClass", "name", class.Name)
+		return class, nil
 	}
 	logD.Info("getting ObjectBucketClaim's StorageClass")
-	class, err := c.StorageV1().StorageClasses().Get(obc.Spec.StorageClassName, metav1.GetOptions{})
+	class, err := classes.Get(obc.Spec.StorageClassName)
 	if err != nil {
 		return nil, fmt.Errorf("error getting StorageClass %q: %v", obc.Spec.StorageClassName, err)
 	}
@@ -187,6 +625,32 @@ func storageClassForClaim(c kubernetes.Interface, obc *v1alpha1.ObjectBucketClai
 	return class, nil
 }
 
+// defaultStorageClass returns the StorageClass annotated as the default for OBCs, or nil if there is none. It is an
+// error for more than one StorageClass to be annotated as the default.
+func defaultStorageClass(classes storagelisters.StorageClassLister) (*storagev1.StorageClass, error) {
+	list, err := classes.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("error listing StorageClasses: %v", err)
+	}
+	var defaults []*storagev1.StorageClass
+	for _, class := range list {
+		if class.Annotations[api.DefaultStorageClassAnnotation] == "true" {
+			defaults = append(defaults, class)
+		}
+	}
+	switch len(defaults) {
+	case 0:
+		return nil, nil
+	case 1:
+		return defaults[0], nil
+	}
+	names := make([]string, 0, len(defaults))
+	for _, sc := range defaults {
+		names = append(names, sc.Name)
+	}
+	return nil, fmt.Errorf("found %d default StorageClasses %v, expected at most 1", len(defaults), names)
+}
+
 func storageClassForObjectBucket(ob *v1alpha1.ObjectBucket, c kubernetes.Interface) (*storagev1.StorageClass, error) {
 	if ob == nil {
 		return nil, fmt.Errorf("got nil ObjectBucket pointer")
@@ -204,14 +668,96 @@ func storageClassForObjectBucket(ob *v1alpha1.ObjectBucket, c kubernetes.Interfa
 	return class, nil
 }
 
+// finalizerFor returns the library's finalizer of obj's kind. Each kind has its own, so that releasing one resource
+// cannot clear the finalizer guarding another.
+func finalizerFor(obj metav1.Object) string {
+	switch obj.(type) {
+	case *v1alpha1.ObjectBucket:
+		return objectBucketFinalizer
+	case *corev1.ConfigMap:
+		return configMapFinalizer
+	case *corev1.Secret:
+		return secretFinalizer
+	case *corev1.ServiceAccount:
+		return serviceAccountFinalizer
+	}
+	return finalizer
+}
+
+// isLibraryFinalizer returns whether f is one of the library's finalizers.
+func isLibraryFinalizer(f string) bool {
+	switch f {
+	case finalizer, objectBucketFinalizer, configMapFinalizer, secretFinalizer, serviceAccountFinalizer:
+		return true
+	}
+	return false
+}
+
+// removeFinalizer removes the library's finalizer of obj's kind from obj, and the finalizer shared by all kinds which
+// resources generated by earlier versions of the library carry. Finalizers of other controllers are left in place.
 func removeFinalizer(obj metav1.Object) {
+	own := finalizerFor(obj)
 	finalizers := obj.GetFinalizers()
-	for i, f := range finalizers {
+	kept := make([]string, 0, len(finalizers))
+	for _, f := range finalizers {
+		if f != own && f != finalizer {
+			kept = append(kept, f)
+		}
+	}
+	obj.SetFinalizers(kept)
+}
+
+// migrateFinalizer replaces the finalizer shared by all kinds, set by earlier versions of the library, with the
+// library's finalizer of obj's kind. It returns whether obj changed.
+func migrateFinalizer(obj metav1.Object) bool {
+	own := finalizerFor(obj)
+	if own == finalizer || !hasFinalizer(obj.GetFinalizers(), finalizer) {
+		return false
+	}
+	var finalizers []string
+	for _, f := range obj.GetFinalizers() {
 		if f == finalizer {
-			obj.SetFinalizers(append(finalizers[:i], finalizers[i+1:]...))
-			break
+			f = own
+		}
+		if !hasFinalizer(finalizers, f) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	obj.SetFinalizers(finalizers)
+	return true
+}
+
+// provisionerFinalizers returns the finalizers of obj other than the library's.
+func provisionerFinalizers(obj metav1.Object) []string {
+	var others []string
+	for _, f := range obj.GetFinalizers() {
+		if !isLibraryFinalizer(f) {
+			others = append(others, f)
+		}
+	}
+	return others
+}
+
+// hasLibraryFinalizer returns true if obj carries any of the library's finalizers.
+func hasLibraryFinalizer(obj metav1.Object) bool {
+	for _, f := range obj.GetFinalizers() {
+		if isLibraryFinalizer(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingClaimFinalizers returns the finalizers which other controllers must remove from the deleted claim before it is
+// released: all but the library's and those of the garbage collector, which waits for the claim's dependents.
+func pendingClaimFinalizers(obc *v1alpha1.ObjectBucketClaim) []string {
+	var pending []string
+	for _, f := range provisionerFinalizers(obc) {
+		if f != metav1.FinalizerOrphanDependents && f != metav1.FinalizerDeleteDependents {
+			pending = append(pending, f)
 		}
 	}
+	return pending
 }
 
 // replace illegal label value characters with "-".
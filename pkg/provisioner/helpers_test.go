@@ -20,12 +20,17 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/fake"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
 
+	corev1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -190,7 +195,6 @@ func TestStorageClassForClaim(t *testing.T) {
 
 	type args struct {
 		obc       *v1alpha1.ObjectBucketClaim
-		client    *fake.Clientset
 		extClient *externalFake.Clientset
 	}
 
@@ -204,7 +208,6 @@ func TestStorageClassForClaim(t *testing.T) {
 			name: "nil OBC ptr",
 			args: args{
 				obc:       nil,
-				client:    fake.NewSimpleClientset(),
 				extClient: externalFake.NewSimpleClientset(),
 			},
 			want:    nil,
@@ -219,7 +222,6 @@ func TestStorageClassForClaim(t *testing.T) {
 						StorageClassName: "",
 					},
 				},
-				client:    fake.NewSimpleClientset(),
 				extClient: externalFake.NewSimpleClientset(),
 			},
 			want:    nil,
@@ -233,7 +235,6 @@ func TestStorageClassForClaim(t *testing.T) {
 						StorageClassName: storageClassName,
 					},
 				},
-				client:    fake.NewSimpleClientset(),
 				extClient: externalFake.NewSimpleClientset(),
 			},
 			want: &storagev1.StorageClass{
@@ -253,7 +254,6 @@ func TestStorageClassForClaim(t *testing.T) {
 						StorageClassName: storageClassName,
 					},
 				},
-				client:    fake.NewSimpleClientset(),
 				extClient: externalFake.NewSimpleClientset(),
 			},
 			want:    nil,
@@ -271,14 +271,12 @@ func TestStorageClassForClaim(t *testing.T) {
 					t.Errorf("error pre-creating OBC: %v", err)
 				}
 			}
-			class := tt.want
-			if class != nil {
-				if class, err = tt.args.client.StorageV1().StorageClasses().Create(class); err != nil {
-					t.Errorf("error pre-creating StorageClass: %v", err)
-				}
+			var classes []*storagev1.StorageClass
+			if tt.want != nil {
+				classes = append(classes, tt.want)
 			}
 
-			got, err := storageClassForClaim(tt.args.client, tt.args.obc)
+			got, err := storageClassForClaim(newClassLister(classes...), tt.args.obc)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("StorageClassForClaim() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -290,6 +288,60 @@ func TestStorageClassForClaim(t *testing.T) {
 	}
 }
 
+// newClassLister returns a StorageClassLister backed by an indexer pre-populated with classes.
+func newClassLister(classes ...*storagev1.StorageClass) storagelisters.StorageClassLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, class := range classes {
+		_ = indexer.Add(class)
+	}
+	return storagelisters.NewStorageClassLister(indexer)
+}
+
+func TestStorageClassForClaimDefault(t *testing.T) {
+	newClass := func(name string, isDefault bool) *storagev1.StorageClass {
+		sc := &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if isDefault {
+			sc.Annotations = map[string]string{api.DefaultStorageClassAnnotation: "true"}
+		}
+		return sc
+	}
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: objMeta}
+
+	tests := []struct {
+		name    string
+		classes []*storagev1.StorageClass
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "no default class",
+			classes: []*storagev1.StorageClass{newClass("a", false)},
+			wantErr: true,
+		},
+		{
+			name:    "single default class",
+			classes: []*storagev1.StorageClass{newClass("a", false), newClass("b", true)},
+			want:    "b",
+		},
+		{
+			name:    "multiple default classes",
+			classes: []*storagev1.StorageClass{newClass("a", true), newClass("b", true)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storageClassForClaim(newClassLister(tt.classes...), obc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("storageClassForClaim() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Name != tt.want {
+				t.Errorf("storageClassForClaim() = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
 func TestGenerateBucketName(t *testing.T) {
 	type args struct {
 		prefix string
@@ -326,7 +378,7 @@ func TestGenerateBucketName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := generateBucketName(tt.args.prefix)
+			got := generateBucketName(tt.args.prefix, 0, maxNameLen)
 			if len(got) > maxNameLen {
 				t.Errorf("GenerateName() wanted len <= %d, got len %d", maxNameLen, len(got))
 			}
@@ -337,4 +389,235 @@ func TestGenerateBucketName(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestGenerateBucketNameSuffixLength(t *testing.T) {
+	for _, suffixLen := range []int{MinBucketNameSuffixLength, 16, MaxBucketNameSuffixLength} {
+		for _, prefix := range []string{"foobar", rand.String(maxNameLen * 2)} {
+			got := generateBucketName(prefix, suffixLen, maxNameLen)
+			if len(got) > maxNameLen {
+				t.Errorf("generateBucketName(%d) wanted len <= %d, got len %d", suffixLen, maxNameLen, len(got))
+			}
+			i := strings.LastIndex(got, "-")
+			if i < 1 || len(got)-i-1 != suffixLen {
+				t.Errorf("generateBucketName(%d) = %q, want a %d character suffix after a non-empty prefix", suffixLen, got, suffixLen)
+			}
+		}
+	}
+}
+
+func TestObjectBucketNameFromBucketName(t *testing.T) {
+	tests := []struct {
+		name       string
+		bucketName string
+	}{
+		{name: "valid bucket name", bucketName: "my-bucket"},
+		{name: "upper case and underscores", bucketName: "My_Bucket"},
+		{name: "only invalid characters", bucketName: "___"},
+		{name: "over max length", bucketName: rand.String(validation.DNS1123SubdomainMaxLength * 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := objectBucketNameFromBucketName(tt.bucketName)
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Errorf("objectBucketNameFromBucketName() = %q, not a valid name: %v", got, errs)
+			}
+			if again := objectBucketNameFromBucketName(tt.bucketName); again != got {
+				t.Errorf("objectBucketNameFromBucketName() not deterministic: %q != %q", got, again)
+			}
+		})
+	}
+
+	if objectBucketNameFromBucketName("my_bucket") == objectBucketNameFromBucketName("my-bucket") {
+		t.Errorf("objectBucketNameFromBucketName() sanitized names should not collide")
+	}
+}
+
+func TestArtifactNaming(t *testing.T) {
+	if got := (ArtifactNaming{}).Name("my-obc"); got != "my-obc" {
+		t.Errorf("Name() with default naming = %q, want the claim name", got)
+	}
+
+	tests := []struct {
+		name    string
+		naming  ArtifactNaming
+		want    string
+		wantErr bool
+	}{
+		{name: "prefix", naming: ArtifactNaming{Prefix: "obc-"}, want: "obc-my-obc"},
+		{name: "suffix", naming: ArtifactNaming{Suffix: ".bucket"}, want: "my-obc.bucket"},
+		{name: "uppercase prefix", naming: ArtifactNaming{Prefix: "OBC-"}, want: "OBC-my-obc", wantErr: true},
+		{name: "trailing dash suffix", naming: ArtifactNaming{Suffix: "-"}, want: "my-obc-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.naming.Name("my-obc"); got != tt.want {
+				t.Errorf("Name() = %q, want %q", got, tt.want)
+			}
+			if err := tt.naming.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsEmptyAuthentication(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *v1alpha1.Authentication
+		want bool
+	}{
+		{name: "nil", auth: nil, want: true},
+		{name: "no access keys", auth: &v1alpha1.Authentication{}, want: true},
+		{name: "empty access keys", auth: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{}}, want: true},
+		{name: "access keys", auth: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id", SecretAccessKey: "secret"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEmptyAuthentication(tt.auth); got != tt.want {
+				t.Errorf("isEmptyAuthentication() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{UID: "uid-1"}}
+	other := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{UID: "uid-2"}}
+
+	key := idempotencyKey(obc, "bucket", nil)
+	if again := idempotencyKey(obc.DeepCopy(), "bucket", nil); again != key {
+		t.Errorf("idempotencyKey() = %q on retry, want %q", again, key)
+	}
+	if k := idempotencyKey(other, "bucket", nil); k == key {
+		t.Errorf("idempotencyKey() = %q for another claim, want a different key", k)
+	}
+	if k := idempotencyKey(obc, "other-bucket", nil); k == key {
+		t.Errorf("idempotencyKey() = %q for another bucket, want a different key", k)
+	}
+
+	existing := &v1alpha1.ObjectBucket{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{api.IdempotencyKeyAnnotation: "recorded"},
+	}}
+	if k := idempotencyKey(obc, "bucket", existing); k != "recorded" {
+		t.Errorf("idempotencyKey() = %q with an existing OB, want the recorded key", k)
+	}
+	if k := idempotencyKey(obc, "bucket", &v1alpha1.ObjectBucket{}); k != key {
+		t.Errorf("idempotencyKey() = %q with an OB recording no key, want %q", k, key)
+	}
+}
+
+func TestOwnerReferencesFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		wantOwner  bool
+		wantErr    bool
+	}{
+		{"no annotation", "", false, false},
+		{"owner", `{"apiVersion":"example.com/v1","kind":"Tenant","name":"acme","uid":"tenant-uid"}`, true, false},
+		{"owner in the claim's namespace", `{"apiVersion":"example.com/v1","kind":"Tenant","name":"acme","uid":"tenant-uid","namespace":"` + testNamespace + `"}`, true, false},
+		{"owner in another namespace", `{"apiVersion":"example.com/v1","kind":"Tenant","name":"acme","uid":"tenant-uid","namespace":"other"}`, false, true},
+		{"controller", `{"apiVersion":"example.com/v1","kind":"Tenant","name":"acme","uid":"tenant-uid","controller":true}`, false, true},
+		{"missing uid", `{"apiVersion":"example.com/v1","kind":"Tenant","name":"acme"}`, false, true},
+		{"the claim itself", `{"apiVersion":"objectbucket.io/v1alpha1","kind":"ObjectBucketClaim","name":"` + testName + `","uid":"claim-uid"}`, false, true},
+		{"not json", `Tenant/acme`, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      testName,
+				UID:       "claim-uid",
+			}}
+			if tt.annotation != "" {
+				obc.Annotations = map[string]string{api.AdditionalOwnerAnnotation: tt.annotation}
+			}
+			got, err := ownerReferencesFor(obc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ownerReferencesFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := []metav1.OwnerReference{makeOwnerReference(obc)}
+			if tt.wantOwner {
+				isController := false
+				want = append(want, metav1.OwnerReference{
+					APIVersion: "example.com/v1",
+					Kind:       "Tenant",
+					Name:       "acme",
+					UID:        "tenant-uid",
+					Controller: &isController,
+				})
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ownerReferencesFor() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMigrateFinalizer(t *testing.T) {
+	legacy := []string{"example.com/first", finalizer, "example.com/last"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Finalizers: append([]string(nil), legacy...)}}
+	if !migrateFinalizer(secret) {
+		t.Fatalf("migrateFinalizer() = false for the shared finalizer, want true")
+	}
+	want := []string{"example.com/first", secretFinalizer, "example.com/last"}
+	if !reflect.DeepEqual(secret.Finalizers, want) {
+		t.Errorf("migrateFinalizer() finalizers = %v, want %v", secret.Finalizers, want)
+	}
+	if migrateFinalizer(secret) {
+		t.Errorf("migrateFinalizer() = true for a migrated secret, want false")
+	}
+
+	// the claim keeps the shared finalizer
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Finalizers: append([]string(nil), legacy...)}}
+	if migrateFinalizer(obc) || !reflect.DeepEqual(obc.Finalizers, legacy) {
+		t.Errorf("migrateFinalizer() changed the claim's finalizers to %v", obc.Finalizers)
+	}
+
+	// releasing a resource removes the finalizer of its kind and the shared one, never those of other kinds
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{finalizer, secretFinalizer, configMapFinalizer}}}
+	removeFinalizer(cm)
+	if !reflect.DeepEqual(cm.Finalizers, []string{secretFinalizer}) {
+		t.Errorf("removeFinalizer() finalizers = %v, want %v", cm.Finalizers, []string{secretFinalizer})
+	}
+}
+
+func TestPendingClaimFinalizers(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{
+		finalizer,
+		metav1.FinalizerDeleteDependents,
+		"example.com/dns-record",
+		metav1.FinalizerOrphanDependents,
+	}}}
+	want := []string{"example.com/dns-record"}
+	if got := pendingClaimFinalizers(obc); !reflect.DeepEqual(got, want) {
+		t.Errorf("pendingClaimFinalizers() = %v, want %v", got, want)
+	}
+	obc.Finalizers = []string{finalizer}
+	if got := pendingClaimFinalizers(obc); len(got) != 0 {
+		t.Errorf("pendingClaimFinalizers() = %v, want none", got)
+	}
+}
+
+func TestIsBoundToClaim(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, UID: "uid-1"}}
+	tests := []struct {
+		name string
+		ref  *corev1.ObjectReference
+		want bool
+	}{
+		{"unbound", nil, false},
+		{"bound by name only", &corev1.ObjectReference{Namespace: testNamespace, Name: testName}, false},
+		{"bound to another claim", &corev1.ObjectReference{Namespace: testNamespace, Name: testName, UID: "uid-2"}, false},
+		{"bound to the claim", &corev1.ObjectReference{Namespace: testNamespace, Name: testName, UID: "uid-1"}, true},
+	}
+	for _, tt := range tests {
+		ob := &v1alpha1.ObjectBucket{Spec: v1alpha1.ObjectBucketSpec{ClaimRef: tt.ref}}
+		if got := isBoundToClaim(ob, obc); got != tt.want {
+			t.Errorf("%s: isBoundToClaim() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// BucketDescriptor describes an existing bucket of the object store to import with ImportBuckets.
+type BucketDescriptor struct {
+	// BucketName is the name of the bucket in the object store
+	BucketName string
+	// ClaimName is the name of the claim the bucket is bound to, BucketName if empty
+	ClaimName string
+	// Endpoint and Authentication are the connection to the bucket, published in the claim's ConfigMap and Secret
+	Endpoint       *v1alpha1.Endpoint
+	Authentication *v1alpha1.Authentication
+	// AdditionalState is recorded in the ObjectBucket's connection for the provisioner, eg. a backend user name
+	AdditionalState map[string]string
+	// CreateClaim is true if the claim, its Secret and its ConfigMap are created along with the ObjectBucket.
+	// Otherwise only the ObjectBucket is created, annotated with api.AwaitingClaimAnnotation, and adopted by a claim
+	// named ClaimName once one is created.
+	CreateClaim bool
+}
+
+// ImportResult is the outcome of importing a bucket with ImportBuckets.
+type ImportResult struct {
+	BucketName string
+	// ObjectBucket is the name of the ObjectBucket of the bucket
+	ObjectBucket string
+	// Skipped is true if the ObjectBucket already existed and was Bound, ie. the bucket was imported before
+	Skipped bool
+	// Err is set if importing the bucket failed, other buckets are imported regardless
+	Err error
+}
+
+// ImportBuckets creates an ObjectBucket bound to a claim of namespace for each bucket of list, with the Retain reclaim
+// policy so that deleting the claim never deletes the bucket. Buckets whose ObjectBucket exists and is Bound are skipped,
+// and ObjectBuckets left unbound are marked Bound, so that an interrupted import can be run again. For descriptors
+// setting CreateClaim, the claim is created first, then its Secret and ConfigMap, then the ObjectBucket, and the claim
+// is marked Bound last: reconciles of the claim meanwhile are requeued until its ObjectBucket exists.
+func (c *obcController) ImportBuckets(list []BucketDescriptor, class *storagev1.StorageClass, namespace string) ([]ImportResult, error) {
+	if !c.servesStorageClass(class) {
+		return nil, fmt.Errorf("StorageClass is not served by provisioner %q", c.provisionerName)
+	}
+	options, err := c.provisionOptionsFor(nil, class, class.Parameters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters in StorageClass %q: %v", class.Name, err)
+	}
+	results := make([]ImportResult, 0, len(list))
+	for _, desc := range list {
+		result := ImportResult{BucketName: desc.BucketName}
+		result.ObjectBucket, result.Skipped, result.Err = c.importBucket(desc, class, options, namespace)
+		if result.Err != nil {
+			log.Error(result.Err, "error importing bucket", "bucket", desc.BucketName)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// importBucket imports a single bucket, returning the name of its ObjectBucket and whether it was imported before.
+func (c *obcController) importBucket(desc BucketDescriptor, class *storagev1.StorageClass, options *api.ProvisionOptions, namespace string) (string, bool, error) {
+	if desc.BucketName == "" {
+		return "", false, fmt.Errorf("bucket name missing")
+	}
+	if desc.Endpoint == nil {
+		return "", false, fmt.Errorf("endpoint of bucket %q missing", desc.BucketName)
+	}
+	claimName := desc.ClaimName
+	if claimName == "" {
+		claimName = desc.BucketName
+	}
+	ob := &v1alpha1.ObjectBucket{}
+	setObjectBucketName(ob, namespace+"/"+claimName, desc.BucketName, c.obNaming)
+	if ob.Name == "" {
+		return "", false, fmt.Errorf("unable to derive the ObjectBucket name of claim \"%s/%s\"", namespace, claimName)
+	}
+	existing, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+	if err == nil {
+		skipped, err := c.completeImport(existing, desc.CreateClaim)
+		return ob.Name, skipped, err
+	}
+	if !errors.IsNotFound(err) {
+		return ob.Name, false, annotateError(asPermissionError(err, "get", "objectbuckets", "", ob.Name), "error checking for existing OB")
+	}
+
+	retain := corev1.PersistentVolumeReclaimRetain
+	ep := desc.Endpoint.DeepCopy()
+	ep.BucketName = desc.BucketName
+	ob.Spec = v1alpha1.ObjectBucketSpec{
+		StorageClassName: class.Name,
+		ReclaimPolicy:    &retain,
+		// the claim references the OB by name, see ObjectBucketClaimSpec.ObjectBucketName
+		ClaimRef: &corev1.ObjectReference{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       v1alpha1.ObjectBucketClaimGVK().Kind,
+			Namespace:  namespace,
+			Name:       claimName,
+		},
+		Connection: &v1alpha1.Connection{
+			Endpoint:        ep,
+			AdditionalState: desc.AdditionalState,
+		},
+	}
+	ob.SetLabels(c.provisionerLabels)
+	ob.SetFinalizers([]string{objectBucketFinalizer})
+	if !desc.CreateClaim {
+		// the claim does not exist yet, the OB is not an orphan
+		metav1.SetMetaDataAnnotation(&ob.ObjectMeta, api.AwaitingClaimAnnotation, "true")
+	}
+
+	var obc *v1alpha1.ObjectBucketClaim
+	if desc.CreateClaim {
+		if obc, err = c.importClaim(claimName, namespace, class, ob.Name, desc.BucketName); err != nil {
+			return ob.Name, false, err
+		}
+		ob.Spec.ClaimRef = makeObjectReference(obc)
+		if err = c.importArtifacts(obc, ep, desc.Authentication, options); err != nil {
+			return ob.Name, false, err
+		}
+	}
+
+	created, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Create(ob)
+	if err != nil {
+		return ob.Name, false, annotateError(asPermissionError(err, "create", "objectbuckets", "", ob.Name),
+			fmt.Sprintf("error creating OB %q", ob.Name))
+	}
+	if err = c.markImported(created, obc); err != nil {
+		return ob.Name, false, err
+	}
+	log.Info("imported bucket", "bucket", desc.BucketName, "ob", ob.Name)
+	return ob.Name, false, nil
+}
+
+// completeImport marks Bound an existing ObjectBucket, and the claim created along with it, left unbound by an
+// interrupted import. It returns true if both were Bound already, ie. the bucket is skipped.
+func (c *obcController) completeImport(ob *v1alpha1.ObjectBucket, createClaim bool) (bool, error) {
+	if ob.DeletionTimestamp != nil {
+		return false, fmt.Errorf("ObjectBucket %q is being deleted", ob.Name)
+	}
+	var obc *v1alpha1.ObjectBucketClaim
+	if ref := ob.Spec.ClaimRef; createClaim && ref != nil {
+		var err error
+		obc, err = c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, annotateError(asPermissionError(err, "get", "objectbucketclaims", ref.Namespace, ref.Name),
+				fmt.Sprintf("error getting OBC \"%s/%s\"", ref.Namespace, ref.Name))
+		}
+	}
+	if ob.Status.Phase == v1alpha1.ObjectBucketStatusPhaseBound && (obc == nil || obc.Status.Phase == v1alpha1.ObjectBucketClaimStatusPhaseBound) {
+		logD.Info("bucket already imported, skipping", "ob", ob.Name)
+		return true, nil
+	}
+	log.Info("completing interrupted import", "ob", ob.Name)
+	return false, c.markImported(ob, obc)
+}
+
+// markImported marks the ObjectBucket of an imported bucket Bound, then its claim if it was created with it.
+func (c *obcController) markImported(ob *v1alpha1.ObjectBucket, obc *v1alpha1.ObjectBucketClaim) error {
+	if _, err := updateObjectBucketPhase(c.libClientset, ob, v1alpha1.ObjectBucketStatusPhaseBound, true, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+		return annotateError(err, "error updating OB status")
+	}
+	if obc != nil {
+		if _, err := updateObjectBucketClaimPhase(c.libClientset, obc, v1alpha1.ObjectBucketClaimStatusPhaseBound, true, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+			return annotateError(err, "error updating OBC status")
+		}
+	}
+	return nil
+}
+
+// importClaim returns the claim of an imported bucket, creating it bound to the named OB unless it exists, eg. because
+// an earlier import was interrupted before creating the OB. An existing claim must be bound to the same OB.
+func (c *obcController) importClaim(name, namespace string, class *storagev1.StorageClass, obName, bucketName string) (*v1alpha1.ObjectBucketClaim, error) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  namespace,
+			Labels:     c.provisionerLabels,
+			Finalizers: []string{finalizer},
+		},
+		Spec: v1alpha1.ObjectBucketClaimSpec{
+			StorageClassName: class.Name,
+			BucketName:       bucketName,
+			ObjectBucketName: obName,
+		},
+	}
+	claims := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(namespace)
+	created, err := claims.Create(obc)
+	if err == nil {
+		return created, nil
+	}
+	if !errors.IsAlreadyExists(err) {
+		return nil, annotateError(asPermissionError(err, "create", "objectbucketclaims", namespace, name),
+			fmt.Sprintf("error creating OBC \"%s/%s\"", namespace, name))
+	}
+	existing, err := claims.Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "get", "objectbucketclaims", namespace, name),
+			fmt.Sprintf("error getting OBC \"%s/%s\"", namespace, name))
+	}
+	if existing.Spec.ObjectBucketName != obName {
+		return nil, fmt.Errorf("OBC \"%s/%s\" exists and is not bound to ObjectBucket %q", namespace, name, obName)
+	}
+	return existing, nil
+}
+
+// importArtifacts creates the Secret and ConfigMap of the claim of an imported bucket. Artifacts left by an interrupted
+// import are updated in place.
+func (c *obcController) importArtifacts(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication, options *api.ProvisionOptions) error {
+	published, err := c.publishedEndpoint(ep)
+	if err != nil {
+		return err
+	}
+	if auth == nil {
+		auth = &v1alpha1.Authentication{}
+	}
+	budget, cancel := newRetryBudget()
+	defer cancel()
+	name := c.artifactNaming.Name(obc.Name)
+	if _, err = createSecret(budget, obc, name, published, auth, options, c.provisionerLabels, c.clientset, defaultRetryBaseInterval, c.auditor); err != nil {
+		return annotateError(err, "error creating secret for OBC")
+	}
+	if options.SkipConfigMap {
+		return nil
+	}
+	if _, err = createConfigMap(budget, obc, name, published, auth, options, c.provisionerLabels, c.clientset, defaultRetryBaseInterval, c.auditor); err != nil {
+		return annotateError(err, "error creating configmap for OBC")
+	}
+	return nil
+}
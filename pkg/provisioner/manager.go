@@ -18,8 +18,16 @@ package provisioner
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/klog"
@@ -36,6 +44,10 @@ type Provisioner struct {
 	Provisioner     api.Provisioner
 	claimController controller
 	informerFactory informers.SharedInformerFactory
+	// kubeInformerFactory watches the core resources generated by the provisioner
+	kubeInformerFactory kubeinformers.SharedInformerFactory
+	// classInformerFactory watches the cluster's storage classes
+	classInformerFactory kubeinformers.SharedInformerFactory
 }
 
 func initLoggers() {
@@ -78,18 +90,26 @@ func NewProvisioner(
 	clientset := kubernetes.NewForConfigOrDie(cfg)
 
 	informerFactory := setupInformerFactory(libClientset, 0, namespace)
+	kubeInformerFactory := setupKubeInformerFactory(clientset, 0, namespace, provisionerName)
+	classInformerFactory := kubeinformers.NewSharedInformerFactory(clientset, 0)
 
-	p := &Provisioner{
-		Name:            provisionerName,
-		informerFactory: informerFactory,
+	ctrl := NewController(
+		provisionerName,
+		provisioner,
+		clientset,
+		libClientset,
+		informerFactory.Objectbucket().V1alpha1().ObjectBucketClaims(),
+		informerFactory.Objectbucket().V1alpha1().ObjectBuckets())
+	ctrl.watchSecrets(kubeInformerFactory.Core().V1().Secrets())
+	ctrl.watchConfigMaps(kubeInformerFactory.Core().V1().ConfigMaps())
+	ctrl.watchStorageClasses(classInformerFactory.Storage().V1().StorageClasses())
 
-		claimController: NewController(
-			provisionerName,
-			provisioner,
-			clientset,
-			libClientset,
-			informerFactory.Objectbucket().V1alpha1().ObjectBucketClaims(),
-			informerFactory.Objectbucket().V1alpha1().ObjectBuckets()),
+	p := &Provisioner{
+		Name:                 provisionerName,
+		informerFactory:      informerFactory,
+		kubeInformerFactory:  kubeInformerFactory,
+		classInformerFactory: classInformerFactory,
+		claimController:      ctrl,
 	}
 
 	return p, nil
@@ -112,12 +132,448 @@ func (p *Provisioner) SetLabels(labels map[string]string) []string {
 	return nil
 }
 
+// SetLabelSelector allows provisioner authors to restrict the reconciler's list operations (eg. orphan detection) to
+// OBs and OBCs matching the given selector.  Defaults to the provisioner's own label so that multiple provisioners can
+// coexist in a cluster.
+func (p *Provisioner) SetLabelSelector(selector string) error {
+	s, err := labels.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid label selector %q: %v", selector, err)
+	}
+	p.claimController.SetLabelSelector(s)
+	return nil
+}
+
+// SetObjectBucketNaming allows provisioner authors to select how the names of generated ObjectBuckets are derived.
+// Defaults to ObjectBucketNameFromClaim.
+func (p *Provisioner) SetObjectBucketNaming(naming ObjectBucketNaming) {
+	p.claimController.SetObjectBucketNaming(naming)
+}
+
+// SetProvisioningPaused pauses or resumes provisioning of all claims, eg. during maintenance of the object store.
+// Paused claims are re-checked periodically. Deletes proceed while provisioning is paused.
+func (p *Provisioner) SetProvisioningPaused(paused bool) {
+	p.claimController.SetProvisioningPaused(paused)
+}
+
+// Pause stops all calls to the provisioner, deletes included, eg. during maintenance of the object store. Claims
+// keep being queued and are requeued with backoff, nothing is lost. While paused, Ready returns an error and the
+// objectbucket_reconciles_paused gauge is 1.
+func (p *Provisioner) Pause() {
+	p.claimController.SetReconcilesPaused(true)
+}
+
+// Resume undoes Pause and enqueues all claims again, so that those changed while paused are reconciled promptly.
+func (p *Provisioner) Resume() {
+	p.claimController.SetReconcilesPaused(false)
+}
+
+// Paused returns whether reconciles are paused by Pause.
+func (p *Provisioner) Paused() bool {
+	return p.claimController.ReconcilesPaused()
+}
+
+// RegisterProvisioner registers an additional provisioner with the reconciler. Claims whose storage class names the
+// provisioner are dispatched to it, while claims of classes naming a provisioner which is not registered are ignored.
+// Generated resources are labeled with the reconciler's own name whichever provisioner handled the claim.
+func (p *Provisioner) RegisterProvisioner(name string, provisioner api.Provisioner) error {
+	if name == "" || provisioner == nil {
+		return fmt.Errorf("provisioner name and implementation are required")
+	}
+	return p.claimController.RegisterProvisioner(name, provisioner)
+}
+
+// SetAuthenticationPolicy selects what happens when the named provisioner, this Provisioner's or a registered one,
+// returns a nil or empty Authentication from Provision or Grant. Defaults to AuthenticationCreateEmpty, which creates a
+// Secret with empty values.
+func (p *Provisioner) SetAuthenticationPolicy(provisionerName string, policy AuthenticationPolicy) error {
+	switch policy {
+	case AuthenticationCreateEmpty, AuthenticationSkipSecret, AuthenticationFail:
+	default:
+		return fmt.Errorf("invalid authentication policy %d", policy)
+	}
+	p.claimController.SetAuthenticationPolicy(provisionerName, policy)
+	return nil
+}
+
+// SetArtifactReclaimPolicy selects whether the Secret and ConfigMap of a deleted claim whose bucket is retained, ie.
+// whose reclaim policy is Retain, are kept. Defaults to ArtifactReclaimDelete, deleting them with the claim. Kept
+// artifacts carry the api.RetainedFromClaimAnnotation.
+func (p *Provisioner) SetArtifactReclaimPolicy(policy ArtifactReclaimPolicy) {
+	p.claimController.SetArtifactReclaimPolicy(policy)
+}
+
+// SetStaleArtifactPolicy selects what happens when the Secret or ConfigMap of a claim being provisioned was left over by
+// a deleted claim of the same name, eg. because releasing it failed. Defaults to StaleArtifactReclaim, transferring
+// them to the new claim. Only artifacts recording the UID of their claim, in the api.ClaimUIDAnnotation, are
+// recognized as left over; any other object of the same name fails the provisioning.
+func (p *Provisioner) SetStaleArtifactPolicy(policy StaleArtifactPolicy) {
+	p.claimController.SetStaleArtifactPolicy(policy)
+}
+
+// SetDeletionSequence sets the order in which the resources of a deleted claim are deleted or released once Delete or
+// Revoke has been called, eg. DeletionStepObjectBucket, DeletionStepConfigMap, DeletionStepSecret and
+// DeletionStepServiceAccount. Each step is only taken once the previous one succeeded, and the claim's own finalizer is
+// always removed last, so that controllers watching these resources see a deterministic teardown. sequence must hold
+// each step exactly once. Defaults to DefaultDeletionSequence: the ObjectBucket, the Secret, the ConfigMap, then the
+// ServiceAccount.
+func (p *Provisioner) SetDeletionSequence(sequence ...DeletionStep) error {
+	if err := validateDeletionSequence(sequence); err != nil {
+		return err
+	}
+	p.claimController.SetDeletionSequence(append([]DeletionStep(nil), sequence...))
+	return nil
+}
+
+// SetCreationOrder selects whether the ObjectBucket of a claim is created before or after its Secret, ConfigMap and
+// ServiceAccount. Defaults to CreationOrderArtifactsFirst. With CreationOrderObjectBucketFirst the ObjectBucket, the
+// cluster-scoped record of the bucket, exists as soon as anything else was created for the claim, so that a crash
+// always leaves the bucket recorded. Either way the next sync of an interrupted claim completes whatever is missing,
+// and a failed sync deletes what it created.
+func (p *Provisioner) SetCreationOrder(order CreationOrder) error {
+	switch order {
+	case CreationOrderArtifactsFirst, CreationOrderObjectBucketFirst:
+	default:
+		return fmt.Errorf("unknown creation order %d", order)
+	}
+	p.claimController.SetCreationOrder(order)
+	return nil
+}
+
+// SetExternalFinalizers registers finalizers of other controllers, eg. one managing DNS records for buckets, which are
+// added to each claim and its ObjectBucket when the claim is provisioned. The deletion of a claim proceeds in this
+// order:
+//  1. Delete or Revoke is called.
+//  2. The ObjectBucket is deleted, and the claim requeued until all other finalizers are removed from it and it is
+//     gone.
+//  3. The claim is requeued until all other finalizers are removed from it.
+//  4. The claim's Secret and ConfigMap are released and its finalizer removed, completing its deletion.
+//
+// Claims and ObjectBuckets without such finalizers are torn down in the order set with SetDeletionSequence.
+//
+// Other controllers should act once the claim or its ObjectBucket has a deletion timestamp, when the bucket is already
+// deleted or access to it revoked, and then remove their finalizer from both. Finalizers must be domain-qualified
+// names, eg. "example.com/dns-record".
+func (p *Provisioner) SetExternalFinalizers(finalizers ...string) error {
+	for _, f := range finalizers {
+		if isLibraryFinalizer(f) || !strings.Contains(f, "/") || len(validation.IsQualifiedName(f)) > 0 {
+			return fmt.Errorf("invalid external finalizer %q: must be a domain-qualified name other than the library's", f)
+		}
+	}
+	p.claimController.SetExternalFinalizers(finalizers)
+	return nil
+}
+
+// SetEndpointTransformer sets a transformer rewriting the Endpoint returned by Provision and Grant before it is
+// published in claims' ConfigMaps and Secrets, eg. to map an internal host to an external one. It also applies to the
+// ConfigMaps of bound claims, which are updated on the next resync. nil, the default, publishes endpoints unchanged.
+func (p *Provisioner) SetEndpointTransformer(t EndpointTransformer) {
+	p.claimController.SetEndpointTransformer(t)
+}
+
+// SetBucketPortDefaulting enables or disables the defaulting of a BucketPort of 0 returned by Provision and Grant. When
+// enabled, the default, claims' ConfigMaps, Secrets and connection strings publish port 80 for http hosts and 443
+// otherwise, rather than 0. Provisioners for which a port of 0 means that no port applies disable it.
+func (p *Provisioner) SetBucketPortDefaulting(enabled bool) {
+	p.claimController.SetBucketPortDefaulting(enabled)
+}
+
+// SetStaleConnectionUpdate enables or disables the update of an OB found to exist already when a claim is provisioned,
+// eg. created by an attempt which failed later on, whose Connection differs from the one returned by Provision or
+// Grant. When disabled, the default, the existing OB is kept as is and may hold a stale endpoint, eg. after the
+// provisioner rebound the claim to another bucket or rotated its backend.
+func (p *Provisioner) SetStaleConnectionUpdate(enabled bool) {
+	p.claimController.SetStaleConnectionUpdate(enabled)
+}
+
+// SetSecretAnnotator sets an annotator adding provider-specific annotations to the Secrets generated for claims, eg.
+// for workload identity integrations. nil, the default, adds none.
+func (p *Provisioner) SetSecretAnnotator(a SecretAnnotator) {
+	p.claimController.SetSecretAnnotator(a)
+}
+
+// SetOnBound sets a hook called once each claim is Bound, eg. to notify a downstream system. The hook is best-effort and
+// called at least once per claim, see OnBoundHook. nil, the default, notifies nothing.
+func (p *Provisioner) SetOnBound(h OnBoundHook) {
+	p.claimController.SetOnBoundHook(h)
+}
+
+// SetOnDeleted sets a hook called once the teardown of each deleted claim completed. The hook is best-effort and called
+// at least once per claim, see OnDeletedHook. nil, the default, notifies nothing.
+func (p *Provisioner) SetOnDeleted(h OnDeletedHook) {
+	p.claimController.SetOnDeletedHook(h)
+}
+
+// SetAnnotationPropagation sets the prefixes of the claims' annotations copied onto their ObjectBuckets, eg. for
+// cost-allocation tooling which only watches the cluster-scoped OBs. Annotations of OBs under the prefixes mirror
+// those of their claims: changes and removals on the claim are applied to its OB on the next resync. nil, the default,
+// propagates none.
+func (p *Provisioner) SetAnnotationPropagation(prefixes []string) {
+	p.claimController.SetAnnotationPropagation(prefixes)
+}
+
+// SetClaimParameterAllowlist sets the storage class parameters claims may override with their AdditionalConfig, eg. a
+// per-claim quota. The AdditionalConfig of claims is merged over the parameters of their class, the claim winning,
+// before the provision options are parsed and Provision or Grant is called. Claims setting any other key fail, so that
+// tenants cannot set privileged parameters. nil, the default, merges nothing and leaves the AdditionalConfig to the
+// provisioner.
+func (p *Provisioner) SetClaimParameterAllowlist(keys []string) {
+	p.claimController.SetClaimParameterAllowlist(keys)
+}
+
+// SetServiceAccountBinder sets a binder returning the ServiceAccount to create for each provisioned claim, eg. annotated
+// with the cloud provider identity granted access to the bucket. The ServiceAccount is owned by the claim and released
+// with its Secret when the claim is deleted. nil, the default, creates none. The provisioner's RBAC must allow the
+// creation and update of ServiceAccounts.
+func (p *Provisioner) SetServiceAccountBinder(b ServiceAccountBinder) {
+	p.claimController.SetServiceAccountBinder(b)
+}
+
+// SetDriftCheckInterval sets how often provisioners implementing api.BucketGetter are asked whether the buckets of bound
+// claims still exist, to catch buckets deleted directly in the object store. A missing bucket is reported by the
+// claim's BucketMissing condition and a warning event, and provisioned again if the storage class sets
+// reprovisionMissingBucket. Defaults to 0, never checking bound claims.
+func (p *Provisioner) SetDriftCheckInterval(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid drift check interval %v: must not be negative", interval)
+	}
+	p.claimController.SetDriftCheckInterval(interval)
+	return nil
+}
+
+// SetDrainTimeout sets how long the bucket of a deleted claim may take to drain, for storage classes setting
+// drainBeforeDelete. The drain is requeued while the provisioner's BucketDrainer reports progress; once timeout has
+// passed since it started, the deletion fails as a timed out cleanup, see SetTimeoutPolicy. Defaults to 24h.
+func (p *Provisioner) SetDrainTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("invalid drain timeout %v: must be positive", timeout)
+	}
+	p.claimController.SetDrainTimeout(timeout)
+	return nil
+}
+
+// SetOptionsCacheSize sets the number of storage classes whose parsed parameters are cached, so that the parameters
+// of a class are only parsed again once it is updated. The least recently used class is evicted when the cache is
+// full. Claims overriding parameters of their class are always parsed. 0 disables the cache, eg. for tests. Defaults
+// to 128. Must be called before Run.
+func (p *Provisioner) SetOptionsCacheSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("invalid options cache size %d: must not be negative", size)
+	}
+	p.claimController.SetOptionsCacheSize(size)
+	return nil
+}
+
+// ImportBuckets brings existing buckets of the object store under the management of the provisioner, eg. when
+// migrating to it. For each bucket of list, an ObjectBucket bound to a claim in namespace is created with the
+// storage class sc, which must name the provisioner, and the Retain reclaim policy so that deleting the claim only
+// revokes access to the bucket. Descriptors setting CreateClaim also get the claim and its Secret and ConfigMap;
+// otherwise the ObjectBucket is adopted by a claim of its name once one is created. Buckets whose ObjectBucket exists
+// are skipped, so ImportBuckets can be run again after a partial failure. The outcome of each bucket is returned, an
+// error is only returned if sc cannot be used.
+func (p *Provisioner) ImportBuckets(list []BucketDescriptor, sc *storagev1.StorageClass, namespace string) ([]ImportResult, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("namespace of the imported claims missing")
+	}
+	return p.claimController.ImportBuckets(list, sc, namespace)
+}
+
+// SetConnectionVerifier sets a verifier which must succeed before a provisioned claim is marked Bound, eg. to check that
+// the bucket can be listed with the generated credentials. Each call is bounded by timeout, 10s if 0. Claims failing
+// verification stay Pending, with their bucket, Secret and ConfigMap in place, and are verified again periodically.
+// nil, the default, marks claims Bound as soon as they are provisioned.
+func (p *Provisioner) SetConnectionVerifier(v ConnectionVerifier, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("invalid connection verification timeout %v: must not be negative", timeout)
+	}
+	p.claimController.SetConnectionVerifier(v, timeout)
+	return nil
+}
+
+// SetReverificationInterval sets how often the connection of bound claims is verified again by the verifier set with
+// SetConnectionVerifier, to catch backends which drifted since the claim was bound. The outcome is recorded in the
+// claim's ConnectionVerified condition. Defaults to 0, verifying the connection only when the claim is bound.
+func (p *Provisioner) SetReverificationInterval(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid reverification interval %v: must not be negative", interval)
+	}
+	p.claimController.SetReverificationInterval(interval)
+	return nil
+}
+
+// SetMaxInFlightProvisions bounds the number of concurrent Provision calls to fewer than the workers set with
+// SetWorkers, for backends which degrade under many concurrent creates. A claim which would exceed the limit is
+// requeued rather than waiting for a call to complete. 0, the default, sets no limit. Must be called before Run.
+func (p *Provisioner) SetMaxInFlightProvisions(max int) error {
+	if max < 0 {
+		return fmt.Errorf("invalid maximum number of provisions in flight %d: must not be negative", max)
+	}
+	p.claimController.SetMaxInFlightProvisions(max)
+	return nil
+}
+
+// SetWorkers sets the number of claims reconciled concurrently. Claims are still reconciled one at a time each.
+// Defaults to 1. Must be called before Run.
+func (p *Provisioner) SetWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("invalid number of workers %d: must be at least 1", workers)
+	}
+	p.claimController.SetWorkers(workers)
+	return nil
+}
+
+// RegisterClaimCollector registers a collector on r exposing the state of each claim of the provisioner: an
+// objectbucket_claim_info series labeled with the claim's namespace, name, phase, storage class and bucket name, and
+// its objectbucket_claim_created_timestamp_seconds. It is opt-in because it emits series per claim: with many claims,
+// or claims which are frequently created and deleted, it can add considerably to the cardinality of the scraping
+// Prometheus.
+func (p *Provisioner) RegisterClaimCollector(r prometheus.Registerer) error {
+	return p.claimController.RegisterClaimCollector(r)
+}
+
+// SetOrphanSweepPolicy enables a sweeper deleting orphaned ObjectBuckets, whose claim no longer exists, eg. because
+// its finalizer was removed by hand. The bucket of an orphan is deleted or its access revoked as if its claim had been
+// deleted. policy sets how often the sweeper scans, how long an OB must have been orphaned before it is deleted and
+// how many OBs a scan deletes at most; fields left at 0 take their default. The sweeper is disabled by default.
+func (p *Provisioner) SetOrphanSweepPolicy(policy OrphanSweepPolicy) error {
+	if policy.Interval < 0 || policy.GracePeriod < 0 || policy.BatchSize < 0 {
+		return fmt.Errorf("invalid orphan sweep policy %+v: values must not be negative", policy)
+	}
+	p.claimController.SetOrphanSweepPolicy(policy)
+	return nil
+}
+
+// RegisterSweepCollector registers a collector on r exposing the results of the last sweep for orphaned
+// ObjectBuckets: objectbucket_orphan_sweep_found, objectbucket_orphan_sweep_deleted and
+// objectbucket_orphan_sweep_last_timestamp_seconds. They are only emitted once the sweeper, enabled with
+// SetOrphanSweepPolicy, has run.
+func (p *Provisioner) RegisterSweepCollector(r prometheus.Registerer) error {
+	return p.claimController.RegisterSweepCollector(r)
+}
+
+// RegisterCleanupCollector registers a collector on r exposing, by namespace, the number of claims which have been
+// terminating for longer than stuckAfter with finalizers still present, objectbucket_claims_stuck_terminating, and the
+// number of orphaned ObjectBuckets, objectbucket_orphaned_objectbuckets. Both are counted from the informer caches on
+// each scrape, so that operators can alert on cleanups which do not complete.
+func (p *Provisioner) RegisterCleanupCollector(r prometheus.Registerer, stuckAfter time.Duration) error {
+	if stuckAfter < 0 {
+		return fmt.Errorf("invalid stuck threshold %v: must not be negative", stuckAfter)
+	}
+	return p.claimController.RegisterCleanupCollector(r, stuckAfter)
+}
+
+// EnqueueOBC queues the claim namespace/name to be reconciled now rather than on its next event, eg. from a webhook or
+// an admin endpoint which knows the claim needs re-processing after an external change. A claim which does not exist
+// or is not managed by the provisioner is skipped when it is reconciled. Claims queued before Run are reconciled once
+// it starts.
+func (p *Provisioner) EnqueueOBC(namespace, name string) error {
+	return p.claimController.EnqueueOBC(namespace, name)
+}
+
+// BindingResult returns the names of the ObjectBucket, ConfigMap, Secret and ServiceAccount generated for the bound claim
+// namespace/name, as named by the provisioner's ArtifactNaming. It returns an error for a claim which is not bound.
+func (p *Provisioner) BindingResult(namespace, name string) (*BindingResult, error) {
+	return p.claimController.BindingResult(namespace, name)
+}
+
+// SetAuditLogger sets the AuditLogger receiving a record of every ObjectBucket, Secret, ConfigMap and claim mutation
+// made by the library, eg. api.NewJSONAuditLogger. Records are discarded by default. Must be set before Run.
+func (p *Provisioner) SetAuditLogger(l api.AuditLogger) {
+	p.claimController.SetAuditLogger(l, p.Name)
+}
+
+// SetArtifactNaming sets a prefix and suffix added to the claim's name to name its generated ConfigMap and Secret, eg.
+// "obc-" to satisfy a naming policy. Both default to empty, naming them after the claim. The naming must not be
+// changed once claims are bound: the artifacts of existing claims are not renamed.
+func (p *Provisioner) SetArtifactNaming(prefix, suffix string) error {
+	naming := ArtifactNaming{Prefix: prefix, Suffix: suffix}
+	if err := naming.Validate(); err != nil {
+		return err
+	}
+	p.claimController.SetArtifactNaming(naming)
+	return nil
+}
+
+// SetFailurePolicy sets when a claim whose provisioning keeps failing is marked Failed: after maxFailures consecutive
+// failed attempts or maxElapsed since the first of them, whichever comes first. 0 disables the corresponding limit;
+// both default to 0, leaving such claims Pending while they are retried. Errors which retrying cannot fix, eg.
+// invalid StorageClass parameters, mark the claim Failed immediately regardless.
+func (p *Provisioner) SetFailurePolicy(maxFailures int, maxElapsed time.Duration) error {
+	if maxFailures < 0 || maxElapsed < 0 {
+		return fmt.Errorf("invalid failure policy: maxFailures %d and maxElapsed %v must not be negative", maxFailures, maxElapsed)
+	}
+	p.claimController.SetFailurePolicy(FailurePolicy{MaxConsecutiveFailures: maxFailures, MaxElapsed: maxElapsed})
+	return nil
+}
+
+// SetTimeoutPolicy selects how a claim is handled when the retry budget of creating or deleting its resources is
+// spent. Defaults to TimeoutCountAsFailure, which retries the claim and counts the timeout towards the failure policy.
+// TimeoutFail marks the claim Failed at once for an operator to intervene, TimeoutRequeue retries it indefinitely.
+func (p *Provisioner) SetTimeoutPolicy(policy TimeoutPolicy) {
+	p.claimController.SetTimeoutPolicy(policy)
+}
+
+// SetErrorClassifier sets the classifier of errors returned by Provision and Grant, for provisioners which do not
+// return the TerminalErr and RetryableErr types of pkg/provisioner/api/errors. Terminal errors mark the claim Failed at
+// once, retryable errors are retried without ever marking it Failed, and unclassified errors are subject to the
+// failure policy.
+func (p *Provisioner) SetErrorClassifier(classifier ErrorClassifier) {
+	p.claimController.SetErrorClassifier(classifier)
+}
+
+// SetConnectionValidation selects how connections returned by Provision and Grant whose BucketHost scheme does not match
+// a well-known BucketPort (eg. https on port 80) are handled. Defaults to ConnectionValidationWarn, which publishes them
+// with a warning event on the claim.
+func (p *Provisioner) SetConnectionValidation(v ConnectionValidation) {
+	p.claimController.SetConnectionValidation(v)
+}
+
+// SetBucketNameSuffixLength sets the length of the random suffix appended to the generateBucketName prefix of claims,
+// between MinBucketNameSuffixLength and MaxBucketNameSuffixLength. The prefix is truncated as needed to keep bucket
+// names within the MaxLength of the claim's BucketNameProfile. Defaults to 0, which appends a UUID.
+func (p *Provisioner) SetBucketNameSuffixLength(n int) error {
+	if n != 0 && (n < MinBucketNameSuffixLength || n > MaxBucketNameSuffixLength) {
+		return fmt.Errorf("invalid bucket name suffix length %d, must be 0 or between %d and %d",
+			n, MinBucketNameSuffixLength, MaxBucketNameSuffixLength)
+	}
+	p.claimController.SetBucketNameSuffixLength(n)
+	return nil
+}
+
+// SetDefaultBucketNamePrefix sets the prefix of the generated bucket names of claims which set neither bucketName nor
+// generateBucketName, so that their buckets can be traced back to the provisioner. The defaultBucketNamePrefix
+// parameter of storage classes overrides it. The prefix is never truncated: claims whose generated names would exceed
+// the MaxLength of their BucketNameProfile fail. Without a prefix, the default, such claims fail as before.
+func (p *Provisioner) SetDefaultBucketNamePrefix(prefix string) error {
+	if prefix != "" {
+		if err := validateBucketNamePrefix(prefix); err != nil {
+			return err
+		}
+	}
+	p.claimController.SetDefaultBucketNamePrefix(prefix)
+	return nil
+}
+
+// SetBucketNameProfile sets the bucket naming rules of the provider type selected by the providerType parameter of
+// storage classes, eg. for a backend accepting longer names than the default profile. Generated and templated bucket
+// names are truncated to the profile's MaxLength, and names of dynamically provisioned buckets are validated against it
+// unless the provisioner implements api.NameValidator. Defaults to the naming rules of S3, Azure Blob Storage and GCS.
+func (p *Provisioner) SetBucketNameProfile(providerType api.ProviderType, profile BucketNameProfile) error {
+	if err := validateBucketNameProfile(profile); err != nil {
+		return err
+	}
+	p.claimController.SetBucketNameProfile(providerType, profile)
+	return nil
+}
+
 // Run starts the claim and bucket controllers.
 func (p *Provisioner) Run(stopCh <-chan struct{}) (err error) {
 	defer klog.Flush()
 	log.Info("starting provisioner", "name", p.Name)
 
 	p.informerFactory.Start(stopCh)
+	p.kubeInformerFactory.Start(stopCh)
+	p.classInformerFactory.Start(stopCh)
 
 	go func() {
 		err = p.claimController.Start(stopCh)
@@ -138,3 +594,19 @@ func setupInformerFactory(c versioned.Interface, resyncPeriod time.Duration, ns
 	}
 	return informers.NewSharedInformerFactory(c, resyncPeriod)
 }
+
+// setupKubeInformerFactory generates an informer factory for core resources, scoped to the given namespace if provided
+// or to the cluster if empty, and to the resources labeled as generated by the named provisioner.
+func setupKubeInformerFactory(c kubernetes.Interface, resyncPeriod time.Duration, ns, provisionerName string) kubeinformers.SharedInformerFactory {
+	selector := labels.SelectorFromSet(labels.Set{
+		provisionerLabelKey: labelValue(provisionerName),
+	}).String()
+	return kubeinformers.NewSharedInformerFactoryWithOptions(
+		c,
+		resyncPeriod,
+		kubeinformers.WithNamespace(ns),
+		kubeinformers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector
+		}),
+	)
+}
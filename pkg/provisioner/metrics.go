@@ -0,0 +1,152 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+var (
+	claimInfoDesc = prometheus.NewDesc(
+		"objectbucket_claim_info",
+		"Information about an ObjectBucketClaim, always 1.",
+		[]string{"namespace", "name", "phase", "storageclass", "bucket_name"},
+		nil,
+	)
+	claimCreatedDesc = prometheus.NewDesc(
+		"objectbucket_claim_created_timestamp_seconds",
+		"Creation time of an ObjectBucketClaim, in seconds since the epoch.",
+		[]string{"namespace", "name"},
+		nil,
+	)
+	reconcilesPausedDesc = prometheus.NewDesc(
+		"objectbucket_reconciles_paused",
+		"Whether all reconciles are paused, 1 if they are and 0 otherwise.",
+		nil,
+		nil,
+	)
+	stuckClaimsDesc = prometheus.NewDesc(
+		"objectbucket_claims_stuck_terminating",
+		"Number of ObjectBucketClaims deleted longer ago than the threshold whose finalizers are still present.",
+		[]string{"namespace"},
+		nil,
+	)
+	orphanedBucketsDesc = prometheus.NewDesc(
+		"objectbucket_orphaned_objectbuckets",
+		"Number of ObjectBuckets whose claim no longer exists, by namespace of the claim.",
+		[]string{"namespace"},
+		nil,
+	)
+)
+
+// claimCollector is a prometheus.Collector exposing the state of each claim in the informer cache which matches the
+// reconciler's label selector. It emits a series per claim, so the number of series grows with the number of claims.
+type claimCollector struct {
+	c *obcController
+}
+
+var _ prometheus.Collector = &claimCollector{}
+
+// Describe implements prometheus.Collector
+func (cc *claimCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- claimInfoDesc
+	ch <- claimCreatedDesc
+	ch <- reconcilesPausedDesc
+}
+
+// Collect implements prometheus.Collector
+func (cc *claimCollector) Collect(ch chan<- prometheus.Metric) {
+	paused := 0.0
+	if cc.c.ReconcilesPaused() {
+		paused = 1
+	}
+	ch <- prometheus.MustNewConstMetric(reconcilesPausedDesc, prometheus.GaugeValue, paused)
+	obcs, err := cc.c.obcLister.List(cc.c.labelSelector)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, obc := range obcs {
+		ch <- prometheus.MustNewConstMetric(claimInfoDesc, prometheus.GaugeValue, 1,
+			obc.Namespace, obc.Name, string(obc.Status.Phase), obc.Spec.StorageClassName, obc.Spec.BucketName)
+		ch <- prometheus.MustNewConstMetric(claimCreatedDesc, prometheus.GaugeValue,
+			float64(obc.CreationTimestamp.Unix()), obc.Namespace, obc.Name)
+	}
+}
+
+// register the claim collector with r.
+func (c *obcController) RegisterClaimCollector(r prometheus.Registerer) error {
+	return r.Register(&claimCollector{c: c})
+}
+
+// cleanupCollector is a prometheus.Collector exposing the objects whose cleanup appears stuck, counted from the
+// informer caches on each collection: claims terminating for longer than stuckAfter with finalizers present, and
+// orphaned OBs. Both are emitted by namespace, only for namespaces with a non-zero count.
+type cleanupCollector struct {
+	c          *obcController
+	stuckAfter time.Duration
+	// now returns the current time, overridden by tests
+	now func() time.Time
+}
+
+var _ prometheus.Collector = &cleanupCollector{}
+
+// Describe implements prometheus.Collector
+func (cc *cleanupCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stuckClaimsDesc
+	ch <- orphanedBucketsDesc
+}
+
+// Collect implements prometheus.Collector
+func (cc *cleanupCollector) Collect(ch chan<- prometheus.Metric) {
+	obcs, err := cc.c.listObjectBucketClaims()
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	now := cc.now()
+	stuck := make(map[string]int)
+	for _, obc := range obcs {
+		if obc.DeletionTimestamp != nil && len(obc.Finalizers) > 0 && now.Sub(obc.DeletionTimestamp.Time) > cc.stuckAfter {
+			stuck[obc.Namespace]++
+		}
+	}
+	for ns, n := range stuck {
+		ch <- prometheus.MustNewConstMetric(stuckClaimsDesc, prometheus.GaugeValue, float64(n), ns)
+	}
+
+	orphans, err := cc.c.orphanedObjectBuckets()
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	orphaned := make(map[string]int)
+	for _, ob := range orphans {
+		orphaned[ob.Spec.ClaimRef.Namespace]++
+	}
+	for ns, n := range orphaned {
+		ch <- prometheus.MustNewConstMetric(orphanedBucketsDesc, prometheus.GaugeValue, float64(n), ns)
+	}
+}
+
+// register the cleanup collector with r, counting claims as stuck once they have been terminating for stuckAfter.
+func (c *obcController) RegisterCleanupCollector(r prometheus.Registerer, stuckAfter time.Duration) error {
+	return r.Register(&cleanupCollector{c: c, stuckAfter: stuckAfter, now: time.Now})
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+func TestClaimCollector(t *testing.T) {
+	ownLabels := map[string]string{provisionerLabelKey: labelValue(provisionerName)}
+	c := newTestController([]*v1alpha1.ObjectBucketClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Labels: ownLabels},
+			Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: "class", BucketName: "bucket"},
+			Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "other-provisioner"}},
+	}, nil)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := c.RegisterClaimCollector(reg); err != nil {
+		t.Fatalf("RegisterClaimCollector() unexpected error: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"namespace":    testNamespace,
+		"name":         testName,
+		"phase":        string(v1alpha1.ObjectBucketClaimStatusPhaseBound),
+		"storageclass": "class",
+		"bucket_name":  "bucket",
+	}
+	found := false
+	for _, mf := range families {
+		if mf.GetName() != "objectbucket_claim_info" {
+			continue
+		}
+		found = true
+		if len(mf.GetMetric()) != 1 {
+			t.Fatalf("objectbucket_claim_info has %d series, want 1 for the provisioner's claim", len(mf.GetMetric()))
+		}
+		for _, lp := range mf.GetMetric()[0].GetLabel() {
+			if want[lp.GetName()] != lp.GetValue() {
+				t.Errorf("objectbucket_claim_info label %s = %q, want %q", lp.GetName(), lp.GetValue(), want[lp.GetName()])
+			}
+		}
+	}
+	if !found {
+		t.Errorf("objectbucket_claim_info not collected")
+	}
+}
+
+func TestCleanupCollector(t *testing.T) {
+	ownLabels := map[string]string{provisionerLabelKey: labelValue(provisionerName)}
+	now := time.Now()
+	deletedAt := func(ago time.Duration) *metav1.Time {
+		ts := metav1.NewTime(now.Add(-ago))
+		return &ts
+	}
+	c := newTestController([]*v1alpha1.ObjectBucketClaim{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "stuck", Labels: ownLabels,
+			DeletionTimestamp: deletedAt(time.Hour), Finalizers: []string{finalizer}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "recent", Labels: ownLabels,
+			DeletionTimestamp: deletedAt(time.Second), Finalizers: []string{finalizer}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Labels: ownLabels}},
+	}, []*v1alpha1.ObjectBucket{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "orphan", Labels: ownLabels},
+			Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: &corev1.ObjectReference{Namespace: "other", Name: "gone"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "bound", Labels: ownLabels},
+			Spec:       v1alpha1.ObjectBucketSpec{ClaimRef: &corev1.ObjectReference{Namespace: testNamespace, Name: testName}},
+		},
+	})
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(&cleanupCollector{c: c, stuckAfter: time.Minute, now: func() time.Time { return now }}); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather() unexpected error: %v", err)
+	}
+
+	want := map[string]struct {
+		namespace string
+		value     float64
+	}{
+		"objectbucket_claims_stuck_terminating": {testNamespace, 1},
+		"objectbucket_orphaned_objectbuckets":   {"other", 1},
+	}
+	for _, mf := range families {
+		w, ok := want[mf.GetName()]
+		if !ok {
+			continue
+		}
+		delete(want, mf.GetName())
+		if len(mf.GetMetric()) != 1 {
+			t.Fatalf("%s has %d series, want 1", mf.GetName(), len(mf.GetMetric()))
+		}
+		m := mf.GetMetric()[0]
+		if ns := m.GetLabel()[0].GetValue(); ns != w.namespace || m.GetGauge().GetValue() != w.value {
+			t.Errorf("%s{namespace=%q} = %v, want {namespace=%q} = %v", mf.GetName(), ns, m.GetGauge().GetValue(), w.namespace, w.value)
+		}
+	}
+	for name := range want {
+		t.Errorf("%s not collected", name)
+	}
+}
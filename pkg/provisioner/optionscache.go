@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"container/list"
+	"sync"
+
+	storagev1 "k8s.io/api/storage/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// defaultOptionsCacheSize is the number of storage classes whose parsed parameters are cached by default
+const defaultOptionsCacheSize = 128
+
+// optionsCache memoizes the ProvisionOptions parsed from the parameters of storage classes, keyed by class name and
+// resourceVersion, so that hot classes are not re-parsed on every reconcile. An entry is replaced as soon as a class
+// is read with a different resourceVersion, and the least recently used entry is evicted once size classes are
+// cached. The cached options are shared by concurrent reconciles and must not be modified.
+type optionsCache struct {
+	mu      sync.Mutex
+	size    int
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type optionsCacheEntry struct {
+	className       string
+	resourceVersion string
+	options         *api.ProvisionOptions
+	err             error
+}
+
+func newOptionsCache(size int) *optionsCache {
+	return &optionsCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the options parsed from the parameters of class, parsing them only if class is not cached at its
+// resourceVersion. Parse errors are cached too, an invalid class stays invalid until it is updated.
+func (oc *optionsCache) get(class *storagev1.StorageClass) (*api.ProvisionOptions, error) {
+	oc.mu.Lock()
+	if elem, ok := oc.entries[class.Name]; ok {
+		entry := elem.Value.(*optionsCacheEntry)
+		if entry.resourceVersion == class.ResourceVersion {
+			oc.lru.MoveToFront(elem)
+			oc.mu.Unlock()
+			return entry.options, entry.err
+		}
+	}
+	oc.mu.Unlock()
+
+	// parse outside the lock, a concurrent parse of the same class only stores an identical entry
+	options, err := ParseProvisionOptions(class.Parameters)
+	oc.put(&optionsCacheEntry{
+		className:       class.Name,
+		resourceVersion: class.ResourceVersion,
+		options:         options,
+		err:             err,
+	})
+	return options, err
+}
+
+func (oc *optionsCache) put(entry *optionsCacheEntry) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if elem, ok := oc.entries[entry.className]; ok {
+		elem.Value = entry
+		oc.lru.MoveToFront(elem)
+		return
+	}
+	oc.entries[entry.className] = oc.lru.PushFront(entry)
+	for oc.lru.Len() > oc.size {
+		oldest := oc.lru.Back()
+		oc.lru.Remove(oldest)
+		delete(oc.entries, oldest.Value.(*optionsCacheEntry).className)
+	}
+}
+
+// invalidate drops the cached options of the named class, if any.
+func (oc *optionsCache) invalidate(className string) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if elem, ok := oc.entries[className]; ok {
+		oc.lru.Remove(elem)
+		delete(oc.entries, className)
+	}
+}
+
+// provisionOptionsFor parses params, the parameters of class as returned by parametersFor for obc. The options of
+// classes are cached unless caching is disabled, or obc overrides parameters of the class, or class has no
+// resourceVersion to tell its revisions apart. Cached options are shared by the claims of the class and returned as a
+// copy, which the caller may modify, eg. to set the CORS rule read from CORSConfigMap.
+func (c *obcController) provisionOptionsFor(obc *v1alpha1.ObjectBucketClaim, class *storagev1.StorageClass, params map[string]string) (*api.ProvisionOptions, error) {
+	if c.optionsCache == nil || class.ResourceVersion == "" || c.overridesParameters(obc) {
+		return ParseProvisionOptions(params)
+	}
+	options, err := c.optionsCache.get(class)
+	if err != nil {
+		return nil, err
+	}
+	return options.DeepCopy(), nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestOptionsCache(t *testing.T) {
+	newClass := func(name, rv, region string) *storagev1.StorageClass {
+		return &storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{Name: name, ResourceVersion: rv},
+			Parameters: map[string]string{v1alpha1.StorageClassRegion: region},
+		}
+	}
+	oc := newOptionsCache(2)
+
+	first, err := oc.get(newClass("a", "1", "us-east-1"))
+	if err != nil {
+		t.Fatalf("get() unexpected error: %v", err)
+	}
+	if got, _ := oc.get(newClass("a", "1", "ignored")); got != first {
+		t.Errorf("get() re-parsed a class at the same resourceVersion")
+	}
+	if got, _ := oc.get(newClass("a", "2", "eu-west-1")); got == first || got.Region != "eu-west-1" {
+		t.Errorf("get() = %+v, want the options of the updated class", got)
+	}
+
+	_, _ = oc.get(newClass("b", "1", "r"))
+	_, _ = oc.get(newClass("c", "1", "r"))
+	if _, ok := oc.entries["a"]; ok || oc.lru.Len() != 2 {
+		t.Errorf("cache holds %d classes, want the least recently used evicted", oc.lru.Len())
+	}
+
+	oc.invalidate("b")
+	if _, ok := oc.entries["b"]; ok {
+		t.Errorf("invalidate() kept the class")
+	}
+
+	invalid := newClass("d", "1", "r")
+	invalid.Parameters[v1alpha1.StorageClassDrainBeforeDelete] = "maybe"
+	if _, err = oc.get(invalid); err == nil {
+		t.Errorf("get() expected error for invalid parameters")
+	}
+}
+
+func TestOptionsCacheConcurrent(t *testing.T) {
+	oc := newOptionsCache(4)
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			class := &storagev1.StorageClass{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("class-%d", i%8), ResourceVersion: "1"},
+			}
+			if _, err := oc.get(class); err != nil {
+				t.Errorf("get() unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if oc.lru.Len() != 4 || len(oc.entries) != 4 {
+		t.Errorf("cache holds %d classes, want 4", oc.lru.Len())
+	}
+}
+
+func TestProvisionOptionsForBypassesCache(t *testing.T) {
+	c := newTestController(nil, nil)
+	c.SetOptionsCacheSize(8)
+	c.claimParameterAllowlist = map[string]bool{v1alpha1.StorageClassRegion: true}
+	class := &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{Name: className, ResourceVersion: "1"},
+		Parameters: map[string]string{v1alpha1.StorageClassRegion: "us-east-1"},
+	}
+	obc := &v1alpha1.ObjectBucketClaim{
+		Spec: v1alpha1.ObjectBucketClaimSpec{AdditionalConfig: map[string]string{v1alpha1.StorageClassRegion: "eu-west-1"}},
+	}
+
+	params, err := c.parametersFor(obc, class)
+	if err != nil {
+		t.Fatalf("parametersFor() unexpected error: %v", err)
+	}
+	got, err := c.provisionOptionsFor(obc, class, params)
+	if err != nil || got.Region != "eu-west-1" {
+		t.Errorf("provisionOptionsFor() = %+v, %v, want the region of the claim", got, err)
+	}
+	if c.optionsCache.lru.Len() != 0 {
+		t.Errorf("provisionOptionsFor() cached the options of a claim overriding its class")
+	}
+
+	if got, _ = c.provisionOptionsFor(&v1alpha1.ObjectBucketClaim{}, class, class.Parameters); got.Region != "us-east-1" {
+		t.Errorf("provisionOptionsFor() = %+v, want the region of the class", got)
+	}
+	if c.optionsCache.lru.Len() != 1 {
+		t.Errorf("provisionOptionsFor() did not cache the options of the class")
+	}
+
+	c.SetOptionsCacheSize(0)
+	if c.optionsCache != nil {
+		t.Errorf("SetOptionsCacheSize(0) did not disable the cache")
+	}
+}
+
+// fakeCORSProvisioner advertises CORS rules
+type fakeCORSProvisioner struct {
+	fakeProvisioner
+}
+
+func (p *fakeCORSProvisioner) Capabilities() api.Capabilities {
+	return api.Capabilities{CORS: true}
+}
+
+func TestProvisionOptionsForCopiesCachedOptions(t *testing.T) {
+	class := &storagev1.StorageClass{
+		ObjectMeta:  metav1.ObjectMeta{Name: className, ResourceVersion: "1"},
+		Provisioner: provisionerName,
+		Parameters: map[string]string{
+			v1alpha1.StorageClassCORSConfigMapName:      "cors",
+			v1alpha1.StorageClassCORSConfigMapNamespace: testNamespace,
+		},
+	}
+	cors := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "cors"},
+		Data: map[string]string{
+			v1alpha1.StorageClassCORSAllowedOrigins: "*",
+			v1alpha1.StorageClassCORSAllowedMethods: "GET",
+		},
+	}
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName},
+		Spec:       v1alpha1.ObjectBucketClaimSpec{StorageClassName: className, BucketName: "bucket"},
+	}
+	// the claim's OB is bound to another claim, which fails the reconcile once the CORS rule is read
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc-" + testNamespace + "-" + testName},
+		Spec: v1alpha1.ObjectBucketSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: testNamespace, Name: "other", UID: "other-uid"},
+		},
+	}
+	c := newTestController(nil, nil)
+	c.provisioner = &fakeCORSProvisioner{}
+	c.SetOptionsCacheSize(8)
+	c.clientset = fake.NewSimpleClientset(cors, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace}})
+	c.libClientset = externalFake.NewSimpleClientset(obc, ob)
+
+	err := c.handleProvisionClaim(testNamespace+"/"+testName, obc, class, &statusUpdates{})
+	if err == nil || !strings.Contains(err.Error(), "already bound") {
+		t.Fatalf("handleProvisionClaim() error = %v, want the OB of the claim bound to another claim", err)
+	}
+	cached, err := c.optionsCache.get(class)
+	if err != nil {
+		t.Fatalf("get() unexpected error: %v", err)
+	}
+	if cached.CORS != nil {
+		t.Errorf("cached options CORS = %+v, want the rule read by the reconcile kept out of the cache", cached.CORS)
+	}
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+// ParseProvisionOptions parses and validates the typed provisioning options found in a storage class's parameters.
+// Parameters which are not set leave the corresponding option at its zero value.
+func ParseProvisionOptions(params map[string]string) (*api.ProvisionOptions, error) {
+	opts := &api.ProvisionOptions{}
+
+	objectLock, err := parseObjectLockOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	opts.ObjectLock = objectLock
+
+	connectionString, err := parseConnectionStringOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	opts.ConnectionString = connectionString
+
+	lifecycle, err := parseLifecycleRules(params)
+	if err != nil {
+		return nil, err
+	}
+	opts.Lifecycle = lifecycle
+
+	if tmpl, ok := params[v1alpha1.StorageClassBucketNameTemplate]; ok {
+		if params[v1alpha1.StorageClassBucket] != "" {
+			return nil, fmt.Errorf("%q cannot be combined with %q", v1alpha1.StorageClassBucketNameTemplate, v1alpha1.StorageClassBucket)
+		}
+		if _, err = parseBucketNameTemplate(tmpl); err != nil {
+			return nil, err
+		}
+		opts.BucketNameTemplate = tmpl
+	}
+	if prefix, ok := params[v1alpha1.StorageClassDefaultBucketNamePrefix]; ok {
+		if err = validateBucketNamePrefix(prefix); err != nil {
+			return nil, fmt.Errorf("invalid %q: %v", v1alpha1.StorageClassDefaultBucketNamePrefix, err)
+		}
+		opts.DefaultBucketNamePrefix = prefix
+	}
+
+	if t, ok := params[v1alpha1.StorageClassProviderType]; ok {
+		switch pt := api.ProviderType(strings.ToLower(t)); pt {
+		case api.ProviderTypeS3, api.ProviderTypeAzure, api.ProviderTypeGCS:
+			opts.ProviderType = pt
+		default:
+			return nil, fmt.Errorf("invalid %q %q, expected one of %q, %q or %q", v1alpha1.StorageClassProviderType, t,
+				api.ProviderTypeS3, api.ProviderTypeAzure, api.ProviderTypeGCS)
+		}
+	}
+
+	if inConfigMap, ok := params[v1alpha1.StorageClassAccessKeyIDInConfigMap]; ok {
+		if opts.AccessKeyIDInConfigMap, err = strconv.ParseBool(inConfigMap); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassAccessKeyIDInConfigMap, inConfigMap)
+		}
+	}
+
+	if skip, ok := params[v1alpha1.StorageClassSkipConfigMap]; ok {
+		if opts.SkipConfigMap, err = strconv.ParseBool(skip); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassSkipConfigMap, skip)
+		}
+	}
+	if virtualHosted, ok := params[v1alpha1.StorageClassVirtualHostedStyle]; ok {
+		if opts.VirtualHostedStyle, err = strconv.ParseBool(virtualHosted); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassVirtualHostedStyle, virtualHosted)
+		}
+	}
+	if reprovision, ok := params[v1alpha1.StorageClassReprovisionMissingBucket]; ok {
+		if opts.ReprovisionMissingBucket, err = strconv.ParseBool(reprovision); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassReprovisionMissingBucket, reprovision)
+		}
+	}
+	if opts.SecretKeyNaming, err = parseSecretKeyNaming(params); err != nil {
+		return nil, err
+	}
+	if key, ok := params[v1alpha1.StorageClassEnvFileKey]; ok {
+		if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %q %q: %s", v1alpha1.StorageClassEnvFileKey, key, strings.Join(errs, ", "))
+		}
+		if strings.HasPrefix(key, reservedConfigMapKeyPrefix) {
+			return nil, fmt.Errorf("invalid %q %q: the %q prefix is reserved", v1alpha1.StorageClassEnvFileKey, key,
+				reservedConfigMapKeyPrefix)
+		}
+		opts.EnvFileKey = key
+	}
+	if drain, ok := params[v1alpha1.StorageClassDrainBeforeDelete]; ok {
+		if opts.DrainBeforeDelete, err = strconv.ParseBool(drain); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassDrainBeforeDelete, drain)
+		}
+	}
+
+	opts.Region = params[v1alpha1.StorageClassRegion]
+	opts.StorageTier = params[v1alpha1.StorageClassStorageTier]
+
+	name, namespace := params[v1alpha1.StorageClassSSECustomerKeySecretName], params[v1alpha1.StorageClassSSECustomerKeySecretNamespace]
+	if name != "" || namespace != "" {
+		if name == "" || namespace == "" {
+			return nil, fmt.Errorf("%q and %q must be set together", v1alpha1.StorageClassSSECustomerKeySecretName,
+				v1alpha1.StorageClassSSECustomerKeySecretNamespace)
+		}
+		opts.SSECustomerKeySecret = &corev1.SecretReference{Name: name, Namespace: namespace}
+	}
+
+	if days, ok := params[v1alpha1.StorageClassCredentialRotationDays]; ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %q %q, expected a positive integer", v1alpha1.StorageClassCredentialRotationDays, days)
+		}
+		opts.CredentialRotationDays = n
+	}
+
+	if opts.CORS, err = parseCORSConfig(params); err != nil {
+		return nil, err
+	}
+	name, namespace = params[v1alpha1.StorageClassCORSConfigMapName], params[v1alpha1.StorageClassCORSConfigMapNamespace]
+	if name != "" || namespace != "" {
+		if name == "" || namespace == "" {
+			return nil, fmt.Errorf("%q and %q must be set together", v1alpha1.StorageClassCORSConfigMapName,
+				v1alpha1.StorageClassCORSConfigMapNamespace)
+		}
+		if opts.CORS != nil {
+			return nil, fmt.Errorf("%q cannot be combined with the CORS parameters", v1alpha1.StorageClassCORSConfigMapName)
+		}
+		opts.CORSConfigMap = &corev1.ObjectReference{Kind: "ConfigMap", Name: name, Namespace: namespace}
+	}
+
+	if shared, ok := params[v1alpha1.StorageClassSharedBucket]; ok {
+		if opts.SharedBucket, err = strconv.ParseBool(shared); err != nil {
+			return nil, fmt.Errorf("invalid %q %q, expected a boolean", v1alpha1.StorageClassSharedBucket, shared)
+		}
+		if opts.SharedBucket && params[v1alpha1.StorageClassBucket] == "" {
+			return nil, fmt.Errorf("%q requires %q to be set", v1alpha1.StorageClassSharedBucket, v1alpha1.StorageClassBucket)
+		}
+	}
+
+	return opts, nil
+}
+
+func parseObjectLockOptions(params map[string]string) (*api.ObjectLockOptions, error) {
+	mode, hasMode := params[v1alpha1.StorageClassObjectLockMode]
+	days, hasDays := params[v1alpha1.StorageClassObjectLockRetentionDays]
+	if !hasMode && !hasDays {
+		return nil, nil
+	}
+	if !hasMode {
+		return nil, fmt.Errorf("%q requires %q to be set", v1alpha1.StorageClassObjectLockRetentionDays, v1alpha1.StorageClassObjectLockMode)
+	}
+
+	opts := &api.ObjectLockOptions{
+		Mode: api.ObjectLockMode(strings.ToLower(mode)),
+	}
+	switch opts.Mode {
+	case api.ObjectLockModeGovernance, api.ObjectLockModeCompliance:
+	default:
+		return nil, fmt.Errorf("invalid %q %q, expected %q or %q", v1alpha1.StorageClassObjectLockMode, mode,
+			api.ObjectLockModeGovernance, api.ObjectLockModeCompliance)
+	}
+	if hasDays {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %q %q, expected a positive integer", v1alpha1.StorageClassObjectLockRetentionDays, days)
+		}
+		opts.RetentionDays = n
+	}
+	return opts, nil
+}
+
+func parseLifecycleRules(params map[string]string) (*api.LifecycleRules, error) {
+	rules := &api.LifecycleRules{}
+	for param, days := range map[string]*int{
+		v1alpha1.StorageClassLifecycleExpirationDays:                  &rules.ExpirationDays,
+		v1alpha1.StorageClassLifecycleNoncurrentVersionExpirationDays: &rules.NoncurrentVersionExpirationDays,
+	} {
+		v, ok := params[param]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid %q %q, expected a positive integer", param, v)
+		}
+		*days = n
+	}
+	if *rules == (api.LifecycleRules{}) {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// corsMethods are the HTTP methods which may be allowed by a CORS rule
+var corsMethods = map[string]bool{"GET": true, "PUT": true, "POST": true, "DELETE": true, "HEAD": true}
+
+// parseCORSConfig parses the CORS rule found in data, the parameters of a storage class or the ConfigMap it references.
+// It returns nil if none of the CORS keys are set.
+func parseCORSConfig(data map[string]string) (*api.CORSConfig, error) {
+	origins, hasOrigins := data[v1alpha1.StorageClassCORSAllowedOrigins]
+	methods, hasMethods := data[v1alpha1.StorageClassCORSAllowedMethods]
+	headers, hasHeaders := data[v1alpha1.StorageClassCORSAllowedHeaders]
+	if !hasOrigins && !hasMethods && !hasHeaders {
+		return nil, nil
+	}
+	cors := &api.CORSConfig{
+		AllowedOrigins: splitList(origins),
+		AllowedHeaders: splitList(headers),
+	}
+	if len(cors.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("%q is required by the CORS parameters", v1alpha1.StorageClassCORSAllowedOrigins)
+	}
+	for _, m := range splitList(methods) {
+		m = strings.ToUpper(m)
+		if !corsMethods[m] {
+			return nil, fmt.Errorf("invalid %q %q, expected GET, PUT, POST, DELETE or HEAD", v1alpha1.StorageClassCORSAllowedMethods, m)
+		}
+		cors.AllowedMethods = append(cors.AllowedMethods, m)
+	}
+	if len(cors.AllowedMethods) == 0 {
+		return nil, fmt.Errorf("%q is required by the CORS parameters", v1alpha1.StorageClassCORSAllowedMethods)
+	}
+	return cors, nil
+}
+
+// splitList splits a comma-separated list, dropping empty elements and surrounding whitespace.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+func parseConnectionStringOptions(params map[string]string) (*api.ConnectionStringOptions, error) {
+	tmpl, hasTmpl := params[v1alpha1.StorageClassConnectionStringTemplate]
+	key, hasKey := params[v1alpha1.StorageClassConnectionStringKey]
+	if !hasTmpl {
+		if hasKey {
+			return nil, fmt.Errorf("%q requires %q to be set", v1alpha1.StorageClassConnectionStringKey, v1alpha1.StorageClassConnectionStringTemplate)
+		}
+		return nil, nil
+	}
+	if !hasKey {
+		key = bucketConnectionString
+	}
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid %q %q: %v", v1alpha1.StorageClassConnectionStringKey, key, errs)
+	}
+	// parse now so that syntax errors are reported before anything is provisioned
+	if _, err := parseConnectionStringTemplate(tmpl); err != nil {
+		return nil, err
+	}
+	return &api.ConnectionStringOptions{Key: key, Template: tmpl}, nil
+}
+
+// provisionerCapabilities returns the capabilities advertised by p, if any.
+func provisionerCapabilities(p api.Provisioner) api.Capabilities {
+	if ca, ok := p.(api.CapabilityAdvertiser); ok {
+		return ca.Capabilities()
+	}
+	return api.Capabilities{}
+}
+
+// validateCapabilities returns an error if opts request a feature which the provisioner does not advertise.
+// parseSecretKeyNaming returns the KeyNamingProfile selected by the secretKeyProfile or secretKeyPrefix parameter, the
+// zero value if neither is set.
+func parseSecretKeyNaming(params map[string]string) (v1alpha1.KeyNamingProfile, error) {
+	profile, hasProfile := params[v1alpha1.StorageClassSecretKeyProfile]
+	prefix, hasPrefix := params[v1alpha1.StorageClassSecretKeyPrefix]
+	if hasProfile && hasPrefix {
+		return v1alpha1.KeyNamingProfile{}, fmt.Errorf("only one of %q and %q may be set",
+			v1alpha1.StorageClassSecretKeyProfile, v1alpha1.StorageClassSecretKeyPrefix)
+	}
+	if hasPrefix {
+		naming := v1alpha1.KeyNamingWithPrefix(prefix)
+		if errs := validation.IsConfigMapKey(naming.SecretAccessKey); len(errs) > 0 {
+			return v1alpha1.KeyNamingProfile{}, fmt.Errorf("invalid %q %q: %s", v1alpha1.StorageClassSecretKeyPrefix, prefix,
+				strings.Join(errs, ", "))
+		}
+		return naming, nil
+	}
+	switch strings.ToLower(profile) {
+	case "":
+		return v1alpha1.KeyNamingProfile{}, nil
+	case "aws":
+		return v1alpha1.KeyNamingAWS, nil
+	case "minio":
+		return v1alpha1.KeyNamingMinio, nil
+	case "generic":
+		return v1alpha1.KeyNamingGeneric, nil
+	}
+	return v1alpha1.KeyNamingProfile{}, fmt.Errorf("invalid %q %q, expected \"aws\", \"minio\" or \"generic\"",
+		v1alpha1.StorageClassSecretKeyProfile, profile)
+}
+
+func validateCapabilities(p api.Provisioner, opts *api.ProvisionOptions) error {
+	caps := provisionerCapabilities(p)
+	if opts.ObjectLock != nil && !caps.ObjectLock {
+		return fmt.Errorf("object-lock requested but not supported by the provisioner")
+	}
+	if opts.Lifecycle != nil && !caps.Lifecycle {
+		return fmt.Errorf("lifecycle rules requested but not supported by the provisioner")
+	}
+	if opts.SSECustomerKeySecret != nil && !caps.SSECustomerKey {
+		return fmt.Errorf("customer-provided encryption keys requested but not supported by the provisioner")
+	}
+	if opts.StorageTier != "" && !supportsStorageTier(caps, opts.StorageTier) {
+		return fmt.Errorf("storage tier %q requested but not supported by the provisioner, expected one of %q",
+			opts.StorageTier, caps.StorageTiers)
+	}
+	if (opts.CORS != nil || opts.CORSConfigMap != nil) && !caps.CORS {
+		return fmt.Errorf("CORS rules requested but not supported by the provisioner")
+	}
+	if _, ok := p.(api.CredentialRotator); opts.CredentialRotationDays != 0 && !ok {
+		return fmt.Errorf("credential rotation requested but not supported by the provisioner")
+	}
+	if _, ok := p.(api.BucketDrainer); opts.DrainBeforeDelete && !ok {
+		return fmt.Errorf("draining buckets before delete requested but not supported by the provisioner")
+	}
+	return nil
+}
+
+func supportsStorageTier(caps api.Capabilities, tier string) bool {
+	for _, t := range caps.StorageTiers {
+		if t == tier {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,462 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+func TestParseObjectLockOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    *api.ObjectLockOptions
+		wantErr bool
+	}{
+		{
+			name:   "not requested",
+			params: map[string]string{},
+			want:   nil,
+		}, {
+			name: "mode and retention",
+			params: map[string]string{
+				v1alpha1.StorageClassObjectLockMode:          "Compliance",
+				v1alpha1.StorageClassObjectLockRetentionDays: "30",
+			},
+			want: &api.ObjectLockOptions{Mode: api.ObjectLockModeCompliance, RetentionDays: 30},
+		}, {
+			name: "invalid mode",
+			params: map[string]string{
+				v1alpha1.StorageClassObjectLockMode: "forever",
+			},
+			wantErr: true,
+		}, {
+			name: "retention without mode",
+			params: map[string]string{
+				v1alpha1.StorageClassObjectLockRetentionDays: "30",
+			},
+			wantErr: true,
+		}, {
+			name: "non-positive retention",
+			params: map[string]string{
+				v1alpha1.StorageClassObjectLockMode:          "governance",
+				v1alpha1.StorageClassObjectLockRetentionDays: "0",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseObjectLockOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseObjectLockOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseObjectLockOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLifecycleRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    *api.LifecycleRules
+		wantErr bool
+	}{
+		{
+			name:   "not requested",
+			params: map[string]string{},
+			want:   nil,
+		}, {
+			name: "expiration only",
+			params: map[string]string{
+				v1alpha1.StorageClassLifecycleExpirationDays: "7",
+			},
+			want: &api.LifecycleRules{ExpirationDays: 7},
+		}, {
+			name: "expiration and noncurrent version expiration",
+			params: map[string]string{
+				v1alpha1.StorageClassLifecycleExpirationDays:                  "30",
+				v1alpha1.StorageClassLifecycleNoncurrentVersionExpirationDays: "1",
+			},
+			want: &api.LifecycleRules{ExpirationDays: 30, NoncurrentVersionExpirationDays: 1},
+		}, {
+			name: "non-positive days",
+			params: map[string]string{
+				v1alpha1.StorageClassLifecycleNoncurrentVersionExpirationDays: "0",
+			},
+			wantErr: true,
+		}, {
+			name: "not a number",
+			params: map[string]string{
+				v1alpha1.StorageClassLifecycleExpirationDays: "a week",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLifecycleRules(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLifecycleRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLifecycleRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsSharedBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{"not shared", map[string]string{v1alpha1.StorageClassBucket: "shared"}, false, false},
+		{"shared", map[string]string{v1alpha1.StorageClassBucket: "shared", v1alpha1.StorageClassSharedBucket: "true"}, true, false},
+		{"shared without bucket", map[string]string{v1alpha1.StorageClassSharedBucket: "true"}, false, true},
+		{"not a boolean", map[string]string{v1alpha1.StorageClassBucket: "shared", v1alpha1.StorageClassSharedBucket: "yes"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.SharedBucket != tt.want {
+				t.Errorf("ParseProvisionOptions().SharedBucket = %v, want %v", got.SharedBucket, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsSkipConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    bool
+		wantErr bool
+	}{
+		{"unset", map[string]string{}, false, false},
+		{"skipped", map[string]string{v1alpha1.StorageClassSkipConfigMap: "true"}, true, false},
+		{"not a boolean", map[string]string{v1alpha1.StorageClassSkipConfigMap: "yes"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.SkipConfigMap != tt.want {
+				t.Errorf("ParseProvisionOptions().SkipConfigMap = %v, want %v", got.SkipConfigMap, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsCORS(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    *api.CORSConfig
+		wantRef bool
+		wantErr bool
+	}{
+		{"unset", map[string]string{}, nil, false, false},
+		{
+			name: "inline",
+			params: map[string]string{
+				v1alpha1.StorageClassCORSAllowedOrigins: "https://a.example.com, https://b.example.com",
+				v1alpha1.StorageClassCORSAllowedMethods: "get,PUT",
+				v1alpha1.StorageClassCORSAllowedHeaders: "Authorization",
+			},
+			want: &api.CORSConfig{
+				AllowedOrigins: []string{"https://a.example.com", "https://b.example.com"},
+				AllowedMethods: []string{"GET", "PUT"},
+				AllowedHeaders: []string{"Authorization"},
+			},
+		},
+		{
+			name: "configMap",
+			params: map[string]string{
+				v1alpha1.StorageClassCORSConfigMapName:      "cors",
+				v1alpha1.StorageClassCORSConfigMapNamespace: "ns",
+			},
+			wantRef: true,
+		},
+		{
+			name:    "missing origins",
+			params:  map[string]string{v1alpha1.StorageClassCORSAllowedMethods: "GET"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid method",
+			params:  map[string]string{v1alpha1.StorageClassCORSAllowedOrigins: "*", v1alpha1.StorageClassCORSAllowedMethods: "PATCH"},
+			wantErr: true,
+		},
+		{
+			name: "inline and configMap",
+			params: map[string]string{
+				v1alpha1.StorageClassCORSAllowedOrigins:     "*",
+				v1alpha1.StorageClassCORSAllowedMethods:     "GET",
+				v1alpha1.StorageClassCORSConfigMapName:      "cors",
+				v1alpha1.StorageClassCORSConfigMapNamespace: "ns",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got.CORS, tt.want) {
+				t.Errorf("ParseProvisionOptions().CORS = %+v, want %+v", got.CORS, tt.want)
+			}
+			if (got.CORSConfigMap != nil) != tt.wantRef {
+				t.Errorf("ParseProvisionOptions().CORSConfigMap = %v, want set %v", got.CORSConfigMap, tt.wantRef)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsProviderType(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    api.ProviderType
+		wantErr bool
+	}{
+		{"not set", map[string]string{}, "", false},
+		{"azure", map[string]string{v1alpha1.StorageClassProviderType: "Azure"}, api.ProviderTypeAzure, false},
+		{"gcs", map[string]string{v1alpha1.StorageClassProviderType: "gcs"}, api.ProviderTypeGCS, false},
+		{"unknown", map[string]string{v1alpha1.StorageClassProviderType: "swift"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.ProviderType != tt.want {
+				t.Errorf("ParseProvisionOptions().ProviderType = %q, want %q", got.ProviderType, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsSecretKeyNaming(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    v1alpha1.KeyNamingProfile
+		wantErr bool
+	}{
+		{"not set", map[string]string{}, v1alpha1.KeyNamingProfile{}, false},
+		{"minio", map[string]string{v1alpha1.StorageClassSecretKeyProfile: "MinIO"}, v1alpha1.KeyNamingMinio, false},
+		{"generic", map[string]string{v1alpha1.StorageClassSecretKeyProfile: "generic"}, v1alpha1.KeyNamingGeneric, false},
+		{"prefix", map[string]string{v1alpha1.StorageClassSecretKeyPrefix: "MYAPP_"}, v1alpha1.KeyNamingWithPrefix("MYAPP_"), false},
+		{"invalid prefix", map[string]string{v1alpha1.StorageClassSecretKeyPrefix: "my app "}, v1alpha1.KeyNamingProfile{}, true},
+		{"unknown profile", map[string]string{v1alpha1.StorageClassSecretKeyProfile: "gcs"}, v1alpha1.KeyNamingProfile{}, true},
+		{"profile and prefix", map[string]string{
+			v1alpha1.StorageClassSecretKeyProfile: "aws",
+			v1alpha1.StorageClassSecretKeyPrefix:  "MYAPP_",
+		}, v1alpha1.KeyNamingProfile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.SecretKeyNaming != tt.want {
+				t.Errorf("ParseProvisionOptions().SecretKeyNaming = %v, want %v", got.SecretKeyNaming, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsBucketNameTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"not set", map[string]string{}, "", false},
+		{"template", map[string]string{v1alpha1.StorageClassBucketNameTemplate: "{{ .Namespace }}-{{ .Name }}"}, "{{ .Namespace }}-{{ .Name }}", false},
+		{"existing bucket", map[string]string{v1alpha1.StorageClassBucket: "b", v1alpha1.StorageClassBucketNameTemplate: "{{ .Name }}"}, "", true},
+		{"invalid template", map[string]string{v1alpha1.StorageClassBucketNameTemplate: "{{ .Name "}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.BucketNameTemplate != tt.want {
+				t.Errorf("ParseProvisionOptions().BucketNameTemplate = %q, want %q", got.BucketNameTemplate, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsEnvFileKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"not set", map[string]string{}, "", false},
+		{"valid", map[string]string{v1alpha1.StorageClassEnvFileKey: "bucket.env"}, "bucket.env", false},
+		{"invalid key", map[string]string{v1alpha1.StorageClassEnvFileKey: "bucket env"}, "", true},
+		{"reserved prefix", map[string]string{v1alpha1.StorageClassEnvFileKey: "BUCKET_ENV"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.EnvFileKey != tt.want {
+				t.Errorf("ParseProvisionOptions().EnvFileKey = %q, want %q", got.EnvFileKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsDefaultBucketNamePrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		wantErr bool
+	}{
+		{"valid", "team-a", false},
+		{"empty", "", true},
+		{"uppercase", "Team", true},
+		{"leading hyphen", "-team", true},
+		{"too long", strings.Repeat("x", maxNameLen), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(map[string]string{v1alpha1.StorageClassDefaultBucketNamePrefix: tt.prefix})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.DefaultBucketNamePrefix != tt.prefix {
+				t.Errorf("ParseProvisionOptions().DefaultBucketNamePrefix = %q, want %q", got.DefaultBucketNamePrefix, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestParseProvisionOptionsSSECustomerKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    *corev1.SecretReference
+		wantErr bool
+	}{
+		{"not set", map[string]string{}, nil, false},
+		{"secret", map[string]string{
+			v1alpha1.StorageClassSSECustomerKeySecretName:      "sse-key",
+			v1alpha1.StorageClassSSECustomerKeySecretNamespace: "keys",
+		}, &corev1.SecretReference{Name: "sse-key", Namespace: "keys"}, false},
+		{"missing namespace", map[string]string{v1alpha1.StorageClassSSECustomerKeySecretName: "sse-key"}, nil, true},
+		{"missing name", map[string]string{v1alpha1.StorageClassSSECustomerKeySecretNamespace: "keys"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProvisionOptions(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseProvisionOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got.SSECustomerKeySecret, tt.want) {
+				t.Errorf("ParseProvisionOptions().SSECustomerKeySecret = %v, want %v", got.SSECustomerKeySecret, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	opts := &api.ProvisionOptions{
+		ObjectLock: &api.ObjectLockOptions{Mode: api.ObjectLockModeGovernance},
+	}
+	if err := validateCapabilities(&fakeProvisioner{}, opts); err == nil {
+		t.Errorf("validateCapabilities() expected error for unadvertised object-lock")
+	}
+	if err := validateCapabilities(&fakeProvisioner{}, &api.ProvisionOptions{}); err != nil {
+		t.Errorf("validateCapabilities() unexpected error: %v", err)
+	}
+	tier := &api.ProvisionOptions{StorageTier: "cold"}
+	if err := validateCapabilities(&fakeProvisioner{}, tier); err == nil {
+		t.Errorf("validateCapabilities() expected error for an unadvertised storage tier")
+	}
+	if err := validateCapabilities(&fakeTieredProvisioner{tiers: []string{"hot", "cold"}}, tier); err != nil {
+		t.Errorf("validateCapabilities() unexpected error: %v", err)
+	}
+	if err := validateCapabilities(&fakeTieredProvisioner{tiers: []string{"hot"}}, tier); err == nil {
+		t.Errorf("validateCapabilities() expected error for an unknown storage tier")
+	}
+	rotation := &api.ProvisionOptions{CredentialRotationDays: 30}
+	if err := validateCapabilities(&fakeProvisioner{}, rotation); err == nil {
+		t.Errorf("validateCapabilities() expected error for unsupported credential rotation")
+	}
+	if err := validateCapabilities(&fakeRotator{}, rotation); err != nil {
+		t.Errorf("validateCapabilities() unexpected error: %v", err)
+	}
+	cors := &api.ProvisionOptions{CORSConfigMap: &corev1.ObjectReference{Name: "cors", Namespace: "ns"}}
+	if err := validateCapabilities(&fakeProvisioner{}, cors); err == nil {
+		t.Errorf("validateCapabilities() expected error for unadvertised CORS")
+	}
+}
+
+// fakeTieredProvisioner advertises the storage tiers it supports
+type fakeTieredProvisioner struct {
+	fakeProvisioner
+	tiers []string
+}
+
+func (p *fakeTieredProvisioner) Capabilities() api.Capabilities {
+	return api.Capabilities{StorageTiers: p.tiers}
+}
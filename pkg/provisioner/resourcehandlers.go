@@ -17,8 +17,15 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -26,18 +33,21 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
 )
 
 const (
 	// defaultRetryBaseInterval controls how long to wait for a single create API object call
 	defaultRetryBaseInterval = time.Second * 3
-	// defaultRetryTimeout defines how long in total to try to create an API object before ending the reconciliation
-	// attempt
+	// defaultRetryTimeout defines how long in total to try to create API objects before ending the reconciliation
+	// attempt. The creates of a reconcile share the budget, see newRetryBudget.
 	defaultRetryTimeout = time.Second * 30
 
 	bucketName      = "BUCKET_NAME"
@@ -45,185 +55,1175 @@ const (
 	bucketPort      = "BUCKET_PORT"
 	bucketRegion    = "BUCKET_REGION"
 	bucketSubRegion = "BUCKET_SUBREGION"
-	// finalizer is applied to all resources generated by the provisioner and to the obc
+	// bucketHosts is only written when the provisioner lists hosts equivalent to the bucket host, comma-separated
+	bucketHosts = "BUCKET_HOSTS"
+	// azure and gcs provider type keys, written instead of the region keys
+	azureStorageAccount   = "AZURE_STORAGE_ACCOUNT"
+	azureStorageContainer = "AZURE_STORAGE_CONTAINER"
+	gcsProject            = "GOOGLE_CLOUD_PROJECT"
+	gcsLocation           = "GCS_LOCATION"
+	// bucketObjectLockEnabled is only written when the bucket was requested with object-lock
+	bucketObjectLockEnabled = "BUCKET_OBJECT_LOCK_ENABLED"
+	// bucketLifecycleEnabled is only written when the bucket was requested with lifecycle rules
+	bucketLifecycleEnabled = "BUCKET_LIFECYCLE_ENABLED"
+	// bucketCORSEnabled is only written when the bucket was requested with a CORS rule
+	bucketCORSEnabled = "BUCKET_CORS_ENABLED"
+	// bucketSSECustomerKeyEnabled is only written when the bucket was requested with a customer-provided key, which
+	// itself is never written
+	bucketSSECustomerKeyEnabled = "BUCKET_SSE_C_ENABLED"
+	// bucketStorageTier is only written when the bucket was requested in a storage tier
+	bucketStorageTier = "BUCKET_STORAGE_TIER"
+	// bucketFQDN is only written when the storage class opts in to virtual-hosted-style access
+	bucketFQDN = "BUCKET_FQDN"
+	// bucketPrefix is only written for claims of a shared bucket
+	bucketPrefix = "BUCKET_PREFIX"
+	// bucketURL and its internal and external variants are only written when the provisioner reports an external
+	// address besides the in-cluster one. bucketURL is the URL of the in-cluster address.
+	bucketURL         = "BUCKET_URL"
+	bucketURLInternal = "BUCKET_URL_INTERNAL"
+	bucketURLExternal = "BUCKET_URL_EXTERNAL"
+	// bucketSTSEndpoint is only written when the provisioner reports an STS endpoint
+	bucketSTSEndpoint = "BUCKET_STS_ENDPOINT"
+	// bucketConnectionString is the default key of a templated connection string
+	bucketConnectionString = "BUCKET_CONNECTION_STRING"
+	// bucketBlobsConfigMap is only written when large values were moved out of the bucket ConfigMap. It names the
+	// ConfigMap, in the same namespace, holding those keys with their values unchanged.
+	bucketBlobsConfigMap = "BUCKET_BLOBS_CONFIGMAP"
+	// blobsConfigMapSuffix is appended to the bucket ConfigMap's name to name the blobs ConfigMap
+	blobsConfigMapSuffix = "-blobs"
+	// maxInlineConfigMapDataSize is the data size above which the largest values are moved out of the bucket
+	// ConfigMap, leaving ample headroom below the 1MiB object size limit.
+	maxInlineConfigMapDataSize = 512 * 1024
+	// reservedConfigMapKeyPrefix is reserved for keys written by the library. Provisioner-supplied
+	// config data may not use it.
+	reservedConfigMapKeyPrefix = "BUCKET_"
+	// finalizer is applied to the obc. Resources generated by earlier versions of the library carry it as well, it is
+	// replaced by the finalizer of their kind when their claim is reconciled.
 	finalizer = api.Domain + "/finalizer"
+	// objectBucketFinalizer, configMapFinalizer, secretFinalizer and serviceAccountFinalizer are applied to the
+	// resources generated for the obc, each kind having its own so that releasing one cannot affect another
+	objectBucketFinalizer   = api.Domain + "/objectbucket-finalizer"
+	configMapFinalizer      = api.Domain + "/configmap-finalizer"
+	secretFinalizer         = api.Domain + "/secret-finalizer"
+	serviceAccountFinalizer = api.Domain + "/serviceaccount-finalizer"
 	// label applied to all resources generated by the provisioner and to the obc
 	provisionerLabelKey    = "bucket-provisioner"
 	objectBucketNameFormat = "obc-%s-%s"
 )
 
-// newBucketConfigMap returns a config map from a given endpoint and ObjectBucketClaim.
+// provisionedConfigKeys are the ConfigMap keys reflecting properties the bucket was provisioned with. They are only
+// written when the ConfigMap is created and never synced from the storage class afterwards.
+var provisionedConfigKeys = []string{bucketObjectLockEnabled, bucketLifecycleEnabled, bucketCORSEnabled, bucketSSECustomerKeyEnabled, bucketStorageTier}
+
+// deleteObjectBucketBackoff bounds the retries of transient errors deleting an OB
+var deleteObjectBucketBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// newBucketConfigMap returns a config map with the given name from a given endpoint and ObjectBucketClaim.
+// Options, if not nil, are the provision options of the bucket and determine which optional keys are written,
+// and the provider type's keys written in addition to BUCKET_NAME, BUCKET_HOST and BUCKET_PORT. Auth is only read
+// for the access key ID written when opted in by the options, it may be nil.
 // A finalizer is added to reduce chances of the CM being accidentally deleted. An OwnerReference
-// is added so that the CM is automatically garbage collected when the parent OBC is deleted.
-func newBucketConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string) (*corev1.ConfigMap, error) {
+// is added so that the CM is automatically garbage collected when the parent OBC is deleted, and another
+// for the owner named by the OBC's AdditionalOwnerAnnotation, if any.
+func newBucketConfigMap(obc *v1alpha1.ObjectBucketClaim, name string, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication, options *api.ProvisionOptions, labels map[string]string) (*corev1.ConfigMap, error) {
 	if ep == nil {
 		return nil, fmt.Errorf("cannot construct configMap, got nil Endpoint")
 	}
 	if obc == nil {
 		return nil, fmt.Errorf("cannot construct configMap, got nil OBC")
 	}
+	meta, err := newArtifactObjectMeta(obc, name, configMapFinalizer, labels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct configMap: %v", err)
+	}
 
-	return &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:       obc.Name,
-			Namespace:  obc.Namespace,
-			Finalizers: []string{finalizer},
-			Labels:     labels,
-			OwnerReferences: []metav1.OwnerReference{
-				makeOwnerReference(obc),
-			},
-		},
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: meta,
 		Data: map[string]string{
-			bucketName:      ep.BucketName,
-			bucketHost:      ep.BucketHost,
-			bucketPort:      strconv.Itoa(ep.BucketPort),
-			bucketRegion:    ep.Region,
-			bucketSubRegion: ep.SubRegion,
+			bucketName: ep.BucketName,
+			bucketHost: ep.BucketHost,
+			bucketPort: strconv.Itoa(ep.BucketPort),
 		},
-	}, nil
+	}
+	if len(ep.BucketHosts) > 0 {
+		hosts, err := joinBucketHosts(ep.BucketHosts)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct configMap: %v", err)
+		}
+		configMap.Data[bucketHosts] = hosts
+	}
+	if ep.ExternalBucketHost != "" {
+		internal := composeBucketURL(ep.BucketHost, ep.BucketPort, ep.BucketName)
+		configMap.Data[bucketURL] = internal
+		configMap.Data[bucketURLInternal] = internal
+		configMap.Data[bucketURLExternal] = composeBucketURL(ep.ExternalBucketHost, ep.ExternalBucketPort, ep.BucketName)
+	}
+	var providerType api.ProviderType
+	if options != nil {
+		providerType = options.ProviderType
+	}
+	switch providerType {
+	case api.ProviderTypeAzure:
+		configMap.Data[azureStorageAccount] = ep.StorageAccount
+		configMap.Data[azureStorageContainer] = ep.BucketName
+	case api.ProviderTypeGCS:
+		configMap.Data[gcsProject] = ep.Project
+		configMap.Data[gcsLocation] = ep.Region
+	default:
+		configMap.Data[bucketRegion] = ep.Region
+		if ep.Region == "" && options != nil {
+			// many provisioners leave the region to the storage class
+			configMap.Data[bucketRegion] = options.Region
+		}
+		configMap.Data[bucketSubRegion] = ep.SubRegion
+		if ep.STSEndpoint != "" {
+			configMap.Data[bucketSTSEndpoint] = stsEndpointURL(ep)
+		}
+	}
+	if options != nil && options.ObjectLock != nil {
+		configMap.Data[bucketObjectLockEnabled] = strconv.FormatBool(true)
+	}
+	if options != nil && options.Lifecycle != nil {
+		configMap.Data[bucketLifecycleEnabled] = strconv.FormatBool(true)
+	}
+	if options != nil && (options.CORS != nil || options.CORSConfigMap != nil) {
+		configMap.Data[bucketCORSEnabled] = strconv.FormatBool(true)
+	}
+	if options != nil && options.SSECustomerKeySecret != nil {
+		configMap.Data[bucketSSECustomerKeyEnabled] = strconv.FormatBool(true)
+	}
+	if options != nil && options.StorageTier != "" {
+		configMap.Data[bucketStorageTier] = options.StorageTier
+	}
+	if ep.BucketPrefix != "" {
+		configMap.Data[bucketPrefix] = ep.BucketPrefix
+	}
+	if options != nil && options.VirtualHostedStyle {
+		fqdn, err := composeBucketFQDN(ep.BucketHost, ep.BucketName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct configMap: %v", err)
+		}
+		configMap.Data[bucketFQDN] = fqdn
+	}
+	if options != nil && options.AccessKeyIDInConfigMap && auth != nil && auth.AccessKeys != nil {
+		configMap.Data[v1alpha1.AwsKeyField] = auth.AccessKeys.AccessKeyID
+	}
+	if err := mergeAdditionalConfigData(configMap.Data, ep.AdditionalConfigData); err != nil {
+		return nil, fmt.Errorf("cannot construct configMap: %v", err)
+	}
+	if options != nil && options.ConnectionString != nil {
+		sensitive, err := connectionStringIsSensitive(options.ConnectionString)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct configMap: %v", err)
+		}
+		if !sensitive {
+			if err = addConnectionString(configMap.Data, options.ConnectionString, ep, nil); err != nil {
+				return nil, fmt.Errorf("cannot construct configMap: %v", err)
+			}
+		}
+	}
+	if options != nil && options.EnvFileKey != "" {
+		if _, ok := configMap.Data[options.EnvFileKey]; ok {
+			return nil, fmt.Errorf("cannot construct configMap: env file key %q collides with an existing key", options.EnvFileKey)
+		}
+		configMap.Data[options.EnvFileKey] = renderEnvFile(configMap.Data)
+	}
+	return configMap, nil
 }
 
-// newCredentialsSecret returns a secret with data appropriate to the supported authenticaion
+// renderEnvFile renders the BUCKET_* keys of data as an env file, one KEY=value line per key sorted by key. Values
+// which the shell would split or expand are single-quoted, so that the file can be sourced as is.
+func renderEnvFile(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if strings.HasPrefix(k, reservedConfigMapKeyPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + quoteEnvValue(data[k]) + "\n")
+	}
+	return b.String()
+}
+
+// quoteEnvValue single-quotes v unless it only holds characters which the shell takes literally.
+func quoteEnvValue(v string) string {
+	safe := true
+	for _, r := range v {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:/@%+,=", r)) {
+			safe = false
+			break
+		}
+	}
+	if safe && v != "" {
+		return v
+	}
+	return "'" + strings.Replace(v, "'", `'\''`, -1) + "'"
+}
+
+// addConnectionString renders the connection string into data, refusing to overwrite an existing key.
+func addConnectionString(data map[string]string, opts *api.ConnectionStringOptions, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication) error {
+	if _, ok := data[opts.Key]; ok {
+		return fmt.Errorf("connection string key %q collides with an existing key", opts.Key)
+	}
+	value, err := renderConnectionString(opts, ep, auth)
+	if err != nil {
+		return err
+	}
+	data[opts.Key] = value
+	return nil
+}
+
+// stsEndpointURL returns the STS endpoint of ep as a URL. An endpoint given without a scheme uses the scheme of the
+// bucket host, if it has one, and https otherwise.
+func stsEndpointURL(ep *v1alpha1.Endpoint) string {
+	if strings.Contains(ep.STSEndpoint, "://") {
+		return ep.STSEndpoint
+	}
+	scheme := "https"
+	if i := strings.Index(ep.BucketHost, "://"); i > 0 {
+		scheme = ep.BucketHost[:i]
+	}
+	return scheme + "://" + ep.STSEndpoint
+}
+
+// composeBucketURL returns the path-style URL of bucket at host and port. A host without a scheme is assumed to be
+// served over TLS, and a port of 0 or implied by the scheme is left out.
+func composeBucketURL(host string, port int, bucket string) string {
+	scheme := "https"
+	if i := strings.Index(host, "://"); i > 0 {
+		scheme, host = strings.ToLower(host[:i]), host[i+3:]
+	}
+	host = strings.TrimSuffix(host, "/")
+	if port != 0 && port != defaultBucketPort(scheme+"://"+host) {
+		host += ":" + strconv.Itoa(port)
+	}
+	return scheme + "://" + host + "/" + bucket
+}
+
+// joinBucketHosts validates hosts and joins them with commas, in order. Duplicates are rejected, they would skew the
+// load balancing of clients.
+func joinBucketHosts(hosts []string) (string, error) {
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if err := validateBucketHost(host); err != nil {
+			return "", err
+		}
+		if seen[host] {
+			return "", fmt.Errorf("bucket host %q is listed more than once", host)
+		}
+		seen[host] = true
+	}
+	return strings.Join(hosts, ","), nil
+}
+
+// validateBucketHost returns an error unless host is a hostname or IP address, optionally with an http or https
+// scheme and a port.
+func validateBucketHost(host string) error {
+	rest := host
+	if i := strings.Index(host, "://"); i > 0 {
+		if scheme := strings.ToLower(host[:i]); scheme != "http" && scheme != "https" {
+			return fmt.Errorf("bucket host %q has unsupported scheme %q", host, scheme)
+		}
+		rest = host[i+3:]
+	}
+	u, err := url.Parse("//" + strings.TrimSuffix(rest, "/"))
+	if err != nil || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.Hostname() == "" {
+		return fmt.Errorf("invalid bucket host %q", host)
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("bucket host %q has invalid port %q", host, port)
+		}
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(u.Hostname())); len(errs) > 0 {
+		return fmt.Errorf("bucket host %q is not a valid hostname: %s", host, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// composeBucketFQDN returns the DNS name of bucket for virtual-hosted-style access, <bucket>.<host>. The scheme, port
+// and path of host are left out. The name must be a valid hostname, which rules out eg. bucket names with underscores.
+func composeBucketFQDN(host, bucket string) (string, error) {
+	if i := strings.Index(host, "://"); i > 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, "/:"); i >= 0 {
+		host = host[:i]
+	}
+	fqdn := strings.ToLower(bucket + "." + host)
+	if errs := validation.IsDNS1123Subdomain(fqdn); len(errs) > 0 {
+		return "", fmt.Errorf("bucket FQDN %q is not a valid hostname: %s", fqdn, strings.Join(errs, ", "))
+	}
+	return fqdn, nil
+}
+
+// defaultBucketPort returns the port implied by the scheme of host: 80 for http and 443 otherwise, a host without a
+// scheme being assumed to be served over TLS like the STS endpoint.
+func defaultBucketPort(host string) int {
+	if i := strings.Index(host, "://"); i > 0 && strings.EqualFold(host[:i], "http") {
+		return 80
+	}
+	return 443
+}
+
+// mergeAdditionalConfigData adds the provisioner-supplied additional config data to data. This lets provisioners
+// publish backend-specific connection details (eg. a console URL) which don't fit the Endpoint fields. Keys must be
+// valid ConfigMap keys and must not use the prefix reserved for keys written by the library.
+func mergeAdditionalConfigData(data, additional map[string]string) error {
+	for k, v := range additional {
+		if strings.HasPrefix(k, reservedConfigMapKeyPrefix) {
+			return fmt.Errorf("additional config key %q uses reserved prefix %q", k, reservedConfigMapKeyPrefix)
+		}
+		if _, ok := data[k]; ok {
+			return fmt.Errorf("additional config key %q is written by the library", k)
+		}
+		if errs := validation.IsConfigMapKey(k); len(errs) > 0 {
+			return fmt.Errorf("invalid additional config key %q: %v", k, errs)
+		}
+		data[k] = v
+	}
+	return nil
+}
+
+// newCredentialsSecret returns a secret with the given name and data appropriate to the supported authenticaion
 // method. Even if the values for the Authentication keys are empty, we generate the secret.
+// If the options skip the claim's ConfigMap, the keys it would hold are written to the secret as well.
 // A finalizer is added to reduce chances of the secret being accidentally deleted.
 // An OwnerReference is added so that the secret is automatically garbage collected when the
-// parent OBC is deleted.
-func newCredentialsSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string) (*corev1.Secret, error) {
+// parent OBC is deleted, and another for the owner named by the OBC's AdditionalOwnerAnnotation, if any.
+func newCredentialsSecret(obc *v1alpha1.ObjectBucketClaim, name string, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication, options *api.ProvisionOptions, labels map[string]string) (*corev1.Secret, error) {
 	if obc == nil {
 		return nil, fmt.Errorf("ObjectBucketClaim required to generate secret")
 	}
 	if auth == nil {
 		return nil, fmt.Errorf("got nil authentication, nothing to do")
 	}
-
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:       obc.Name,
-			Namespace:  obc.Namespace,
-			Finalizers: []string{finalizer},
-			Labels:     labels,
-			OwnerReferences: []metav1.OwnerReference{
-				makeOwnerReference(obc),
-			},
-		},
+	meta, err := newArtifactObjectMeta(obc, name, secretFinalizer, labels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct secret: %v", err)
 	}
 
-	secret.StringData = auth.ToMap()
+	secret := &corev1.Secret{ObjectMeta: meta}
+
+	secret.StringData = secretKeys(obc, auth, options)
+	if options != nil && options.ConnectionString != nil {
+		sensitive, err := connectionStringIsSensitive(options.ConnectionString)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct secret: %v", err)
+		}
+		if sensitive {
+			if err = addConnectionString(secret.StringData, options.ConnectionString, ep, auth); err != nil {
+				return nil, fmt.Errorf("cannot construct secret: %v", err)
+			}
+		}
+	}
+	if options != nil && options.SkipConfigMap {
+		// without a ConfigMap the secret is the only place the claim's app finds its endpoint
+		endpoint, err := newBucketConfigMap(obc, name, ep, auth, options, labels)
+		if err != nil {
+			return nil, fmt.Errorf("cannot construct secret: %v", err)
+		}
+		for k, v := range endpoint.Data {
+			if cur, ok := secret.StringData[k]; ok && cur != v {
+				return nil, fmt.Errorf("cannot construct secret: endpoint key %q collides with a credential key", k)
+			}
+			secret.StringData[k] = v
+		}
+	}
 	return secret, nil
 }
 
-// createObjectBucket creates an OB based on the passed-in ob spec.
-// Note: a finalizer has been added to reduce chances of the ob being accidentally deleted.
-func createObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+// secretKeys returns the credential keys of the claim's Secret: named by the claim's SecretKeyMapAnnotation, if set,
+// which is keyed by the default names, and otherwise by the SecretKeyNaming of options.
+func secretKeys(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, options *api.ProvisionOptions) map[string]string {
+	if _, ok := obc.Annotations[api.SecretKeyMapAnnotation]; ok || options == nil {
+		return remapSecretKeys(obc, auth.ToMap())
+	}
+	return auth.ToMapWithNaming(options.SecretKeyNaming)
+}
+
+// remapSecretKeys renames the keys of data according to the claim's SecretKeyMapAnnotation, if set. The annotation
+// must map known credential keys to valid, distinct Secret key names. If it cannot be parsed or fails validation a
+// warning is logged and data is returned unchanged.
+func remapSecretKeys(obc *v1alpha1.ObjectBucketClaim, data map[string]string) map[string]string {
+	raw, ok := obc.Annotations[api.SecretKeyMapAnnotation]
+	if !ok {
+		return data
+	}
+	keyMap := make(map[string]string)
+	if err := json.Unmarshal([]byte(raw), &keyMap); err != nil {
+		log.Error(err, "unable to parse annotation, using default secret keys", "annotation", api.SecretKeyMapAnnotation)
+		return data
+	}
+
+	remapped := make(map[string]string, len(data))
+	for k, v := range data {
+		newKey, ok := keyMap[k]
+		if !ok {
+			newKey = k
+		}
+		if errs := validation.IsConfigMapKey(newKey); len(errs) > 0 {
+			log.Error(nil, "invalid secret key name in annotation, using default secret keys", "key", newKey, "errors", errs)
+			return data
+		}
+		if _, dup := remapped[newKey]; dup {
+			log.Error(nil, "duplicate secret key name in annotation, using default secret keys", "key", newKey)
+			return data
+		}
+		remapped[newKey] = v
+	}
+	for k := range keyMap {
+		if _, ok := data[k]; !ok {
+			log.Error(nil, "unknown secret key in annotation, using default secret keys", "key", k)
+			return data
+		}
+	}
+	return remapped
+}
+
+// createObjectBucket creates an OB based on the passed-in ob spec. An OB left by a previous attempt for the same claim
+// is returned as is, unless updateConnection is set and its Connection differs from ob's, in which case it is updated.
+// Note: a finalizer is added to reduce chances of the ob being accidentally deleted. The provisioner's finalizers, if
+// any, follow the library's. See deleteObjectBucket for the order in which they are removed.
+func createObjectBucket(ctx context.Context, ob *v1alpha1.ObjectBucket, provisionerFinalizers []string, updateConnection bool, c versioned.Interface, retryInterval time.Duration, a *auditor) (result *v1alpha1.ObjectBucket, err error) {
 	logD.Info("creating ObjectBucket", "name", ob.Name)
+	finalizers := []string{objectBucketFinalizer}
+	for _, f := range provisionerFinalizers {
+		if !isLibraryFinalizer(f) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	ob.SetFinalizers(finalizers)
 
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
-		result, err = c.ObjectbucketV1alpha1().ObjectBuckets().Create(ob)
-		if errors.IsAlreadyExists(err) {
-			err = nil
-		} else if err != nil {
+	var (
+		attempts int
+		lastErr  error
+		start    = time.Now()
+	)
+	err = wait.PollImmediateUntil(retryInterval, func() (bool, error) {
+		attempts++
+		result, lastErr = c.ObjectbucketV1alpha1().ObjectBuckets().Create(ob)
+		if errors.IsForbidden(lastErr) {
+			return false, asPermissionError(lastErr, "create", "objectbuckets", "", ob.Name)
+		}
+		if errors.IsAlreadyExists(lastErr) {
+			// a previous attempt for the same claim may have created it, an OB bound to any other claim is not ours
+			result, lastErr = c.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+			if lastErr == nil && !sameClaimRef(result.Spec.ClaimRef, ob.Spec.ClaimRef) {
+				result = nil
+				return false, fmt.Errorf("ObjectBucket %q already exists and is bound to another claim", ob.Name)
+			}
+			if lastErr == nil && updateConnection && !sameConnection(result.Spec.Connection, ob.Spec.Connection) {
+				log.Info("existing ObjectBucket has a stale connection, updating it", "name", ob.Name)
+				result.Spec.Connection = ob.Spec.Connection
+				result, lastErr = c.ObjectbucketV1alpha1().ObjectBuckets().Update(result)
+			}
+		}
+		if lastErr != nil {
 			// could be intermittent api error
-			log.Error(err, "probably not fatal, retrying")
+			log.Error(lastErr, "probably not fatal, retrying")
+			return false, nil
 		}
-		return (err == nil), err
-	})
+		return true, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		err = newRetryExhaustedError(time.Since(start).Round(time.Millisecond), attempts, lastErr)
+	}
+	a.audit(api.AuditOperationCreate, "ObjectBucket", "", ob.Name, claimOfObjectBucket(ob), err)
 	return
 }
 
-func createSecret(obc *v1alpha1.ObjectBucketClaim, auth *v1alpha1.Authentication, labels map[string]string, c kubernetes.Interface, retryInterval, retryTimeout time.Duration) (*corev1.Secret, error) {
-	secret, err := newCredentialsSecret(obc, auth, labels)
+// sameConnection returns true if both connections hold the same endpoint and additional state. The authentication is
+// not stored in the OB and is ignored.
+func sameConnection(a, b *v1alpha1.Connection) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	sameState := len(a.AdditionalState) == 0 && len(b.AdditionalState) == 0 || reflect.DeepEqual(a.AdditionalState, b.AdditionalState)
+	return sameState && reflect.DeepEqual(a.Endpoint, b.Endpoint)
+}
+
+// adoptObjectBucket binds an existing, unbound OB to the claim of the provisioned ob: its spec is replaced by ob's and
+// the library's and provisioner's finalizers are added. Labels and annotations set by the admin are kept. A conflict
+// is not retried, the OB may have been bound meanwhile and the reconcile must check again.
+func adoptObjectBucket(ctx context.Context, existing, ob *v1alpha1.ObjectBucket, provisionerFinalizers []string, c versioned.Interface, retryInterval time.Duration, a *auditor) (result *v1alpha1.ObjectBucket, err error) {
+	logD.Info("adopting ObjectBucket", "name", existing.Name)
+	adopted := existing.DeepCopy()
+	adopted.Spec = ob.Spec
+	migrateFinalizer(adopted)
+	finalizers := adopted.GetFinalizers()
+	for _, f := range append([]string{objectBucketFinalizer}, provisionerFinalizers...) {
+		if !hasFinalizer(finalizers, f) {
+			finalizers = append(finalizers, f)
+		}
+	}
+	adopted.SetFinalizers(finalizers)
+	labels := adopted.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range ob.GetLabels() {
+		labels[k] = v
+	}
+	adopted.SetLabels(labels)
+	for k, v := range ob.GetAnnotations() {
+		metav1.SetMetaDataAnnotation(&adopted.ObjectMeta, k, v)
+	}
+	// an OB imported without its claim is bound from now on
+	delete(adopted.Annotations, api.AwaitingClaimAnnotation)
+
+	var (
+		attempts int
+		lastErr  error
+		start    = time.Now()
+	)
+	err = wait.PollImmediateUntil(retryInterval, func() (bool, error) {
+		attempts++
+		result, lastErr = c.ObjectbucketV1alpha1().ObjectBuckets().Update(adopted)
+		if errors.IsForbidden(lastErr) {
+			return false, asPermissionError(lastErr, "update", "objectbuckets", "", adopted.Name)
+		}
+		if errors.IsConflict(lastErr) || errors.IsNotFound(lastErr) {
+			return false, lastErr
+		}
+		if lastErr != nil {
+			// could be intermittent api error
+			log.Error(lastErr, "probably not fatal, retrying")
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		err = newRetryExhaustedError(time.Since(start).Round(time.Millisecond), attempts, lastErr)
+	}
+	a.audit(api.AuditOperationAdopt, "ObjectBucket", "", adopted.Name, claimOfObjectBucket(adopted), err)
+	return
+}
+
+// secretDataEqual returns true if the data of secret, whether in Data or StringData, is exactly data.
+func secretDataEqual(secret *corev1.Secret, data map[string]string) bool {
+	current := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		current[k] = string(v)
+	}
+	for k, v := range secret.StringData {
+		current[k] = v
+	}
+	if len(current) != len(data) {
+		return false
+	}
+	for k, v := range data {
+		if cur, ok := current[k]; !ok || cur != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sameClaimRef returns true if both references are set and refer to the same claim.
+func sameClaimRef(a, b *corev1.ObjectReference) bool {
+	return a != nil && b != nil && a.UID == b.UID && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+func hasFinalizer(finalizers []string, f string) bool {
+	for _, existing := range finalizers {
+		if existing == f {
+			return true
+		}
+	}
+	return false
+}
+
+func createSecret(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, name string, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication, options *api.ProvisionOptions, labels map[string]string, c kubernetes.Interface, retryInterval time.Duration, a *auditor) (*corev1.Secret, error) {
+	secret, err := newCredentialsSecret(obc, name, ep, auth, options, labels)
 	if err != nil {
 		return nil, err
 	}
 	logD.Info("creating Secret", "name", secret.Namespace+"/"+secret.Name)
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (done bool, err error) {
-		secret, err = c.CoreV1().Secrets(obc.Namespace).Create(secret)
+	var (
+		attempts int
+		lastErr  error
+		start    = time.Now()
+	)
+	err = wait.PollImmediateUntil(retryInterval, func() (done bool, err error) {
+		attempts++
+		var created *corev1.Secret
+		created, err = c.CoreV1().Secrets(obc.Namespace).Create(secret)
+		if errors.IsAlreadyExists(err) {
+			// a previous attempt for the claim may have created it, its credentials are replaced by the new ones. An
+			// unchanged secret is not updated, so that pods watching it are not restarted needlessly.
+			var existing *corev1.Secret
+			if existing, err = c.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{}); err == nil {
+				switch {
+				case isAdoptable(existing):
+					// pre-created for the claim, its keys are kept
+					created, err = adoptSecret(existing, secret.StringData, obc, c)
+				case !isControlledByClaim(existing, obc):
+					return false, newNameCollisionError("secret", obc.Namespace, name, obc)
+				case !secretDataEqual(existing, secret.StringData):
+					existing.Data = nil
+					existing.StringData = secret.StringData
+					created, err = c.CoreV1().Secrets(obc.Namespace).Update(existing)
+				default:
+					created = existing
+				}
+			}
+		}
 		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				// The object already exists don't spam the logs, instead let the request be requeued
-				return true, err
+			if errors.IsForbidden(err) {
+				return false, asPermissionError(err, "create", "secrets", obc.Namespace, name)
 			}
 			// The error could be intermittent, log and try again
 			log.Error(err, "probably not fatal, retrying")
+			lastErr = err
 			return false, nil
 		}
+		secret = created
 		return true, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		err = newRetryExhaustedError(time.Since(start).Round(time.Millisecond), attempts, lastErr)
+	}
+	a.audit(api.AuditOperationCreate, "Secret", obc.Namespace, name, obc.Namespace+"/"+obc.Name, err)
+	if err != nil {
+		// the secret must not be cleaned up, it may belong to someone else
+		return nil, err
+	}
+	return secret, nil
+}
+
+// isAdoptable returns true if the secret was pre-created for the claim and marked for adoption with the
+// api.AdoptSecretAnnotation.
+func isAdoptable(secret *corev1.Secret) bool {
+	adopt, err := strconv.ParseBool(secret.Annotations[api.AdoptSecretAnnotation])
+	return err == nil && adopt
+}
+
+// adoptSecret adds the keys of data which the secret does not hold yet, and attaches the secret to the claim. Keys the
+// secret already holds are left untouched, whatever their value. A secret with nothing to change is not updated.
+func adoptSecret(secret *corev1.Secret, data map[string]string, obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface) (*corev1.Secret, error) {
+	missing := make(map[string]string)
+	for k, v := range data {
+		if _, ok := secret.Data[k]; ok {
+			continue
+		}
+		if _, ok := secret.StringData[k]; ok {
+			continue
+		}
+		missing[k] = v
+	}
+	if len(missing) == 0 && isControlledByClaim(secret, obc) && hasFinalizer(secret.Finalizers, secretFinalizer) {
+		return secret, nil
+	}
+	log.Info("adopting pre-created secret", "name", secret.Namespace+"/"+secret.Name, "addedKeys", len(missing))
+	if err := attachToClaim(secret, obc); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		if secret.StringData == nil {
+			secret.StringData = make(map[string]string, len(missing))
+		}
+		for k, v := range missing {
+			secret.StringData[k] = v
+		}
+	}
+	return c.CoreV1().Secrets(secret.Namespace).Update(secret)
+}
+
+// annotateSecret adds annotations to the secret. A secret which already carries them is not updated.
+func annotateSecret(secret *corev1.Secret, annotations map[string]string, c kubernetes.Interface) (*corev1.Secret, error) {
+	if hasAnnotations(secret, annotations) {
+		return secret, nil
+	}
+	var updated *corev1.Secret
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().Secrets(secret.Namespace).Get(secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for k, v := range annotations {
+			metav1.SetMetaDataAnnotation(&latest.ObjectMeta, k, v)
+		}
+		updated, err = c.CoreV1().Secrets(latest.Namespace).Update(latest)
+		return err
 	})
-	return secret, err
+	if err != nil {
+		return nil, asPermissionError(err, "update", "secrets", secret.Namespace, secret.Name)
+	}
+	return updated, nil
+}
+
+// hasAnnotations returns true if obj carries all of annotations.
+func hasAnnotations(obj metav1.Object, annotations map[string]string) bool {
+	for k, v := range annotations {
+		if got, ok := obj.GetAnnotations()[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
 }
 
-func createConfigMap(obc *v1alpha1.ObjectBucketClaim, ep *v1alpha1.Endpoint, labels map[string]string, c kubernetes.Interface, retryInterval, retryTimeout time.Duration) (*corev1.ConfigMap, error) {
-	configMap, err := newBucketConfigMap(obc, ep, labels)
+// createServiceAccount creates the ServiceAccount returned by the provisioner's ServiceAccountBinder for the claim, in
+// the claim's namespace and owned by it. A ServiceAccount already created for the claim has its annotations updated.
+func createServiceAccount(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, desired *corev1.ServiceAccount, labels map[string]string, c kubernetes.Interface, retryInterval time.Duration, a *auditor) (*corev1.ServiceAccount, error) {
+	meta, err := newArtifactObjectMeta(obc, desired.Name, serviceAccountFinalizer, labels)
+	if err != nil {
+		return nil, fmt.Errorf("cannot construct service account: %v", err)
+	}
+	for k, v := range desired.Labels {
+		metav1.SetMetaDataLabel(&meta, k, v)
+	}
+	meta.Annotations = desired.Annotations
+	sa := &corev1.ServiceAccount{ObjectMeta: meta}
+
+	logD.Info("creating ServiceAccount", "name", sa.Namespace+"/"+sa.Name)
+	var (
+		attempts int
+		lastErr  error
+		start    = time.Now()
+	)
+	err = wait.PollImmediateUntil(retryInterval, func() (done bool, err error) {
+		attempts++
+		var created *corev1.ServiceAccount
+		created, err = c.CoreV1().ServiceAccounts(obc.Namespace).Create(sa)
+		if errors.IsAlreadyExists(err) {
+			var existing *corev1.ServiceAccount
+			if existing, err = c.CoreV1().ServiceAccounts(obc.Namespace).Get(sa.Name, metav1.GetOptions{}); err == nil {
+				if !isControlledByClaim(existing, obc) {
+					return false, newNameCollisionError("service account", obc.Namespace, sa.Name, obc)
+				}
+				created = existing
+				if !hasAnnotations(existing, sa.Annotations) {
+					for k, v := range sa.Annotations {
+						metav1.SetMetaDataAnnotation(&existing.ObjectMeta, k, v)
+					}
+					created, err = c.CoreV1().ServiceAccounts(obc.Namespace).Update(existing)
+				}
+			}
+		}
+		if err != nil {
+			if errors.IsForbidden(err) {
+				return false, asPermissionError(err, "create", "serviceaccounts", obc.Namespace, sa.Name)
+			}
+			// The error could be intermittent, log and try again
+			log.Error(err, "probably not fatal, retrying")
+			lastErr = err
+			return false, nil
+		}
+		sa = created
+		return true, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		err = newRetryExhaustedError(time.Since(start).Round(time.Millisecond), attempts, lastErr)
+	}
+	a.audit(api.AuditOperationCreate, "ServiceAccount", obc.Namespace, desired.Name, obc.Namespace+"/"+obc.Name, err)
+	if err != nil {
+		// the service account must not be cleaned up, it may belong to someone else
+		return nil, err
+	}
+	return sa, nil
+}
+
+func createConfigMap(ctx context.Context, obc *v1alpha1.ObjectBucketClaim, name string, ep *v1alpha1.Endpoint, auth *v1alpha1.Authentication, options *api.ProvisionOptions, labels map[string]string, c kubernetes.Interface, retryInterval time.Duration, a *auditor) (*corev1.ConfigMap, error) {
+	configMap, err := newBucketConfigMap(obc, name, ep, auth, options, labels)
 	if err != nil {
 		return nil, err
 	}
+	// the blobs must exist before the bucket ConfigMap references them
+	if blobs := splitConfigMap(configMap, maxInlineConfigMapDataSize); blobs != nil {
+		if err = applyBlobsConfigMap(ctx, blobs, c, retryInterval); err != nil {
+			return nil, err
+		}
+	}
 
 	logD.Info("creating ConfigMap", "name", configMap.Namespace+"/"+configMap.Name)
-	err = wait.PollImmediate(retryInterval, retryTimeout, func() (done bool, err error) {
-		configMap, err = c.CoreV1().ConfigMaps(obc.Namespace).Create(configMap)
+	var (
+		attempts int
+		lastErr  error
+		start    = time.Now()
+	)
+	err = wait.PollImmediateUntil(retryInterval, func() (done bool, err error) {
+		attempts++
+		var created *corev1.ConfigMap
+		created, err = c.CoreV1().ConfigMaps(obc.Namespace).Create(configMap)
+		if errors.IsAlreadyExists(err) {
+			// a previous attempt for the claim may have created it, its data is replaced if it changed, independently
+			// of the secret
+			var existing *corev1.ConfigMap
+			if existing, err = c.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{}); err == nil {
+				if !isControlledByClaim(existing, obc) {
+					return false, newNameCollisionError("configMap", obc.Namespace, name, obc)
+				}
+				created = existing
+				if !reflect.DeepEqual(existing.Data, configMap.Data) {
+					existing.Data = configMap.Data
+					created, err = c.CoreV1().ConfigMaps(obc.Namespace).Update(existing)
+				}
+			}
+		}
 		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				// The object already exists don't spam the logs, instead let the request be requeued
-				return true, err
+			if errors.IsForbidden(err) {
+				return false, asPermissionError(err, "create", "configmaps", obc.Namespace, name)
 			}
 			// The error could be intermittent, log and try again
 			log.Error(err, "probably not fatal, retrying")
+			lastErr = err
 			return false, nil
 		}
+		configMap = created
 		return true, nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		err = newRetryExhaustedError(time.Since(start).Round(time.Millisecond), attempts, lastErr)
+	}
+	a.audit(api.AuditOperationCreate, "ConfigMap", obc.Namespace, name, obc.Namespace+"/"+obc.Name, err)
+	if err != nil {
+		// the configMap must not be cleaned up, it may belong to someone else
+		return nil, err
+	}
+	return configMap, nil
+}
+
+// syncConfigMapData copies the endpoint-derived keys of desired into cm. All other keys, as well as cm's labels and
+// annotations, are left intact so that user additions survive the update. Returns true if any key changed.
+func syncConfigMapData(cm, desired *corev1.ConfigMap) (changed bool) {
+	if cm.Data == nil {
+		cm.Data = make(map[string]string, len(desired.Data))
+	}
+	for k, v := range desired.Data {
+		if cur, ok := cm.Data[k]; !ok || cur != v {
+			logD.Info("configMap key drifted", "key", k, "old", cur, "new", v)
+			cm.Data[k] = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// splitConfigMap keeps the data of cm under limit bytes by moving its largest values (eg. CA bundles) to a dedicated
+// ConfigMap, which is returned. The endpoint keys are never moved, and cm records the blobs ConfigMap's name under
+// BUCKET_BLOBS_CONFIGMAP so that consumers can find the moved keys. Returns nil if cm is small enough.
+// The blobs ConfigMap shares cm's owner references, so it is garbage collected with the claim.
+func splitConfigMap(cm *corev1.ConfigMap, limit int) *corev1.ConfigMap {
+	size := configMapDataSize(cm.Data)
+	if size <= limit {
+		return nil
+	}
+
+	var movable []string
+	for k := range cm.Data {
+		switch k {
+		case bucketName, bucketHost, bucketPort, bucketRegion, bucketSubRegion,
+			azureStorageAccount, azureStorageContainer, gcsProject, gcsLocation:
+		default:
+			movable = append(movable, k)
+		}
+	}
+	// largest first, by name for ties so that the split is stable across reconciles
+	sort.Slice(movable, func(i, j int) bool {
+		li, lj := len(cm.Data[movable[i]]), len(cm.Data[movable[j]])
+		if li != lj {
+			return li > lj
+		}
+		return movable[i] < movable[j]
 	})
-	return configMap, err
+
+	blobs := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cm.Name + blobsConfigMapSuffix,
+			Namespace:       cm.Namespace,
+			Labels:          cm.Labels,
+			OwnerReferences: cm.OwnerReferences,
+		},
+		Data: make(map[string]string),
+	}
+	// account for the reference added to cm
+	size += len(bucketBlobsConfigMap) + len(blobs.Name)
+	for _, k := range movable {
+		if size <= limit {
+			break
+		}
+		blobs.Data[k] = cm.Data[k]
+		size -= len(k) + len(cm.Data[k])
+		delete(cm.Data, k)
+	}
+	cm.Data[bucketBlobsConfigMap] = blobs.Name
+	log.Info("configMap data too large, moved values to a separate configMap", "configMap", blobs.Name,
+		"keys", len(blobs.Data))
+	return blobs
+}
+
+func configMapDataSize(data map[string]string) (size int) {
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// applyBlobsConfigMap creates the blobs ConfigMap, or overwrites its data if it exists, eg. after a failed provision.
+func applyBlobsConfigMap(ctx context.Context, blobs *corev1.ConfigMap, c kubernetes.Interface, retryInterval time.Duration) error {
+	logD.Info("creating blobs ConfigMap", "name", blobs.Namespace+"/"+blobs.Name)
+	return wait.PollImmediateUntil(retryInterval, func() (bool, error) {
+		_, err := c.CoreV1().ConfigMaps(blobs.Namespace).Create(blobs)
+		if errors.IsAlreadyExists(err) {
+			var cur *corev1.ConfigMap
+			if cur, err = c.CoreV1().ConfigMaps(blobs.Namespace).Get(blobs.Name, metav1.GetOptions{}); err == nil {
+				cur.Data = blobs.Data
+				_, err = c.CoreV1().ConfigMaps(blobs.Namespace).Update(cur)
+			}
+		}
+		if errors.IsForbidden(err) {
+			return false, asPermissionError(err, "create", "configmaps", blobs.Namespace, blobs.Name)
+		}
+		if err != nil {
+			log.Error(err, "probably not fatal, retrying")
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+}
+
+// newRetryBudget returns a context bounding the total time spent retrying the creates of a single reconcile. Each
+// create polls for the part of the budget which is left rather than for the full timeout, so the reconcile is bounded
+// regardless of how many objects it creates.
+func newRetryBudget() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), defaultRetryTimeout)
+}
+
+// retryExhaustedError is returned by the create and delete helpers once their retry budget is spent. How the claim is
+// handled then is decided by the TimeoutPolicy.
+type retryExhaustedError struct {
+	error
+}
+
+// newRetryExhaustedError wraps the last error seen by a poll loop so that a spent retry budget can be told apart from
+// a single failed attempt.
+func newRetryExhaustedError(elapsed time.Duration, attempts int, lastErr error) error {
+	return &retryExhaustedError{fmt.Errorf("gave up after %v (%d attempts): %v", elapsed, attempts, lastErr)}
+}
+
+func isRetryExhausted(err error) bool {
+	_, ok := err.(*retryExhaustedError)
+	return ok
 }
 
 // Only the finalizer needs to be removed. The CM will be garbage collected since its
 // ownerReference refers to the parent OBC.
-func releaseConfigMap(cm *corev1.ConfigMap, c kubernetes.Interface) (err error) {
+func releaseConfigMap(cm *corev1.ConfigMap, c kubernetes.Interface, a *auditor) (err error) {
 	if cm == nil {
 		logD.Info("got nil configmap, skipping")
 		return nil
 	}
-	cm, err = c.CoreV1().ConfigMaps(cm.Namespace).Get(cm.Name, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-	logD.Info("removing configmap finalizer")
-	removeFinalizer(cm)
-	cm, err = c.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
-	if err != nil {
+	ns, name, claim := cm.Namespace, cm.Name, claimOf(cm)
+	defer func() { a.audit(api.AuditOperationRelease, "ConfigMap", ns, name, claim, err) }()
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().ConfigMaps(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		logD.Info("removing configmap finalizer")
+		removeFinalizer(latest)
+		_, err = c.CoreV1().ConfigMaps(ns).Update(latest)
 		return err
-	}
-
-	return nil
+	})
+	return releaseError("configmaps", ns, name, err)
 }
 
 // Only the finalizer needs to be removed. The Secret will be garbage collected since its
 // ownerReference refers to the parent OBC.
-func releaseSecret(sec *corev1.Secret, c kubernetes.Interface) (err error) {
+func releaseSecret(sec *corev1.Secret, c kubernetes.Interface, a *auditor) (err error) {
 	if sec == nil {
 		logD.Info("got nil secret, skipping")
 		return nil
 	}
-	sec, err = c.CoreV1().Secrets(sec.Namespace).Get(sec.Name, metav1.GetOptions{})
+	ns, name, claim := sec.Namespace, sec.Name, claimOf(sec)
+	defer func() { a.audit(api.AuditOperationRelease, "Secret", ns, name, claim, err) }()
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		logD.Info("removing secret finalizer")
+		removeFinalizer(latest)
+		_, err = c.CoreV1().Secrets(ns).Update(latest)
+		return err
+	})
+	return releaseError("secrets", ns, name, err)
+}
+
+// releaseError returns the outcome of releasing a claim's Secret, ConfigMap or ServiceAccount from the error of getting
+// or updating it: an object already gone is released, and a transient API error requeues the claim rather than failing
+// its deletion, so that the deletion completes once the API server recovers.
+func releaseError(resource, ns, name string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.IsNotFound(err):
+		logD.Info("already deleted, nothing to release", "resource", resource, "name", ns+"/"+name)
+		return nil
+	case isTransientError(err):
+		log.Error(err, "transient error releasing, requeuing", "resource", resource, "name", ns+"/"+name)
+		return &requeueAfterError{
+			delay:  defaultReleaseRequeueDelay,
+			reason: fmt.Sprintf("error releasing %s \"%s/%s\": %v", resource, ns, name, err),
+		}
+	}
+	return asPermissionError(err, "update", resource, ns, name)
+}
+
+// reclaimStaleArtifacts transfers the Secret and ConfigMaps named name, left over by a deleted claim of the same name
+// as obc, to obc. Missing artifacts and artifacts of obc or of no claim are left alone.
+func reclaimStaleArtifacts(obc *v1alpha1.ObjectBucketClaim, name string, c kubernetes.Interface) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := c.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) || (err == nil && !isStaleArtifact(secret, obc)) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("reclaiming secret of a deleted claim", "secret", name, "claimUID", secret.Annotations[api.ClaimUIDAnnotation])
+		if err = attachToClaim(secret, obc); err != nil {
+			return err
+		}
+		_, err = c.CoreV1().Secrets(obc.Namespace).Update(secret)
+		return err
+	})
 	if err != nil {
+		return asPermissionError(err, "update", "secrets", obc.Namespace, name)
+	}
+	blobs, err := reclaimStaleConfigMap(obc, name, c)
+	if err != nil || blobs == "" {
 		return err
 	}
-	logD.Info("removing secret finalizer")
-	removeFinalizer(sec)
-	sec, err = c.CoreV1().Secrets(sec.Namespace).Update(sec)
+	// the blobs of a stale ConfigMap are stale as well
+	_, err = reclaimStaleConfigMap(obc, blobs, c)
+	return err
+}
+
+// reclaimStaleConfigMap transfers the ConfigMap named name, left over by a deleted claim, to obc. It returns the name of
+// the blobs ConfigMap of a reclaimed ConfigMap, if any.
+func reclaimStaleConfigMap(obc *v1alpha1.ObjectBucketClaim, name string, c kubernetes.Interface) (blobs string, err error) {
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := c.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) || (err == nil && !isStaleArtifact(cm, obc)) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("reclaiming configMap of a deleted claim", "configMap", name, "claimUID", cm.Annotations[api.ClaimUIDAnnotation])
+		if err = attachToClaim(cm, obc); err != nil {
+			return err
+		}
+		blobs = cm.Data[bucketBlobsConfigMap]
+		_, err = c.CoreV1().ConfigMaps(obc.Namespace).Update(cm)
+		return err
+	})
 	if err != nil {
+		return "", asPermissionError(err, "update", "configmaps", obc.Namespace, name)
+	}
+	return blobs, nil
+}
+
+// Only the finalizer needs to be removed. The ServiceAccount will be garbage collected since its ownerReference refers
+// to the parent OBC.
+func releaseServiceAccount(namespace, name string, c kubernetes.Interface, a *auditor) (err error) {
+	if name == "" {
+		logD.Info("got no service account, skipping")
+		return nil
+	}
+	var claim string
+	defer func() { a.audit(api.AuditOperationRelease, "ServiceAccount", namespace, name, claim, err) }()
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().ServiceAccounts(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		claim = claimOf(latest)
+		logD.Info("removing service account finalizer")
+		removeFinalizer(latest)
+		_, err = c.CoreV1().ServiceAccounts(namespace).Update(latest)
 		return err
+	})
+	return releaseError("serviceaccounts", namespace, name, err)
+}
+
+// retainConfigMaps detaches the claim's ConfigMap, and its blobs ConfigMap if any, from the claim so that they survive
+// its deletion.
+func retainConfigMaps(cm *corev1.ConfigMap, obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface, a *auditor) error {
+	if cm == nil {
+		return nil
 	}
+	names := []string{cm.Name}
+	if blobs, ok := cm.Data[bucketBlobsConfigMap]; ok {
+		names = append(names, blobs)
+	}
+	for _, name := range names {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latest, err := c.CoreV1().ConfigMaps(cm.Namespace).Get(name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			detachFromClaim(latest, obc)
+			_, err = c.CoreV1().ConfigMaps(cm.Namespace).Update(latest)
+			return err
+		})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		a.audit(api.AuditOperationRetain, "ConfigMap", cm.Namespace, name, obc.Namespace+"/"+obc.Name, err)
+		if err != nil {
+			return fmt.Errorf("error retaining configMap \"%s/%s\": %v", cm.Namespace, name, err)
+		}
+	}
+	return nil
+}
 
+// retainSecret detaches the claim's Secret from the claim so that it survives its deletion.
+func retainSecret(sec *corev1.Secret, obc *v1alpha1.ObjectBucketClaim, c kubernetes.Interface, a *auditor) error {
+	if sec == nil {
+		return nil
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.CoreV1().Secrets(sec.Namespace).Get(sec.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		detachFromClaim(latest, obc)
+		_, err = c.CoreV1().Secrets(sec.Namespace).Update(latest)
+		return err
+	})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	a.audit(api.AuditOperationRetain, "Secret", sec.Namespace, sec.Name, obc.Namespace+"/"+obc.Name, err)
+	if err != nil {
+		return fmt.Errorf("error retaining secret \"%s/%s\": %v", sec.Namespace, sec.Name, err)
+	}
 	return nil
 }
 
 // Remove the finalizer allowing the OBC to finally be deleted.
-func releaseOBC(obc *v1alpha1.ObjectBucketClaim, c versioned.Interface) (err error) {
+func releaseOBC(obc *v1alpha1.ObjectBucketClaim, c versioned.Interface, a *auditor) (err error) {
 	if obc == nil {
 		logD.Info("got nil obc, skipping")
 		return nil
 	}
 	obcNsName := obc.Namespace + "/" + obc.Name
+	ns, name := obc.Namespace, obc.Name
+	defer func() { a.audit(api.AuditOperationRelease, "ObjectBucketClaim", ns, name, obcNsName, err) }()
 	obc, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
 	if err != nil {
 		return fmt.Errorf("unable to Get obc %q in order to remove finalizer: %v", obcNsName, err)
@@ -239,34 +1239,70 @@ func releaseOBC(obc *v1alpha1.ObjectBucketClaim, c versioned.Interface) (err err
 	return nil
 }
 
-// The OB does not have an ownerReference and must be explicitly deleted after its
-// finalizer is removed.
-// Uses Update() because Patch Strategies are not supported for CRDs
-// https://github.com/kubernetes/kubernetes/issues/50037
-func deleteObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface) error {
+// deleteObjectBucket removes the library's finalizer from the OB and deletes it: the OB does not have an
+// ownerReference and must be deleted explicitly. The finalizer is removed with Update() because Patch Strategies are
+// not supported for CRDs, https://github.com/kubernetes/kubernetes/issues/50037. Provisioner finalizers are left in
+// place: the deletion timestamp is the provisioner's signal to run its own cleanup and remove them. The ordering is:
+//  1. the provisioner's Delete or Revoke is called
+//  2. the library removes its finalizer and deletes the OB
+//  3. the provisioner removes its finalizers, after which the OB is gone
+//  4. the library releases the claim's ConfigMap, Secret and the claim itself
+//
+// Steps 2 and 4 are taken in the order of the controller's DeletionSequence, the claim always being released last.
+func deleteObjectBucket(ob *v1alpha1.ObjectBucket, c versioned.Interface, a *auditor) (err error) {
 	// skip if ob is nil or otherwise wasn't instantiated.
 	// note: the ob is returned by Provision and Grant, partially filled
 	if ob == nil || ob.ObjectMeta.UID == "" {
 		return nil
 	}
+	name, claim := ob.Name, claimOfObjectBucket(ob)
+	defer func() { a.audit(api.AuditOperationDelete, "ObjectBucket", "", name, claim, err) }()
 
-	logD.Info("removing ObjectBucket finalizer", "name", ob.Name)
-	removeFinalizer(ob)
-	ob, err := c.ObjectbucketV1alpha1().ObjectBuckets().Update(ob)
-	if err != nil {
+	logD.Info("removing ObjectBucket finalizer", "name", name)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		removeFinalizer(ob)
+		_, err := c.ObjectbucketV1alpha1().ObjectBuckets().Update(ob)
+		if errors.IsConflict(err) {
+			// retry against the latest version of the ob
+			latest, getErr := c.ObjectbucketV1alpha1().ObjectBuckets().Get(name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
+			}
+			ob = latest
+		}
 		return err
+	})
+	if errors.IsNotFound(err) {
+		log.Error(err, "ObjectBucket vanished before we could delete it, skipping", "name", name)
+		return nil
 	}
-
-	logD.Info("deleting ObjectBucket", "name", ob.Name)
-	err = c.ObjectbucketV1alpha1().ObjectBuckets().Delete(ob.Name, &metav1.DeleteOptions{})
 	if err != nil {
-		if errors.IsNotFound(err) {
-			log.Error(err, "ObjectBucket vanished before we could delete it, skipping", "name", ob.Name)
-			return nil
+		return fmt.Errorf("error removing finalizer from ObjectBucket %q: %v", name, err)
+	}
+
+	logD.Info("deleting ObjectBucket", "name", name)
+	var lastErr error
+	err = wait.ExponentialBackoff(deleteObjectBucketBackoff, func() (bool, error) {
+		lastErr = c.ObjectbucketV1alpha1().ObjectBuckets().Delete(name, &metav1.DeleteOptions{})
+		switch {
+		case lastErr == nil:
+			return true, nil
+		case errors.IsNotFound(lastErr):
+			log.Error(lastErr, "ObjectBucket vanished before we could delete it, skipping", "name", name)
+			return true, nil
+		case isTransientError(lastErr):
+			log.Error(lastErr, "probably not fatal, retrying")
+			return false, nil
 		}
-		return fmt.Errorf("error deleting ObjectBucket %q: %v", ob.Name, err)
+		return false, lastErr
+	})
+	if err == wait.ErrWaitTimeout {
+		return &retryExhaustedError{fmt.Errorf("error deleting ObjectBucket %q: gave up: %v", name, lastErr)}
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting ObjectBucket %q: %v", name, err)
 	}
-	logD.Info("ObjectBucket deleted", "name", ob.Name)
+	logD.Info("ObjectBucket deleted", "name", name)
 	return nil
 }
 
@@ -275,31 +1311,164 @@ func updateClaim(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, retryIn
 	logD.Info("updating", "obc", obc.Namespace+"/"+obc.Name)
 	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
 		result, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Update(obc)
-		return (err == nil), err
+		return (err == nil), asPermissionError(err, "update", "objectbucketclaims", obc.Namespace, obc.Name)
+	})
+	return
+}
+
+func updateConfigMap(c kubernetes.Interface, cm *corev1.ConfigMap, retryInterval, retryTimeout time.Duration) (result *corev1.ConfigMap, err error) {
+
+	logD.Info("updating", "configMap", cm.Namespace+"/"+cm.Name)
+	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
+		result, err = c.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
+		return (err == nil), asPermissionError(err, "update", "configmaps", cm.Namespace, cm.Name)
 	})
 	return
 }
 
-func updateObjectBucketClaimPhase(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, phase v1alpha1.ObjectBucketClaimStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+func updateSecret(c kubernetes.Interface, secret *corev1.Secret, retryInterval, retryTimeout time.Duration) (result *corev1.Secret, err error) {
+
+	logD.Info("updating", "secret", secret.Namespace+"/"+secret.Name)
+	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
+		result, err = c.CoreV1().Secrets(secret.Namespace).Update(secret)
+		return (err == nil), asPermissionError(err, "update", "secrets", secret.Namespace, secret.Name)
+	})
+	return
+}
+
+// updateObjectBucketClaimPhase sets the phase of the obc. Unless force is set, a transition which is not valid according
+// to v1alpha1.ClaimPhaseTransitions, eg. from bound back to pending, is not written and an error is returned.
+func updateObjectBucketClaimPhase(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, phase v1alpha1.ObjectBucketClaimStatusPhase, force bool, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
 	logD.Info("updating status:", "obc", obc.Namespace+"/"+obc.Name, "old status",
 		obc.Status.Phase, "new status", phase)
-	obc.Status.Phase = phase
+	var invalid error
+	result, err = updateObjectBucketClaimStatus(c, obc, func(status *v1alpha1.ObjectBucketClaimStatus) {
+		// checked against the latest status on conflict
+		invalid = nil
+		if !force && !v1alpha1.IsValidClaimTransition(status.Phase, phase) {
+			invalid = fmt.Errorf("invalid phase transition of OBC \"%s/%s\" from %q to %q", obc.Namespace, obc.Name, status.Phase, phase)
+			return
+		}
+		status.Phase = phase
+	}, retryInterval, retryTimeout)
+	if err == nil && invalid != nil {
+		return nil, invalid
+	}
+	return result, err
+}
+
+// updateObjectBucketClaimStatus applies mutate to the status of the obc and writes it. On conflict, mutate is applied
+// again to the latest version of the obc. A mutation leaving the status unchanged is not written, the obc is returned
+// as is.
+func updateObjectBucketClaimStatus(c versioned.Interface, obc *v1alpha1.ObjectBucketClaim, mutate func(*v1alpha1.ObjectBucketClaimStatus), retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucketClaim, err error) {
+	if !mutateClaimStatus(obc, mutate) {
+		return obc, nil
+	}
 
 	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
 		result, err = c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).UpdateStatus(obc)
-		return (err == nil), err
+		if errors.IsConflict(err) {
+			// retry against the latest version of the obc
+			latest, getErr := c.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return false, getErr
+			}
+			obc = latest
+			if !mutateClaimStatus(obc, mutate) {
+				result = obc
+				return true, nil
+			}
+			return false, nil
+		}
+		return (err == nil), asPermissionError(err, "update", "objectbucketclaims/status", obc.Namespace, obc.Name)
 	})
 	return
 }
 
-func updateObjectBucketPhase(c versioned.Interface, ob *v1alpha1.ObjectBucket, phase v1alpha1.ObjectBucketStatusPhase, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+// updateObjectBucketPhase sets the phase of the ob. Unless force is set, a transition which is not valid according to
+// v1alpha1.BucketPhaseTransitions is not written and an error is returned.
+func updateObjectBucketPhase(c versioned.Interface, ob *v1alpha1.ObjectBucket, phase v1alpha1.ObjectBucketStatusPhase, force bool, retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
 	logD.Info("updating status:", "ob", ob.Name, "old status", ob.Status.Phase,
 		"new status", phase)
-	ob.Status.Phase = phase
+	var invalid error
+	result, err = updateObjectBucketStatus(c, ob, func(status *v1alpha1.ObjectBucketStatus) {
+		// checked against the latest status on conflict
+		invalid = nil
+		if !force && !v1alpha1.IsValidBucketTransition(status.Phase, phase) {
+			invalid = fmt.Errorf("invalid phase transition of OB %q from %q to %q", ob.Name, status.Phase, phase)
+			return
+		}
+		status.Phase = phase
+	}, retryInterval, retryTimeout)
+	if err == nil && invalid != nil {
+		return nil, invalid
+	}
+	return result, err
+}
+
+// updateObjectBucketStatus applies mutate to the status of the ob and writes it. On conflict, mutate is applied again
+// to the latest version of the ob. A mutation leaving the status unchanged is not written, the ob is returned as is.
+func updateObjectBucketStatus(c versioned.Interface, ob *v1alpha1.ObjectBucket, mutate func(*v1alpha1.ObjectBucketStatus), retryInterval, retryTimeout time.Duration) (result *v1alpha1.ObjectBucket, err error) {
+	if !mutateBucketStatus(ob, mutate) {
+		return ob, nil
+	}
 
 	err = wait.PollImmediate(retryInterval, retryTimeout, func() (bool, error) {
 		result, err = c.ObjectbucketV1alpha1().ObjectBuckets().UpdateStatus(ob)
-		return (err == nil), err
+		if errors.IsConflict(err) {
+			// retry against the latest version of the ob
+			latest, getErr := c.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return false, getErr
+			}
+			ob = latest
+			if !mutateBucketStatus(ob, mutate) {
+				result = ob
+				return true, nil
+			}
+			return false, nil
+		}
+		return (err == nil), asPermissionError(err, "update", "objectbuckets/status", "", ob.Name)
 	})
 	return
 }
+
+// mutateClaimStatus applies mutate to the status of the obc and returns true if the status changed.
+func mutateClaimStatus(obc *v1alpha1.ObjectBucketClaim, mutate func(*v1alpha1.ObjectBucketClaimStatus)) bool {
+	before := obc.Status.DeepCopy()
+	mutate(&obc.Status)
+	return !reflect.DeepEqual(before, &obc.Status)
+}
+
+// mutateBucketStatus applies mutate to the status of the ob and returns true if the status changed.
+func mutateBucketStatus(ob *v1alpha1.ObjectBucket, mutate func(*v1alpha1.ObjectBucketStatus)) bool {
+	before := ob.Status.DeepCopy()
+	mutate(&ob.Status)
+	return !reflect.DeepEqual(before, &ob.Status)
+}
+
+// setBucketStatusEndpoint copies the bucket name and region of the ob's Endpoint into its status, so that they can be
+// queried without digging into the connection.
+func setBucketStatusEndpoint(status *v1alpha1.ObjectBucketStatus, ob *v1alpha1.ObjectBucket) {
+	if ob.Spec.Connection == nil || ob.Spec.Endpoint == nil {
+		return
+	}
+	status.BucketName = ob.Spec.Endpoint.BucketName
+	status.Region = ob.Spec.Endpoint.Region
+}
+
+// isTransientError returns true for server-side errors which are expected to clear on their own, eg. an overloaded
+// or restarting API server.
+func isTransientError(err error) bool {
+	return errors.IsInternalError(err) || errors.IsServerTimeout(err) || errors.IsTimeout(err) ||
+		errors.IsTooManyRequests(err) || errors.IsServiceUnavailable(err) || errors.IsUnexpectedServerError(err)
+}
+
+// asPermissionError converts a forbidden API error into a PermissionErr so that it is not mistaken for a transient
+// one. Other errors are returned unchanged.
+func asPermissionError(err error, verb, resource, namespace, name string) error {
+	if errors.IsForbidden(err) {
+		return pErr.NewPermissionError(verb, resource, namespace, name, err)
+	}
+	return err
+}
@@ -17,16 +17,27 @@ limitations under the License.
 package provisioner
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
-
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8sTesting "k8s.io/client-go/testing"
 
 	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+	pErr "github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api/errors"
 )
 
 func TestNewCredentialsSecret(t *testing.T) {
@@ -44,6 +55,8 @@ func TestNewCredentialsSecret(t *testing.T) {
 			finalizer,
 		},
 	}
+	secretMeta := *testObjectMeta.DeepCopy()
+	secretMeta.Finalizers = []string{secretFinalizer}
 
 	type args struct {
 		obc            *v1alpha1.ObjectBucketClaim
@@ -88,7 +101,7 @@ func TestNewCredentialsSecret(t *testing.T) {
 				},
 			},
 			want: &corev1.Secret{
-				ObjectMeta: testObjectMeta,
+				ObjectMeta: secretMeta,
 				StringData: map[string]string{
 					v1alpha1.AwsKeyField:    authKey,
 					v1alpha1.AwsSecretField: authSecret,
@@ -110,7 +123,7 @@ func TestNewCredentialsSecret(t *testing.T) {
 				},
 			},
 			want: &corev1.Secret{
-				ObjectMeta: testObjectMeta,
+				ObjectMeta: secretMeta,
 				StringData: map[string]string{
 					v1alpha1.AwsKeyField:    "",
 					v1alpha1.AwsSecretField: "",
@@ -122,7 +135,7 @@ func TestNewCredentialsSecret(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := newCredentialsSecret(tt.args.obc, tt.args.authentication)
+			got, err := newCredentialsSecret(tt.args.obc, testObjectMeta.Name, nil, tt.args.authentication, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewCredentailsSecret() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -134,6 +147,61 @@ func TestNewCredentialsSecret(t *testing.T) {
 	}
 }
 
+func TestNewCredentialsSecretSkipConfigMap(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	ep := &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketPort: 443, BucketName: "bucket", Region: "us-east-1"}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "key", SecretAccessKey: "secret"}}
+
+	got, err := newCredentialsSecret(obc, testName, ep, auth, &api.ProvisionOptions{SkipConfigMap: true, AccessKeyIDInConfigMap: true}, nil)
+	if err != nil {
+		t.Fatalf("newCredentialsSecret() error = %v", err)
+	}
+	want := map[string]string{
+		v1alpha1.AwsKeyField:    "key",
+		v1alpha1.AwsSecretField: "secret",
+		bucketName:              "bucket",
+		bucketHost:              "s3.example.com",
+		bucketPort:              "443",
+		bucketRegion:            "us-east-1",
+		bucketSubRegion:         "",
+	}
+	if !reflect.DeepEqual(got.StringData, want) {
+		t.Errorf("newCredentialsSecret().StringData = %v, want %v", got.StringData, want)
+	}
+
+	if _, err = newCredentialsSecret(obc, testName, nil, auth, &api.ProvisionOptions{SkipConfigMap: true}, nil); err == nil {
+		t.Error("newCredentialsSecret() without an endpoint succeeded, want error")
+	}
+}
+
+func TestNewCredentialsSecretKeyNaming(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "key", SecretAccessKey: "secret"}}
+	options := &api.ProvisionOptions{SecretKeyNaming: v1alpha1.KeyNamingMinio}
+
+	got, err := newCredentialsSecret(obc, testName, nil, auth, options, nil)
+	if err != nil {
+		t.Fatalf("newCredentialsSecret() error = %v", err)
+	}
+	want := map[string]string{"MINIO_ACCESS_KEY": "key", "MINIO_SECRET_KEY": "secret"}
+	if !reflect.DeepEqual(got.StringData, want) {
+		t.Errorf("newCredentialsSecret().StringData = %v, want %v", got.StringData, want)
+	}
+	if read := authenticationFromSecret(obc, got, options.SecretKeyNaming); !reflect.DeepEqual(read, auth) {
+		t.Errorf("authenticationFromSecret() = %+v, want %+v", read.AccessKeys, auth.AccessKeys)
+	}
+
+	// the claim's key map is keyed by the default names and overrides the naming of the class
+	obc.Annotations = map[string]string{api.SecretKeyMapAnnotation: `{"AWS_ACCESS_KEY_ID":"ACCESS_KEY"}`}
+	if got, err = newCredentialsSecret(obc, testName, nil, auth, options, nil); err != nil {
+		t.Fatalf("newCredentialsSecret() error = %v", err)
+	}
+	want = map[string]string{"ACCESS_KEY": "key", v1alpha1.AwsSecretField: "secret"}
+	if !reflect.DeepEqual(got.StringData, want) {
+		t.Errorf("newCredentialsSecret().StringData = %v, want %v", got.StringData, want)
+	}
+}
+
 func TestNewBucketConfigMap(t *testing.T) {
 
 	const (
@@ -149,10 +217,20 @@ func TestNewBucketConfigMap(t *testing.T) {
 		Namespace:  "test-obc-namespace",
 		Finalizers: []string{finalizer},
 	}
+	cmMeta := *objMeta.DeepCopy()
+	cmMeta.Finalizers = []string{configMapFinalizer}
+	cmMeta.OwnerReferences = []metav1.OwnerReference{
+		makeOwnerReference(&v1alpha1.ObjectBucketClaim{ObjectMeta: objMeta}),
+	}
 
 	type args struct {
-		ep  *v1alpha1.Endpoint
-		obc *v1alpha1.ObjectBucketClaim
+		ep      *v1alpha1.Endpoint
+		obc     *v1alpha1.ObjectBucketClaim
+		auth    *v1alpha1.Authentication
+		options *api.ProvisionOptions
+	}
+	auth := &v1alpha1.Authentication{
+		AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "access-key-id", SecretAccessKey: "secret"},
 	}
 	tests := []struct {
 		name    string
@@ -178,7 +256,7 @@ func TestNewBucketConfigMap(t *testing.T) {
 				},
 			},
 			want: &corev1.ConfigMap{
-				ObjectMeta: objMeta,
+				ObjectMeta: cmMeta,
 				Data: map[string]string{
 					bucketName:      name,
 					bucketHost:      host,
@@ -189,6 +267,138 @@ func TestNewBucketConfigMap(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "endpoint without region falls back to the storage class",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{Region: "class-region"},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "class-region",
+					bucketSubRegion: "",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "endpoint region takes precedence over the storage class",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{Region: "class-region"},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    region,
+					bucketSubRegion: "",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "storage tier",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{StorageTier: "cold"},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:        name,
+					bucketHost:        host,
+					bucketPort:        strconv.Itoa(port),
+					bucketRegion:      region,
+					bucketSubRegion:   "",
+					bucketStorageTier: "cold",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "azure provider type",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:     host,
+					BucketPort:     port,
+					BucketName:     name,
+					Region:         region,
+					StorageAccount: "account",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{ProviderType: api.ProviderTypeAzure},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:            name,
+					bucketHost:            host,
+					bucketPort:            strconv.Itoa(port),
+					azureStorageAccount:   "account",
+					azureStorageContainer: name,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "gcs provider type",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+					Region:     region,
+					Project:    "project",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{ProviderType: api.ProviderTypeGCS},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:  name,
+					bucketHost:  host,
+					bucketPort:  strconv.Itoa(port),
+					gcsProject:  "project",
+					gcsLocation: region,
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "endpoint with only region",
 			args: args{
@@ -207,7 +417,7 @@ func TestNewBucketConfigMap(t *testing.T) {
 				},
 			},
 			want: &corev1.ConfigMap{
-				ObjectMeta: objMeta,
+				ObjectMeta: cmMeta,
 				Data: map[string]string{
 					bucketName:      name,
 					bucketHost:      host,
@@ -236,7 +446,7 @@ func TestNewBucketConfigMap(t *testing.T) {
 				},
 			},
 			want: &corev1.ConfigMap{
-				ObjectMeta: objMeta,
+				ObjectMeta: cmMeta,
 				Data: map[string]string{
 					bucketName:      name,
 					bucketHost:      host,
@@ -247,17 +457,1162 @@ func TestNewBucketConfigMap(t *testing.T) {
 			},
 			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-
-			got, err := newBucketConfigMap(tt.args.ep, tt.args.obc)
-			if (err != nil) == !tt.wantErr {
-				t.Errorf("newBucketConfigMap() error = %v, wantErr %v", err, tt.wantErr)
-			} else if !reflect.DeepEqual(got, tt.want) {
-				gotjson, _ := json.MarshalIndent(got, "", "\t")
-				wantjson, _ := json.MarshalIndent(tt.want, "", "\t")
-				t.Errorf("newBucketConfigMap() = %v, want %v", string(gotjson), string(wantjson))
+		{
+			name: "with access key id opted in",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				auth:    auth,
+				options: &api.ProvisionOptions{AccessKeyIDInConfigMap: true},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:           name,
+					bucketHost:           host,
+					bucketPort:           strconv.Itoa(port),
+					bucketRegion:         "",
+					bucketSubRegion:      "",
+					v1alpha1.AwsKeyField: "access-key-id",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with access key id not opted in",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				auth: auth,
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with object-lock requested",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{
+					ObjectLock: &api.ObjectLockOptions{Mode: api.ObjectLockModeCompliance, RetentionDays: 1},
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:              name,
+					bucketHost:              host,
+					bucketPort:              strconv.Itoa(port),
+					bucketRegion:            "",
+					bucketSubRegion:         "",
+					bucketObjectLockEnabled: "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with lifecycle rules requested",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost: host,
+					BucketPort: port,
+					BucketName: name,
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+				options: &api.ProvisionOptions{
+					Lifecycle: &api.LifecycleRules{ExpirationDays: 7},
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:             name,
+					bucketHost:             host,
+					bucketPort:             strconv.Itoa(port),
+					bucketRegion:           "",
+					bucketSubRegion:        "",
+					bucketLifecycleEnabled: "true",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with STS endpoint",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:  host,
+					BucketPort:  port,
+					BucketName:  name,
+					STSEndpoint: "sts.example.com:8443",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:        name,
+					bucketHost:        host,
+					bucketPort:        strconv.Itoa(port),
+					bucketRegion:      "",
+					bucketSubRegion:   "",
+					bucketSTSEndpoint: "https://sts.example.com:8443",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with bucket prefix",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:   host,
+					BucketPort:   port,
+					BucketName:   name,
+					BucketPrefix: "my-claim-x7k2p",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					bucketPrefix:    "my-claim-x7k2p",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with external endpoint",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:         host,
+					BucketPort:         port,
+					BucketName:         name,
+					ExternalBucketHost: "https://s3.example.com",
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:        name,
+					bucketHost:        host,
+					bucketPort:        strconv.Itoa(port),
+					bucketRegion:      "",
+					bucketSubRegion:   "",
+					bucketURL:         "http://www.test.com:11111/bucket-name",
+					bucketURLInternal: "http://www.test.com:11111/bucket-name",
+					bucketURLExternal: "https://s3.example.com/bucket-name",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with additional config data",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:           host,
+					BucketPort:           port,
+					BucketName:           name,
+					AdditionalConfigData: map[string]string{"CONSOLE_URL": "https://console"},
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want: &corev1.ConfigMap{
+				ObjectMeta: cmMeta,
+				Data: map[string]string{
+					bucketName:      name,
+					bucketHost:      host,
+					bucketPort:      strconv.Itoa(port),
+					bucketRegion:    "",
+					bucketSubRegion: "",
+					"CONSOLE_URL":   "https://console",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "with additional config data clobbering a reserved key",
+			args: args{
+				ep: &v1alpha1.Endpoint{
+					BucketHost:           host,
+					BucketPort:           port,
+					BucketName:           name,
+					AdditionalConfigData: map[string]string{bucketHost: "elsewhere"},
+				},
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "with nil endpoint",
+			args: args{
+				obc: &v1alpha1.ObjectBucketClaim{
+					ObjectMeta: objMeta,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := newBucketConfigMap(tt.args.obc, tt.args.obc.Name, tt.args.ep, tt.args.auth, tt.args.options, nil)
+			if (err != nil) == !tt.wantErr {
+				t.Errorf("newBucketConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				gotjson, _ := json.MarshalIndent(got, "", "\t")
+				wantjson, _ := json.MarshalIndent(tt.want, "", "\t")
+				t.Errorf("newBucketConfigMap() = %v, want %v", string(gotjson), string(wantjson))
+			}
+		})
+	}
+}
+
+func TestComposeBucketURL(t *testing.T) {
+	tests := []struct {
+		host string
+		port int
+		want string
+	}{
+		{host: "https://s3.example.com", want: "https://s3.example.com/bucket"},
+		{host: "https://s3.example.com", port: 443, want: "https://s3.example.com/bucket"},
+		{host: "HTTP://rgw.storage.svc/", port: 80, want: "http://rgw.storage.svc/bucket"},
+		{host: "rgw.storage.svc", port: 8443, want: "https://rgw.storage.svc:8443/bucket"},
+		{host: "http://rgw.storage.svc", port: 443, want: "http://rgw.storage.svc:443/bucket"},
+	}
+	for _, tt := range tests {
+		if got := composeBucketURL(tt.host, tt.port, "bucket"); got != tt.want {
+			t.Errorf("composeBucketURL(%q, %d) = %q, want %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestComposeBucketFQDN(t *testing.T) {
+	tests := []struct {
+		host    string
+		bucket  string
+		want    string
+		wantErr bool
+	}{
+		{host: "s3.us-east-2.amazonaws.com", bucket: "logs", want: "logs.s3.us-east-2.amazonaws.com"},
+		{host: "https://rgw.storage.svc:8443/", bucket: "logs", want: "logs.rgw.storage.svc"},
+		{host: "HTTP://RGW.storage.svc", bucket: "logs.archive", want: "logs.archive.rgw.storage.svc"},
+		{host: "rgw.storage.svc", bucket: "logs_archive", wantErr: true},
+		{host: "", bucket: "logs", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := composeBucketFQDN(tt.host, tt.bucket)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("composeBucketFQDN(%q, %q) error = %v, wantErr %v", tt.host, tt.bucket, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("composeBucketFQDN(%q, %q) = %q, want %q", tt.host, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestSTSEndpointURL(t *testing.T) {
+	tests := []struct {
+		name string
+		ep   v1alpha1.Endpoint
+		want string
+	}{
+		{"host only", v1alpha1.Endpoint{BucketHost: "s3.example.com", STSEndpoint: "sts.example.com"}, "https://sts.example.com"},
+		{"scheme from bucket host", v1alpha1.Endpoint{BucketHost: "http://s3.example.com", STSEndpoint: "sts.example.com:80"}, "http://sts.example.com:80"},
+		{"explicit scheme", v1alpha1.Endpoint{BucketHost: "https://s3.example.com", STSEndpoint: "http://sts.example.com"}, "http://sts.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stsEndpointURL(&tt.ep); got != tt.want {
+				t.Errorf("stsEndpointURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactsShareObjectMeta(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-obc",
+			Namespace:   "test-obc-namespace",
+			UID:         "obc-uid",
+			Annotations: map[string]string{api.AdditionalOwnerAnnotation: `{"apiVersion":"v1","kind":"ConfigMap","name":"app","uid":"app-uid"}`},
+		},
+	}
+	labels := map[string]string{"app": "test"}
+
+	cm, err := newBucketConfigMap(obc, obc.Name, &v1alpha1.Endpoint{}, nil, nil, labels)
+	if err != nil {
+		t.Fatalf("newBucketConfigMap() unexpected error: %v", err)
+	}
+	secret, err := newCredentialsSecret(obc, obc.Name, nil, &v1alpha1.Authentication{}, nil, labels)
+	if err != nil {
+		t.Fatalf("newCredentialsSecret() unexpected error: %v", err)
+	}
+	if !hasFinalizer(cm.Finalizers, configMapFinalizer) || !hasFinalizer(secret.Finalizers, secretFinalizer) {
+		t.Errorf("configMap finalizers %v and secret finalizers %v, want the finalizer of each kind", cm.Finalizers, secret.Finalizers)
+	}
+	// the metadata only differs by the finalizer of each kind
+	cmMeta, secretMeta := cm.ObjectMeta.DeepCopy(), secret.ObjectMeta.DeepCopy()
+	cmMeta.Finalizers, secretMeta.Finalizers = nil, nil
+	if !reflect.DeepEqual(cmMeta, secretMeta) {
+		t.Errorf("configMap metadata %+v differs from secret metadata %+v", cm.ObjectMeta, secret.ObjectMeta)
+	}
+	if len(cm.OwnerReferences) != 2 || !isControlledByClaim(cm, obc) {
+		t.Errorf("configMap metadata %+v, want the claim as controller and the additional owner", cm.ObjectMeta)
+	}
+}
+
+func TestCreateSecretRetryExhausted(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "secrets", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("transient error")
+	})
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := createSecret(ctx, obc, obc.Name, nil, &v1alpha1.Authentication{}, nil, nil, client, time.Millisecond, nil)
+	if err == nil {
+		t.Fatalf("createSecret() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "attempts") || !strings.Contains(err.Error(), "transient error") {
+		t.Errorf("createSecret() error = %q, want retry budget and last error", err)
+	}
+}
+
+func TestCreateSecretExisting(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+			UID:       "obc-uid",
+		},
+	}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "new-id", SecretAccessKey: "new-secret"}}
+
+	// a secret of the same name which was not generated for the claim is left alone
+	foreign := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: obc.Name, Namespace: obc.Namespace},
+		StringData: map[string]string{"password": "hunter2"},
+	}
+	client := fake.NewSimpleClientset(foreign)
+	got, err := createSecret(context.Background(), obc, obc.Name, nil, auth, nil, nil, client, time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not belong") {
+		t.Fatalf("createSecret() error = %v, want a name collision", err)
+	}
+	if got != nil {
+		t.Errorf("createSecret() = %v on collision, want nil so that it is not cleaned up", got)
+	}
+	cur, _ := client.CoreV1().Secrets(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	if !reflect.DeepEqual(cur.StringData, foreign.StringData) {
+		t.Errorf("foreign secret data = %v, want it unchanged", cur.StringData)
+	}
+
+	// a secret left by a previous attempt for the claim gets the new credentials
+	stale := foreign.DeepCopy()
+	stale.OwnerReferences = []metav1.OwnerReference{makeOwnerReference(obc)}
+	client = fake.NewSimpleClientset(stale)
+	if _, err = createSecret(context.Background(), obc, obc.Name, nil, auth, nil, nil, client, time.Millisecond, nil); err != nil {
+		t.Fatalf("createSecret() unexpected error: %v", err)
+	}
+	cur, _ = client.CoreV1().Secrets(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	if cur.StringData[v1alpha1.AwsKeyField] != "new-id" || cur.StringData["password"] != "" {
+		t.Errorf("secret data = %v, want the new credentials", cur.StringData)
+	}
+}
+
+func TestCreateSecretAdopt(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+			UID:       "obc-uid",
+		},
+	}
+	auth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "new-id", SecretAccessKey: "new-secret"}}
+	precreated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        obc.Name,
+			Namespace:   obc.Namespace,
+			Annotations: map[string]string{api.AdoptSecretAnnotation: "true"},
+		},
+		StringData: map[string]string{v1alpha1.AwsKeyField: "external-id", "password": "hunter2"},
+	}
+	client := fake.NewSimpleClientset(precreated)
+
+	// adopting twice, as a retried attempt would, leaves the user's keys untouched
+	for i := 0; i < 2; i++ {
+		got, err := createSecret(context.Background(), obc, obc.Name, nil, auth, nil, nil, client, time.Millisecond, nil)
+		if err != nil {
+			t.Fatalf("createSecret() unexpected error: %v", err)
+		}
+		want := map[string]string{
+			v1alpha1.AwsKeyField:    "external-id",
+			v1alpha1.AwsSecretField: "new-secret",
+			"password":              "hunter2",
+		}
+		if !reflect.DeepEqual(got.StringData, want) {
+			t.Errorf("secret data = %v, want %v", got.StringData, want)
+		}
+		if !isControlledByClaim(got, obc) || !hasFinalizer(got.Finalizers, secretFinalizer) {
+			t.Errorf("secret metadata %+v, want the claim as controller and the finalizer", got.ObjectMeta)
+		}
+	}
+}
+
+func TestReclaimStaleArtifacts(t *testing.T) {
+	deleted := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-obc", Namespace: "test-obc-namespace", UID: "old-uid"},
+	}
+	obc := deleted.DeepCopy()
+	obc.UID = "new-uid"
+
+	staleMeta, err := newArtifactObjectMeta(deleted, deleted.Name, nil)
+	if err != nil {
+		t.Fatalf("newArtifactObjectMeta() unexpected error: %v", err)
+	}
+	blobsMeta := *staleMeta.DeepCopy()
+	blobsMeta.Name = deleted.Name + "-blobs"
+	secret := &corev1.Secret{ObjectMeta: *staleMeta.DeepCopy()}
+	cm := &corev1.ConfigMap{ObjectMeta: *staleMeta.DeepCopy(), Data: map[string]string{bucketBlobsConfigMap: blobsMeta.Name}}
+	blobs := &corev1.ConfigMap{ObjectMeta: blobsMeta}
+	client := fake.NewSimpleClientset(secret, cm, blobs)
+
+	if err = reclaimStaleArtifacts(obc, obc.Name, client); err != nil {
+		t.Fatalf("reclaimStaleArtifacts() unexpected error: %v", err)
+	}
+	gotSecret, _ := client.CoreV1().Secrets(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	gotCM, _ := client.CoreV1().ConfigMaps(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	gotBlobs, _ := client.CoreV1().ConfigMaps(obc.Namespace).Get(blobsMeta.Name, metav1.GetOptions{})
+	for _, obj := range []metav1.Object{gotSecret, gotCM, gotBlobs} {
+		if !isControlledByClaim(obj, obc) || obj.GetAnnotations()[api.ClaimUIDAnnotation] != string(obc.UID) {
+			t.Errorf("%s not reclaimed: owners %v, annotations %v", obj.GetName(), obj.GetOwnerReferences(), obj.GetAnnotations())
+		}
+		if len(obj.GetFinalizers()) != 1 {
+			t.Errorf("%s finalizers = %v, want only the library's", obj.GetName(), obj.GetFinalizers())
+		}
+	}
+
+	// objects which do not record a claim, and artifacts retained from a deleted claim, are left alone
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: obc.Name, Namespace: obc.Namespace}}
+	retained := cm.DeepCopy()
+	retained.Annotations[api.RetainedFromClaimAnnotation] = deleted.Namespace + "/" + deleted.Name
+	client = fake.NewSimpleClientset(foreign, retained)
+	if err = reclaimStaleArtifacts(obc, obc.Name, client); err != nil {
+		t.Fatalf("reclaimStaleArtifacts() unexpected error: %v", err)
+	}
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" {
+			t.Errorf("reclaimStaleArtifacts() updated %s, want it left alone", a.GetResource().Resource)
+		}
+	}
+}
+
+func TestCreateServiceAccount(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+			UID:       "obc-uid",
+		},
+	}
+	const roleAnnotation = "eks.amazonaws.com/role-arn"
+	desired := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "bucket-user",
+			Annotations: map[string]string{roleAnnotation: "arn:aws:iam::123456789012:role/bucket"},
+		},
+	}
+	client := fake.NewSimpleClientset()
+	sa, err := createServiceAccount(context.Background(), obc, desired, nil, client, time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("createServiceAccount() unexpected error: %v", err)
+	}
+	if sa.Namespace != obc.Namespace || !isControlledByClaim(sa, obc) || !hasFinalizer(sa.Finalizers, serviceAccountFinalizer) {
+		t.Errorf("createServiceAccount() = %+v, want it in the claim's namespace, owned by the claim and finalized", sa.ObjectMeta)
+	}
+
+	// a retried attempt updates the annotations of the service account it created
+	desired.Annotations[roleAnnotation] = "arn:aws:iam::123456789012:role/other"
+	if _, err = createServiceAccount(context.Background(), obc, desired, nil, client, time.Millisecond, nil); err != nil {
+		t.Fatalf("createServiceAccount() unexpected error: %v", err)
+	}
+	cur, _ := client.CoreV1().ServiceAccounts(obc.Namespace).Get(desired.Name, metav1.GetOptions{})
+	if got := cur.Annotations[roleAnnotation]; got != desired.Annotations[roleAnnotation] {
+		t.Errorf("service account annotation = %q, want %q", got, desired.Annotations[roleAnnotation])
+	}
+
+	if err = releaseServiceAccount(obc.Namespace, desired.Name, client, nil); err != nil {
+		t.Fatalf("releaseServiceAccount() unexpected error: %v", err)
+	}
+	cur, _ = client.CoreV1().ServiceAccounts(obc.Namespace).Get(desired.Name, metav1.GetOptions{})
+	if hasFinalizer(cur.Finalizers, serviceAccountFinalizer) {
+		t.Errorf("releaseServiceAccount() kept the finalizer: %v", cur.Finalizers)
+	}
+	if err = releaseServiceAccount(obc.Namespace, "gone", client, nil); err != nil {
+		t.Errorf("releaseServiceAccount() error = %v for a missing service account, want nil", err)
+	}
+
+	// a service account of the same name which was not created for the claim is left alone
+	foreign := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: obc.Namespace}}
+	client = fake.NewSimpleClientset(foreign)
+	sa, err = createServiceAccount(context.Background(), obc, desired, nil, client, time.Millisecond, nil)
+	if err == nil || !strings.Contains(err.Error(), "does not belong") || sa != nil {
+		t.Errorf("createServiceAccount() = %v, %v, want a name collision", sa, err)
+	}
+}
+
+func TestCreateArtifactsUpdatedIndependently(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+			UID:       "obc-uid",
+		},
+	}
+	oldEP := &v1alpha1.Endpoint{BucketHost: "old.example.com", BucketPort: 443, BucketName: "bucket"}
+	newEP := &v1alpha1.Endpoint{BucketHost: "new.example.com", BucketPort: 443, BucketName: "bucket"}
+	oldAuth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "old-id", SecretAccessKey: "old-secret"}}
+	newAuth := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "new-id", SecretAccessKey: "new-secret"}}
+
+	tests := []struct {
+		name             string
+		ep               *v1alpha1.Endpoint
+		auth             *v1alpha1.Authentication
+		wantSecretUpdate bool
+		wantConfigUpdate bool
+	}{
+		{"unchanged", oldEP, oldAuth, false, false},
+		{"endpoint only", newEP, oldAuth, false, true},
+		{"credentials only", oldEP, newAuth, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, _ := newCredentialsSecret(obc, obc.Name, oldEP, oldAuth, nil, nil)
+			cm, _ := newBucketConfigMap(obc, obc.Name, oldEP, oldAuth, nil, nil)
+			client := fake.NewSimpleClientset(secret, cm)
+			updated := map[string]bool{}
+			client.PrependReactor("update", "*", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+				updated[action.GetResource().Resource] = true
+				return false, nil, nil
+			})
+
+			ctx := context.Background()
+			if _, err := createSecret(ctx, obc, obc.Name, tt.ep, tt.auth, nil, nil, client, time.Millisecond, nil); err != nil {
+				t.Fatalf("createSecret() unexpected error: %v", err)
+			}
+			if _, err := createConfigMap(ctx, obc, obc.Name, tt.ep, tt.auth, nil, nil, client, time.Millisecond, nil); err != nil {
+				t.Fatalf("createConfigMap() unexpected error: %v", err)
+			}
+			if updated["secrets"] != tt.wantSecretUpdate {
+				t.Errorf("secret updated = %v, want %v", updated["secrets"], tt.wantSecretUpdate)
+			}
+			if updated["configmaps"] != tt.wantConfigUpdate {
+				t.Errorf("configMap updated = %v, want %v", updated["configmaps"], tt.wantConfigUpdate)
+			}
+		})
+	}
+}
+
+func TestCreateSharedRetryBudget(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	calls := 0
+	client.PrependReactor("create", "*", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		calls++
+		return true, nil, fmt.Errorf("transient error")
+	})
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := createSecret(ctx, obc, obc.Name, nil, &v1alpha1.Authentication{}, nil, nil, client, time.Millisecond, nil); err == nil {
+		t.Fatalf("createSecret() expected error, got nil")
+	}
+
+	// the budget is spent, the next create must not restart the clock
+	calls = 0
+	if _, err := createConfigMap(ctx, obc, obc.Name, &v1alpha1.Endpoint{}, nil, nil, nil, client, time.Millisecond, nil); err == nil {
+		t.Fatalf("createConfigMap() expected error, got nil")
+	}
+	if calls != 1 {
+		t.Errorf("createConfigMap() made %d attempts with a spent budget, want 1", calls)
+	}
+}
+
+func TestCreateConfigMapForbidden(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	calls := 0
+	client.PrependReactor("create", "configmaps", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+		calls++
+		return true, nil, errors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "test-obc", fmt.Errorf("rbac"))
+	})
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-obc",
+			Namespace: "test-obc-namespace",
+		},
+	}
+
+	_, err := createConfigMap(context.Background(), obc, obc.Name, &v1alpha1.Endpoint{}, nil, nil, nil, client, time.Millisecond, nil)
+	if !pErr.IsPermission(err) {
+		t.Fatalf("createConfigMap() error = %v, want a PermissionErr", err)
+	}
+	if calls != 1 {
+		t.Errorf("createConfigMap() made %d attempts, want 1", calls)
+	}
+}
+
+func TestSplitConfigMap(t *testing.T) {
+	newCM := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-obc", Namespace: "test-obc-namespace"},
+			Data:       data,
+		}
+	}
+
+	small := newCM(map[string]string{bucketHost: "host", "CA_BUNDLE": "small"})
+	if blobs := splitConfigMap(small, 1024); blobs != nil {
+		t.Errorf("splitConfigMap() = %v, want nil under the limit", blobs)
+	}
+
+	large := newCM(map[string]string{
+		bucketHost:  strings.Repeat("h", 100),
+		"CA_BUNDLE": strings.Repeat("c", 2000),
+		"OTHER":     strings.Repeat("o", 500),
+	})
+	blobs := splitConfigMap(large, 1024)
+	if blobs == nil {
+		t.Fatalf("splitConfigMap() = nil, want a blobs configMap")
+	}
+	if _, ok := blobs.Data["CA_BUNDLE"]; !ok || len(blobs.Data) != 1 {
+		t.Errorf("splitConfigMap() moved %v, want only the largest value", blobs.Data)
+	}
+	if large.Data[bucketBlobsConfigMap] != blobs.Name || large.Data[bucketHost] == "" {
+		t.Errorf("splitConfigMap() left %v, want endpoint keys and a reference to %q", large.Data, blobs.Name)
+	}
+	if size := configMapDataSize(large.Data); size > 1024 {
+		t.Errorf("splitConfigMap() left %d bytes, want at most 1024", size)
+	}
+}
+
+func TestCreateObjectBucketFinalizers(t *testing.T) {
+	client := externalFake.NewSimpleClientset()
+	ob := &v1alpha1.ObjectBucket{ObjectMeta: metav1.ObjectMeta{Name: "test-ob"}}
+
+	got, err := createObjectBucket(context.Background(), ob, []string{"example.com/cleanup", finalizer}, false, client, time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("createObjectBucket() unexpected error: %v", err)
+	}
+	want := []string{objectBucketFinalizer, "example.com/cleanup"}
+	if !reflect.DeepEqual(got.Finalizers, want) {
+		t.Errorf("createObjectBucket() finalizers = %v, want %v", got.Finalizers, want)
+	}
+	if others := provisionerFinalizers(got); !reflect.DeepEqual(others, want[1:]) {
+		t.Errorf("provisionerFinalizers() = %v, want %v", others, want[1:])
+	}
+}
+
+func TestCreateObjectBucketStaleConnection(t *testing.T) {
+	claimRef := &corev1.ObjectReference{Namespace: testNamespace, Name: testName, UID: "obc-uid"}
+	newOB := func(host string) *v1alpha1.ObjectBucket {
+		return &v1alpha1.ObjectBucket{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-ob"},
+			Spec: v1alpha1.ObjectBucketSpec{
+				ClaimRef: claimRef,
+				Connection: &v1alpha1.Connection{
+					Endpoint:       &v1alpha1.Endpoint{BucketHost: host, BucketName: "bucket"},
+					Authentication: &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: "id"}},
+				},
+			},
+		}
+	}
+
+	for _, update := range []bool{false, true} {
+		t.Run(fmt.Sprintf("update=%v", update), func(t *testing.T) {
+			stale := newOB("old.example.com")
+			stale.Spec.Connection.Authentication = nil
+			client := externalFake.NewSimpleClientset(stale)
+
+			got, err := createObjectBucket(context.Background(), newOB("new.example.com"), nil, update, client, time.Millisecond, nil)
+			if err != nil {
+				t.Fatalf("createObjectBucket() unexpected error: %v", err)
+			}
+			want := "old.example.com"
+			if update {
+				want = "new.example.com"
+			}
+			cur, _ := client.ObjectbucketV1alpha1().ObjectBuckets().Get(stale.Name, metav1.GetOptions{})
+			if host := cur.Spec.Connection.Endpoint.BucketHost; host != want || got.Spec.Endpoint.BucketHost != want {
+				t.Errorf("ObjectBucket host = %q, want %q", host, want)
+			}
+		})
+	}
+}
+
+func TestDeleteObjectBucket(t *testing.T) {
+	obName := "test-ob"
+	newOB := func() *v1alpha1.ObjectBucket {
+		return &v1alpha1.ObjectBucket{
+			ObjectMeta: metav1.ObjectMeta{Name: obName, UID: "uid", Finalizers: []string{finalizer}},
+		}
+	}
+	gr := schema.GroupResource{Group: v1alpha1.SchemeGroupVersion.Group, Resource: "objectbuckets"}
+
+	tests := []struct {
+		name    string
+		stored  bool
+		verb    string
+		errs    []error
+		wantErr bool
+		// the fake store is only updated by calls which reach the default reactor
+		wantDeleted bool
+	}{
+		{
+			name:        "conflict then success",
+			stored:      true,
+			verb:        "update",
+			errs:        []error{errors.NewConflict(gr, obName, fmt.Errorf("stale"))},
+			wantDeleted: true,
+		}, {
+			name:        "transient delete error",
+			stored:      true,
+			verb:        "delete",
+			errs:        []error{errors.NewInternalError(fmt.Errorf("etcd"))},
+			wantDeleted: true,
+		}, {
+			name:        "not found",
+			stored:      false,
+			wantDeleted: true,
+		}, {
+			name:   "vanished before delete",
+			stored: true,
+			verb:   "delete",
+			errs:   []error{errors.NewNotFound(gr, obName)},
+		}, {
+			name:    "permanent delete error",
+			stored:  true,
+			verb:    "delete",
+			errs:    []error{errors.NewBadRequest("invalid")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := externalFake.NewSimpleClientset()
+			if tt.stored {
+				client = externalFake.NewSimpleClientset(newOB())
+			}
+			errs := tt.errs
+			if tt.verb != "" {
+				client.PrependReactor(tt.verb, "objectbuckets", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+					if len(errs) == 0 {
+						return false, nil, nil
+					}
+					err := errs[0]
+					errs = errs[1:]
+					return true, nil, err
+				})
+			}
+
+			err := deleteObjectBucket(newOB(), client, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deleteObjectBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(errs) > 0 {
+				t.Errorf("deleteObjectBucket() did not retry, %d injected errors left", len(errs))
+			}
+			if !tt.wantDeleted {
+				return
+			}
+			if _, err = client.ObjectbucketV1alpha1().ObjectBuckets().Get(obName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+				t.Errorf("ObjectBucket still exists after deleteObjectBucket(), get error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncConfigMapData(t *testing.T) {
+	desired := &corev1.ConfigMap{
+		Data: map[string]string{
+			bucketHost: "host",
+			bucketPort: "8443",
+		},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"user": "annotation"},
+		},
+		Data: map[string]string{
+			bucketHost: "host",
+			bucketPort: "80",
+			"USER_KEY": "user-value",
+		},
+	}
+
+	if !syncConfigMapData(cm, desired) {
+		t.Fatalf("syncConfigMapData() = false, want true for drifted port")
+	}
+	want := map[string]string{
+		bucketHost: "host",
+		bucketPort: "8443",
+		"USER_KEY": "user-value",
+	}
+	if !reflect.DeepEqual(cm.Data, want) {
+		t.Errorf("syncConfigMapData() data = %v, want %v", cm.Data, want)
+	}
+	if cm.Annotations["user"] != "annotation" {
+		t.Errorf("syncConfigMapData() dropped user annotations: %v", cm.Annotations)
+	}
+	if syncConfigMapData(cm, desired) {
+		t.Errorf("syncConfigMapData() = true, want false when nothing drifted")
+	}
+}
+
+func TestRemapSecretKeys(t *testing.T) {
+	data := map[string]string{
+		v1alpha1.AwsKeyField:    "key",
+		v1alpha1.AwsSecretField: "secret",
+	}
+	tests := []struct {
+		name       string
+		annotation string
+		want       map[string]string
+	}{
+		{
+			name:       "remaps known keys",
+			annotation: `{"AWS_ACCESS_KEY_ID": "S3_KEY"}`,
+			want: map[string]string{
+				"S3_KEY":                "key",
+				v1alpha1.AwsSecretField: "secret",
+			},
+		}, {
+			name:       "unparseable annotation falls back to defaults",
+			annotation: `{"AWS_ACCESS_KEY_ID": `,
+			want:       data,
+		}, {
+			name:       "unknown key falls back to defaults",
+			annotation: `{"UNKNOWN": "S3_KEY"}`,
+			want:       data,
+		}, {
+			name:       "colliding names fall back to defaults",
+			annotation: `{"AWS_ACCESS_KEY_ID": "AWS_SECRET_ACCESS_KEY"}`,
+			want:       data,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obc := &v1alpha1.ObjectBucketClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{api.SecretKeyMapAnnotation: tt.annotation},
+				},
+			}
+			if got := remapSecretKeys(obc, data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remapSecretKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetainArtifacts(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, UID: "claim-uid"}}
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            name,
+			Finalizers:      []string{finalizer},
+			OwnerReferences: []metav1.OwnerReference{makeOwnerReference(obc)},
+		}
+	}
+	secret := &corev1.Secret{ObjectMeta: meta(testName)}
+	cm := &corev1.ConfigMap{ObjectMeta: meta(testName), Data: map[string]string{bucketBlobsConfigMap: testName + blobsConfigMapSuffix}}
+	blobs := &corev1.ConfigMap{ObjectMeta: meta(testName + blobsConfigMapSuffix)}
+	client := fake.NewSimpleClientset(secret, cm, blobs)
+
+	if err := retainSecret(secret, obc, client, nil); err != nil {
+		t.Fatalf("retainSecret() unexpected error: %v", err)
+	}
+	if err := retainConfigMaps(cm, obc, client, nil); err != nil {
+		t.Fatalf("retainConfigMaps() unexpected error: %v", err)
+	}
+
+	gotSecret, _ := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+	gotCM, _ := client.CoreV1().ConfigMaps(testNamespace).Get(testName, metav1.GetOptions{})
+	gotBlobs, _ := client.CoreV1().ConfigMaps(testNamespace).Get(testName+blobsConfigMapSuffix, metav1.GetOptions{})
+	for _, obj := range []metav1.Object{gotSecret, gotCM, gotBlobs} {
+		if len(obj.GetOwnerReferences()) != 0 || len(obj.GetFinalizers()) != 0 {
+			t.Errorf("%s still owned by the claim: ownerReferences %v, finalizers %v", obj.GetName(), obj.GetOwnerReferences(), obj.GetFinalizers())
+		}
+		if got := obj.GetAnnotations()[api.RetainedFromClaimAnnotation]; got != testNamespace+"/"+testName {
+			t.Errorf("%s annotation %s = %q, want the claim", obj.GetName(), api.RetainedFromClaimAnnotation, got)
+		}
+	}
+}
+
+func TestUpdateStatusSkipsNoOp(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName},
+		Status: v1alpha1.ObjectBucketClaimStatus{
+			Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound,
+			Conditions: []v1alpha1.ObjectBucketClaimCondition{{
+				Type:               v1alpha1.ObjectBucketClaimProvisioned,
+				Status:             corev1.ConditionTrue,
+				Reason:             reasonBound,
+				LastTransitionTime: metav1.Now(),
+			}},
+		},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+		Status:     v1alpha1.ObjectBucketStatus{Phase: v1alpha1.ObjectBucketStatusPhaseBound},
+	}
+	client := externalFake.NewSimpleClientset(obc.DeepCopy(), ob.DeepCopy())
+
+	if _, err := updateObjectBucketClaimPhase(client, obc.DeepCopy(), v1alpha1.ObjectBucketClaimStatusPhaseBound, false, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("updateObjectBucketClaimPhase() unexpected error = %v", err)
+	}
+	if _, err := updateObjectBucketClaimStatus(client, obc.DeepCopy(), func(status *v1alpha1.ObjectBucketClaimStatus) {
+		setClaimCondition(status, v1alpha1.ObjectBucketClaimCondition{
+			Type:   v1alpha1.ObjectBucketClaimProvisioned,
+			Status: corev1.ConditionTrue,
+			Reason: reasonBound,
+		})
+	}, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("updateObjectBucketClaimStatus() unexpected error = %v", err)
+	}
+	if _, err := updateObjectBucketPhase(client, ob.DeepCopy(), v1alpha1.ObjectBucketStatusPhaseBound, false, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("updateObjectBucketPhase() unexpected error = %v", err)
+	}
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" {
+			t.Errorf("unexpected %s of %s for a no-op status transition", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+
+	if _, err := updateObjectBucketClaimPhase(client, obc.DeepCopy(), v1alpha1.ObjectBucketClaimStatusPhaseFailed, false, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("updateObjectBucketClaimPhase() unexpected error = %v", err)
+	}
+	updates := 0
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" && a.GetSubresource() == "status" {
+			updates++
+		}
+	}
+	if updates != 1 {
+		t.Errorf("made %d status updates for a phase change, want 1", updates)
+	}
+}
+
+func TestUpdatePhaseRejectsInvalidTransition(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName},
+		Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+		Status:     v1alpha1.ObjectBucketStatus{Phase: v1alpha1.ObjectBucketStatusPhaseReleased},
+	}
+	client := externalFake.NewSimpleClientset(obc.DeepCopy(), ob.DeepCopy())
+
+	if _, err := updateObjectBucketClaimPhase(client, obc.DeepCopy(), v1alpha1.ObjectBucketClaimStatusPhasePending, false, time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Errorf("updateObjectBucketClaimPhase() from bound to pending succeeded, want error")
+	}
+	if _, err := updateObjectBucketPhase(client, ob.DeepCopy(), v1alpha1.ObjectBucketStatusPhaseFailed, false, time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Errorf("updateObjectBucketPhase() from released to failed succeeded, want error")
+	}
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" {
+			t.Errorf("unexpected %s of %s for an invalid phase transition", a.GetVerb(), a.GetResource().Resource)
+		}
+	}
+
+	got, err := updateObjectBucketClaimPhase(client, obc.DeepCopy(), v1alpha1.ObjectBucketClaimStatusPhasePending, true, time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("updateObjectBucketClaimPhase() forced unexpected error = %v", err)
+	}
+	if got.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhasePending {
+		t.Errorf("updateObjectBucketClaimPhase() forced phase = %q, want %q", got.Status.Phase, v1alpha1.ObjectBucketClaimStatusPhasePending)
+	}
+}
+
+func TestReleaseArtifacts(t *testing.T) {
+	meta := metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Finalizers: []string{secretFinalizer, configMapFinalizer}}
+	tests := []struct {
+		name        string
+		stored      bool
+		verb        string
+		err         error
+		wantErr     bool
+		wantRequeue bool
+	}{
+		{name: "released", stored: true},
+		{name: "already deleted"},
+		{name: "deleted meanwhile", stored: true, verb: "update", err: errors.NewNotFound(schema.GroupResource{}, testName)},
+		{name: "transient get error", stored: true, verb: "get", err: errors.NewServiceUnavailable("unavailable"), wantErr: true, wantRequeue: true},
+		{name: "transient update error", stored: true, verb: "update", err: errors.NewTooManyRequests("slow down", 1), wantErr: true, wantRequeue: true},
+		{name: "other error", stored: true, verb: "update", err: errors.NewBadRequest("invalid"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: *meta.DeepCopy()}
+			cm := &corev1.ConfigMap{ObjectMeta: *meta.DeepCopy()}
+			sa := &corev1.ServiceAccount{ObjectMeta: *meta.DeepCopy()}
+			client := fake.NewSimpleClientset()
+			if tt.stored {
+				client = fake.NewSimpleClientset(secret.DeepCopy(), cm.DeepCopy(), sa.DeepCopy())
+			}
+			if tt.verb != "" {
+				client.PrependReactor(tt.verb, "*", func(action k8sTesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.err
+				})
+			}
+			for kind, err := range map[string]error{
+				"Secret":         releaseSecret(secret, client, nil),
+				"ConfigMap":      releaseConfigMap(cm, client, nil),
+				"ServiceAccount": releaseServiceAccount(sa.Namespace, sa.Name, client, nil),
+			} {
+				if (err != nil) != tt.wantErr {
+					t.Errorf("release%s() error = %v, wantErr %v", kind, err, tt.wantErr)
+				}
+				if _, requeue := err.(*requeueAfterError); requeue != tt.wantRequeue {
+					t.Errorf("release%s() error = %v, want requeue %v", kind, err, tt.wantRequeue)
+				}
+			}
+			if !tt.stored || tt.wantErr || tt.verb != "" {
+				return
+			}
+			got, err := client.CoreV1().Secrets(testNamespace).Get(testName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("error getting secret: %v", err)
+			}
+			if !reflect.DeepEqual(got.Finalizers, []string{configMapFinalizer}) {
+				t.Errorf("releaseSecret() left finalizers %v, want only those of other kinds", got.Finalizers)
+			}
+		})
+	}
+}
+
+func TestNewBucketConfigMapEnvFile(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	ep := &v1alpha1.Endpoint{
+		BucketHost:           "s3.example.com",
+		BucketPort:           443,
+		BucketName:           "bucket",
+		Region:               "us-east-1",
+		AdditionalConfigData: map[string]string{"TIER": "it's hot"},
+	}
+	options := &api.ProvisionOptions{EnvFileKey: "bucket.env"}
+
+	got, err := newBucketConfigMap(obc, testName, ep, nil, options, nil)
+	if err != nil {
+		t.Fatalf("newBucketConfigMap() error = %v", err)
+	}
+	want := "BUCKET_HOST=s3.example.com\nBUCKET_NAME=bucket\nBUCKET_PORT=443\nBUCKET_REGION=us-east-1\nBUCKET_SUBREGION=''\n"
+	if got.Data["bucket.env"] != want {
+		t.Errorf("newBucketConfigMap() env file = %q, want %q", got.Data["bucket.env"], want)
+	}
+	if got.Data[bucketName] != "bucket" {
+		t.Errorf("newBucketConfigMap() dropped the individual keys, %v", got.Data)
+	}
+
+	if quoted := quoteEnvValue("it's $HOME"); quoted != `'it'\''s $HOME'` {
+		t.Errorf("quoteEnvValue() = %s, want the value single-quoted", quoted)
+	}
+
+	options.EnvFileKey = "TIER"
+	if _, err = newBucketConfigMap(obc, testName, ep, nil, options, nil); err == nil {
+		t.Errorf("newBucketConfigMap() expected error for an env file key colliding with a config key")
+	}
+}
+
+func TestNewBucketConfigMapBucketHosts(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: testName, Namespace: testNamespace}}
+	tests := []struct {
+		name    string
+		hosts   []string
+		want    string
+		wantErr bool
+	}{
+		{"not set", nil, "", false},
+		{"ordered", []string{"s3-b.example.com", "s3-a.example.com:9000", "https://10.0.0.1"}, "s3-b.example.com,s3-a.example.com:9000,https://10.0.0.1", false},
+		{"duplicate", []string{"s3.example.com", "s3.example.com"}, "", true},
+		{"invalid hostname", []string{"s3_a.example.com"}, "", true},
+		{"comma", []string{"s3-a.example.com,s3-b.example.com"}, "", true},
+		{"path", []string{"s3.example.com/bucket"}, "", true},
+		{"invalid port", []string{"s3.example.com:0"}, "", true},
+		{"unsupported scheme", []string{"ftp://s3.example.com"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketHosts: tt.hosts}
+			got, err := newBucketConfigMap(obc, testName, ep, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newBucketConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Data[bucketHosts] != tt.want {
+				t.Errorf("newBucketConfigMap() %s = %q, want %q", bucketHosts, got.Data[bucketHosts], tt.want)
+			}
+			if got.Data[bucketHost] != "s3.example.com" {
+				t.Errorf("newBucketConfigMap() %s = %q, want the primary host", bucketHost, got.Data[bucketHost])
 			}
 		})
 	}
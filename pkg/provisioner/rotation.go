@@ -0,0 +1,231 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/provisioner/api"
+)
+
+const (
+	reasonCredentialsRotated       = "CredentialsRotated"
+	reasonCredentialRotationFailed = "CredentialRotationFailed"
+)
+
+// rotateCredentials rotates the credentials of a bound claim whose storage class sets a rotation interval, once the
+// credentials in its Secret are older than the interval: the provisioner issues new credentials, they are written to
+// the Secret, and only then are the old ones revoked. The access key ID of old credentials which could not be revoked is
+// recorded in the OB, and their revocation retried first on the next attempts. The claim is requeued for its next
+// rotation.
+func (c *obcController) rotateCredentials(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, now time.Time) error {
+	provisionOptions, err := c.provisionOptionsForObjectBucket(obc, ob)
+	if err != nil || provisionOptions == nil || provisionOptions.CredentialRotationDays == 0 {
+		return err
+	}
+	rotator, ok := c.provisionerForObjectBucket(ob).(api.CredentialRotator)
+	if !ok {
+		logD.Info("credential rotation requested but not supported by the provisioner", "ob", ob.Name)
+		return nil
+	}
+	if ob, err = c.retryPendingRevocation(obc, ob, rotator); err != nil {
+		return err
+	}
+	name := c.artifactNaming.Name(obc.Name)
+	secret, err := c.clientset.CoreV1().Secrets(obc.Namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// the secret is recreated, with new credentials, by reconcileSecret
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error getting secret \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+
+	interval := time.Duration(provisionOptions.CredentialRotationDays) * 24 * time.Hour
+	if age := now.Sub(credentialsIssued(secret)); age < interval {
+		return &requeueAfterError{delay: interval - age, reason: "waiting for the next credential rotation"}
+	}
+
+	log.Info("rotating credentials", "secret", obc.Namespace+"/"+name)
+	old := authenticationFromSecret(obc, secret, provisionOptions.SecretKeyNaming)
+	auth, err := rotator.RotateCredentials(ob.DeepCopy())
+	if err == nil && auth == nil {
+		err = fmt.Errorf("provisioner returned no credentials")
+	}
+	if err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, reasonCredentialRotationFailed, "error rotating credentials: %v", err)
+		return fmt.Errorf("error rotating credentials: %v", err)
+	}
+	if err = c.replaceCredentials(obc, ob, secret, auth, provisionOptions, now); err != nil {
+		// the old credentials are still in use, the provisioner is asked to rotate again on the next attempt
+		return err
+	}
+
+	if err = rotator.RevokeCredentials(ob.DeepCopy(), old); err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, reasonCredentialRotationFailed,
+			"credentials rotated but the old ones could not be revoked: %v", err)
+		// the new credentials are recorded as issued now, the revocation is retried before the rotation is due
+		if old.AccessKeys != nil && old.AccessKeys.AccessKeyID != "" {
+			if _, recordErr := c.setPendingRevocation(ob, old.AccessKeys.AccessKeyID); recordErr != nil {
+				return fmt.Errorf("error revoking rotated credentials: %v; %v", err, recordErr)
+			}
+		}
+		return fmt.Errorf("error revoking rotated credentials: %v", err)
+	}
+	c.recorder.Event(obc, corev1.EventTypeNormal, reasonCredentialsRotated, "bucket credentials rotated")
+	return &requeueAfterError{delay: interval, reason: "waiting for the next credential rotation"}
+}
+
+// retryPendingRevocation revokes the rotated credentials recorded in the OB's PendingRevocationAnnotation, if any, and
+// removes the annotation once they are. It returns the updated OB.
+func (c *obcController) retryPendingRevocation(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, rotator api.CredentialRotator) (*v1alpha1.ObjectBucket, error) {
+	accessKeyID, ok := ob.Annotations[api.PendingRevocationAnnotation]
+	if !ok {
+		return ob, nil
+	}
+	log.Info("revoking rotated credentials again", "ob", ob.Name, "accessKeyID", accessKeyID)
+	old := &v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{AccessKeyID: accessKeyID}}
+	if err := rotator.RevokeCredentials(ob.DeepCopy(), old); err != nil {
+		c.recorder.Eventf(obc, corev1.EventTypeWarning, reasonCredentialRotationFailed,
+			"rotated credentials could not be revoked: %v", err)
+		return nil, fmt.Errorf("error revoking rotated credentials: %v", err)
+	}
+	return c.setPendingRevocation(ob, "")
+}
+
+// setPendingRevocation records the access key ID of rotated credentials which are yet to be revoked in the OB, or
+// removes the record if accessKeyID is empty.
+func (c *obcController) setPendingRevocation(ob *v1alpha1.ObjectBucket, accessKeyID string) (*v1alpha1.ObjectBucket, error) {
+	updated := ob.DeepCopy()
+	if accessKeyID == "" {
+		delete(updated.Annotations, api.PendingRevocationAnnotation)
+	} else {
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, api.PendingRevocationAnnotation, accessKeyID)
+	}
+	result, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Update(updated)
+	if err != nil {
+		return nil, annotateError(asPermissionError(err, "update", "objectbuckets", "", updated.Name),
+			fmt.Sprintf("error recording pending revocation in ObjectBucket %q", updated.Name))
+	}
+	return result, nil
+}
+
+// replaceCredentials replaces the credentials in the claim's secret with auth, issued at now, and the access key ID in
+// its ConfigMap if the storage class publishes it there.
+func (c *obcController) replaceCredentials(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, secret *corev1.Secret, auth *v1alpha1.Authentication, options *api.ProvisionOptions, now time.Time) (err error) {
+	var ep *v1alpha1.Endpoint
+	if ob.Spec.Connection != nil {
+		if ep, err = c.publishedEndpoint(ob.Spec.Endpoint); err != nil {
+			return err
+		}
+	}
+	desired, err := newCredentialsSecret(obc, secret.Name, ep, auth, options, c.provisionerLabels)
+	if err != nil {
+		return err
+	}
+	// the new data replaces the old entirely, keys of the old credentials must not linger
+	secret.Data = nil
+	secret.StringData = desired.StringData
+	metav1.SetMetaDataAnnotation(&secret.ObjectMeta, api.CredentialsIssuedAnnotation, now.UTC().Format(time.RFC3339))
+	if _, err = updateSecret(c.clientset, secret, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+		return annotateError(err, "error updating secret with new credentials")
+	}
+	if options.AccessKeyIDInConfigMap && !options.SkipConfigMap && auth.AccessKeys != nil {
+		return c.updateConfigMapAccessKeyID(obc, auth.AccessKeys.AccessKeyID)
+	}
+	return nil
+}
+
+// updateConfigMapAccessKeyID writes the new access key ID to the claim's ConfigMap.
+func (c *obcController) updateConfigMapAccessKeyID(obc *v1alpha1.ObjectBucketClaim, accessKeyID string) error {
+	name := c.artifactNaming.Name(obc.Name)
+	cm, err := c.clientset.CoreV1().ConfigMaps(obc.Namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting configMap \"%s/%s\": %v", obc.Namespace, name, err)
+	}
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[v1alpha1.AwsKeyField] = accessKeyID
+	if _, err = updateConfigMap(c.clientset, cm, defaultRetryBaseInterval, defaultRetryTimeout); err != nil {
+		return annotateError(err, "error updating configMap with new access key ID")
+	}
+	return nil
+}
+
+// credentialsIssued returns when the credentials in secret were issued: the time recorded by their last rotation, or
+// the creation of the secret.
+func credentialsIssued(secret *corev1.Secret) time.Time {
+	if v, ok := secret.Annotations[api.CredentialsIssuedAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+		log.Info("ignoring invalid annotation", "annotation", api.CredentialsIssuedAnnotation, "value", v)
+	}
+	return secret.CreationTimestamp.Time
+}
+
+// authenticationFromSecret reads the access keys back from the claim's secret, undoing the renaming of its keys by the
+// claim's SecretKeyMapAnnotation or, without it, by the naming of its storage class.
+func authenticationFromSecret(obc *v1alpha1.ObjectBucketClaim, secret *corev1.Secret, naming v1alpha1.KeyNamingProfile) *v1alpha1.Authentication {
+	data := make(map[string]string, len(secret.Data)+len(secret.StringData))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	for k, v := range secret.StringData {
+		data[k] = v
+	}
+	// defaults maps each key of the secret to its default name
+	var defaults map[string]string
+	if _, ok := obc.Annotations[api.SecretKeyMapAnnotation]; ok {
+		// remapping the default key names to themselves maps each key of the secret to its default name. The key map
+		// may rename other keys than the credentials, which must be passed too for it to be applied.
+		names := map[string]string{
+			v1alpha1.AwsKeyField:    v1alpha1.AwsKeyField,
+			v1alpha1.AwsSecretField: v1alpha1.AwsSecretField,
+		}
+		keyMap := make(map[string]string)
+		if raw := obc.Annotations[api.SecretKeyMapAnnotation]; json.Unmarshal([]byte(raw), &keyMap) == nil {
+			for k := range keyMap {
+				names[k] = k
+			}
+		}
+		defaults = remapSecretKeys(obc, names)
+	} else {
+		defaults = (&v1alpha1.Authentication{AccessKeys: &v1alpha1.AccessKeys{
+			AccessKeyID:     v1alpha1.AwsKeyField,
+			SecretAccessKey: v1alpha1.AwsSecretField,
+		}}).ToMapWithNaming(naming)
+	}
+	keys := &v1alpha1.AccessKeys{}
+	for key, field := range defaults {
+		switch field {
+		case v1alpha1.AwsKeyField:
+			keys.AccessKeyID = data[key]
+		case v1alpha1.AwsSecretField:
+			keys.SecretAccessKey = data[key]
+		}
+	}
+	return &v1alpha1.Authentication{AccessKeys: keys}
+}
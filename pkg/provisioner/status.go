@@ -0,0 +1,191 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned"
+)
+
+// statusUpdates accumulates the status mutations made during a single reconcile so that the status of each object is
+// written once, when the reconcile ends, instead of after every step. Later mutations of the same object replace
+// earlier ones.
+type statusUpdates struct {
+	obc            *v1alpha1.ObjectBucketClaim
+	obcPhase       v1alpha1.ObjectBucketClaimStatusPhase
+	obcConditions  []v1alpha1.ObjectBucketClaimCondition
+	ob             *v1alpha1.ObjectBucket
+	obPhase        v1alpha1.ObjectBucketStatusPhase
+	providerStatus map[string]string
+	// bucketCreated is when Provision created the bucket, recorded in both statuses
+	bucketCreated *metav1.Time
+}
+
+// setClaimPhase records the phase to write to the claim's status on flush.
+func (u *statusUpdates) setClaimPhase(obc *v1alpha1.ObjectBucketClaim, phase v1alpha1.ObjectBucketClaimStatusPhase) {
+	u.obc = obc
+	u.obcPhase = phase
+}
+
+// setClaimCondition records a condition to set in the claim's status on flush. The claim's phase is written as well,
+// so the phase must have been recorded with setClaimPhase.
+func (u *statusUpdates) setClaimCondition(obc *v1alpha1.ObjectBucketClaim, cond v1alpha1.ObjectBucketClaimCondition) {
+	u.obc = obc
+	for i := range u.obcConditions {
+		if u.obcConditions[i].Type == cond.Type {
+			u.obcConditions[i] = cond
+			return
+		}
+	}
+	u.obcConditions = append(u.obcConditions, cond)
+}
+
+// setBucketPhase records the phase to write to the object bucket's status on flush. Binding the object bucket also
+// records its bucket name and region in the status.
+func (u *statusUpdates) setBucketPhase(ob *v1alpha1.ObjectBucket, phase v1alpha1.ObjectBucketStatusPhase) {
+	u.ob = ob
+	u.obPhase = phase
+}
+
+// setProviderStatus records provider status keys to merge into the object bucket's status on flush.
+func (u *statusUpdates) setProviderStatus(updates map[string]string) {
+	if u.providerStatus == nil {
+		u.providerStatus = make(map[string]string, len(updates))
+	}
+	for k, v := range updates {
+		u.providerStatus[k] = v
+	}
+}
+
+// setBucketCreationTimestamp records when the bucket was created, to write to the status of the object bucket and the
+// claim on flush. A timestamp already in either status is kept.
+func (u *statusUpdates) setBucketCreationTimestamp(t metav1.Time) {
+	u.bucketCreated = &t
+}
+
+// flush writes the accumulated mutations with a single UpdateStatus call per object, retrying on conflict. An OB
+// which no longer exists (eg. it was cleaned up after a failed provision) is skipped. A phase which the current phase
+// cannot transition to, eg. a bound claim returning to pending, is not written, the other mutations are.
+func (u *statusUpdates) flush(c versioned.Interface, retryInterval, retryTimeout time.Duration) error {
+	var obErr, obcErr error
+	if u.ob != nil {
+		logD.Info("updating status:", "ob", u.ob.Name, "old status", u.ob.Status.Phase, "new status", u.obPhase)
+		_, obErr = updateObjectBucketStatus(c, u.ob, func(status *v1alpha1.ObjectBucketStatus) {
+			// checked against the latest status on conflict
+			if !v1alpha1.IsValidBucketTransition(status.Phase, u.obPhase) {
+				log.Info("not updating OB phase, invalid transition", "ob", u.ob.Name, "from", status.Phase, "to", u.obPhase)
+			} else {
+				status.Phase = u.obPhase
+				if u.obPhase == v1alpha1.ObjectBucketStatusPhaseBound {
+					setBucketStatusEndpoint(status, u.ob)
+				}
+			}
+			mergeProviderStatus(status, u.providerStatus)
+			if status.BucketCreationTimestamp == nil {
+				status.BucketCreationTimestamp = u.bucketCreated.DeepCopy()
+			}
+		}, retryInterval, retryTimeout)
+		if errors.IsNotFound(obErr) {
+			logD.Info("ObjectBucket is gone, skipping status update", "name", u.ob.Name)
+			obErr = nil
+		}
+	}
+	if u.obc != nil {
+		logD.Info("updating status:", "obc", u.obc.Namespace+"/"+u.obc.Name, "old status",
+			u.obc.Status.Phase, "new status", u.obcPhase)
+		_, obcErr = updateObjectBucketClaimStatus(c, u.obc, func(status *v1alpha1.ObjectBucketClaimStatus) {
+			if !v1alpha1.IsValidClaimTransition(status.Phase, u.obcPhase) {
+				log.Info("not updating OBC phase, invalid transition", "obc", u.obc.Namespace+"/"+u.obc.Name,
+					"from", status.Phase, "to", u.obcPhase)
+			} else {
+				status.Phase = u.obcPhase
+			}
+			for _, cond := range u.obcConditions {
+				setClaimCondition(status, cond)
+			}
+			if status.BucketCreationTimestamp == nil {
+				status.BucketCreationTimestamp = u.bucketCreated.DeepCopy()
+			}
+		}, retryInterval, retryTimeout)
+	}
+
+	switch {
+	case obErr != nil && obcErr != nil:
+		return fmt.Errorf("error updating OB status: %v; error updating OBC status: %v", obErr, obcErr)
+	case obErr != nil:
+		return fmt.Errorf("error updating OB status: %v", obErr)
+	case obcErr != nil:
+		return fmt.Errorf("error updating OBC status: %v", obcErr)
+	}
+	return nil
+}
+
+// setClaimCondition adds cond to status, replacing any condition of the same type. The transition time is preserved
+// unless the condition's status changed.
+func setClaimCondition(status *v1alpha1.ObjectBucketClaimStatus, cond v1alpha1.ObjectBucketClaimCondition) {
+	cond.LastTransitionTime = metav1.Now()
+	for i := range status.Conditions {
+		if status.Conditions[i].Type != cond.Type {
+			continue
+		}
+		if status.Conditions[i].Status == cond.Status {
+			cond.LastTransitionTime = status.Conditions[i].LastTransitionTime
+		}
+		status.Conditions[i] = cond
+		return
+	}
+	status.Conditions = append(status.Conditions, cond)
+}
+
+// getClaimCondition returns the condition of the given type, or nil if status does not have one.
+func getClaimCondition(status *v1alpha1.ObjectBucketClaimStatus, condType v1alpha1.ObjectBucketClaimConditionType) *v1alpha1.ObjectBucketClaimCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// mergeProviderStatus merges updates into the provider status of the ob, removing the keys updated to an empty value.
+// Being applied again on conflict, to the latest status, the merge never drops keys written concurrently by others.
+// Returns true if the status changed.
+func mergeProviderStatus(status *v1alpha1.ObjectBucketStatus, updates map[string]string) bool {
+	changed := false
+	for k, v := range updates {
+		old, ok := status.ProviderStatus[k]
+		switch {
+		case v == "" && ok:
+			delete(status.ProviderStatus, k)
+		case v != "" && (!ok || old != v):
+			if status.ProviderStatus == nil {
+				status.ProviderStatus = make(map[string]string)
+			}
+			status.ProviderStatus[k] = v
+		default:
+			continue
+		}
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTesting "k8s.io/client-go/testing"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+)
+
+func TestStatusUpdatesFlush(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc", Namespace: "ns"},
+		Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBinding},
+	}
+	// the OB is deliberately absent from the clientset, as if it were cleaned up during the reconcile
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+	}
+	client := externalFake.NewSimpleClientset(obc.DeepCopy())
+
+	status := &statusUpdates{}
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhasePending)
+	status.setBucketPhase(ob, v1alpha1.ObjectBucketStatusPhaseBound)
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseBound)
+
+	if err := status.flush(client, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("flush() unexpected error = %v", err)
+	}
+
+	updates := 0
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "update" && a.GetSubresource() == "status" && a.GetResource().Resource == "objectbucketclaims" {
+			updates++
+			got := a.(k8sTesting.UpdateAction).GetObject().(*v1alpha1.ObjectBucketClaim).Status.Phase
+			if got != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+				t.Errorf("flush() wrote phase %q, want %q", got, v1alpha1.ObjectBucketClaimStatusPhaseBound)
+			}
+		}
+	}
+	if updates != 1 {
+		t.Errorf("flush() made %d OBC status updates, want 1", updates)
+	}
+}
+
+func TestStatusUpdatesFlushBoundBucket(t *testing.T) {
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+		Spec: v1alpha1.ObjectBucketSpec{
+			Connection: &v1alpha1.Connection{
+				Endpoint: &v1alpha1.Endpoint{BucketName: "bucket", Region: "us-east-1"},
+			},
+		},
+	}
+	client := externalFake.NewSimpleClientset(ob.DeepCopy())
+
+	status := &statusUpdates{}
+	status.setBucketPhase(ob, v1alpha1.ObjectBucketStatusPhaseBound)
+	if err := status.flush(client, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("flush() unexpected error = %v", err)
+	}
+
+	got, err := client.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ob: %v", err)
+	}
+	want := v1alpha1.ObjectBucketStatus{
+		Phase:      v1alpha1.ObjectBucketStatusPhaseBound,
+		BucketName: "bucket",
+		Region:     "us-east-1",
+	}
+	if !reflect.DeepEqual(got.Status, want) {
+		t.Errorf("flush() wrote status %+v, want %+v", got.Status, want)
+	}
+}
+
+func TestStatusUpdatesFlushBucketCreationTimestamp(t *testing.T) {
+	earlier := metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	created := metav1.NewTime(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC))
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc", Namespace: "ns"},
+		Status: v1alpha1.ObjectBucketClaimStatus{
+			Phase:                   v1alpha1.ObjectBucketClaimStatusPhaseBinding,
+			BucketCreationTimestamp: &earlier,
+		},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ob"},
+	}
+	client := externalFake.NewSimpleClientset(obc.DeepCopy(), ob.DeepCopy())
+
+	status := &statusUpdates{}
+	status.setBucketPhase(ob, v1alpha1.ObjectBucketStatusPhaseBound)
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhaseBound)
+	status.setBucketCreationTimestamp(created)
+	if err := status.flush(client, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("flush() unexpected error = %v", err)
+	}
+
+	gotOB, err := client.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting ob: %v", err)
+	}
+	if ts := gotOB.Status.BucketCreationTimestamp; ts == nil || !ts.Equal(&created) {
+		t.Errorf("flush() wrote OB bucketCreationTimestamp %v, want %v", ts, created)
+	}
+	gotOBC, err := client.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting obc: %v", err)
+	}
+	// a timestamp already in the status is not overwritten
+	if ts := gotOBC.Status.BucketCreationTimestamp; ts == nil || !ts.Equal(&earlier) {
+		t.Errorf("flush() wrote OBC bucketCreationTimestamp %v, want %v", ts, earlier)
+	}
+}
+
+func TestStatusUpdatesFlushInvalidTransition(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "obc", Namespace: "ns"},
+		Status:     v1alpha1.ObjectBucketClaimStatus{Phase: v1alpha1.ObjectBucketClaimStatusPhaseBound},
+	}
+	client := externalFake.NewSimpleClientset(obc.DeepCopy())
+
+	status := &statusUpdates{}
+	status.setClaimPhase(obc, v1alpha1.ObjectBucketClaimStatusPhasePending)
+	status.setClaimCondition(obc, v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimProvisioned,
+		Status: corev1.ConditionFalse,
+		Reason: "Other",
+	})
+	if err := status.flush(client, time.Millisecond, 10*time.Millisecond); err != nil {
+		t.Fatalf("flush() unexpected error = %v", err)
+	}
+
+	got, err := client.ObjectbucketV1alpha1().ObjectBucketClaims(obc.Namespace).Get(obc.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting obc: %v", err)
+	}
+	if got.Status.Phase != v1alpha1.ObjectBucketClaimStatusPhaseBound {
+		t.Errorf("flush() wrote phase %q, want the bound claim kept %q", got.Status.Phase, v1alpha1.ObjectBucketClaimStatusPhaseBound)
+	}
+	if len(got.Status.Conditions) != 1 {
+		t.Errorf("flush() wrote conditions %+v, want the condition written regardless of the phase", got.Status.Conditions)
+	}
+}
+
+func TestSetClaimCondition(t *testing.T) {
+	then := metav1.NewTime(time.Now().Add(-time.Hour))
+	status := &v1alpha1.ObjectBucketClaimStatus{
+		Conditions: []v1alpha1.ObjectBucketClaimCondition{{
+			Type:               v1alpha1.ObjectBucketClaimProvisioned,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: then,
+			Reason:             "PermissionDenied",
+		}},
+	}
+
+	setClaimCondition(status, v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimProvisioned,
+		Status: corev1.ConditionFalse,
+		Reason: "Other",
+	})
+	if len(status.Conditions) != 1 || status.Conditions[0].Reason != "Other" || !status.Conditions[0].LastTransitionTime.Equal(&then) {
+		t.Errorf("setClaimCondition() = %+v, want the reason replaced and the transition time kept", status.Conditions)
+	}
+
+	setClaimCondition(status, v1alpha1.ObjectBucketClaimCondition{
+		Type:   v1alpha1.ObjectBucketClaimProvisioned,
+		Status: corev1.ConditionTrue,
+	})
+	if len(status.Conditions) != 1 || status.Conditions[0].LastTransitionTime.Equal(&then) {
+		t.Errorf("setClaimCondition() = %+v, want the transition time updated", status.Conditions)
+	}
+}
+
+func TestMergeProviderStatus(t *testing.T) {
+	status := &v1alpha1.ObjectBucketStatus{ProviderStatus: map[string]string{"id": "1", "tier": "hot", "other": "x"}}
+
+	if !mergeProviderStatus(status, map[string]string{"id": "2", "tier": "", "new": "y"}) {
+		t.Errorf("mergeProviderStatus() = false, want true when keys change")
+	}
+	want := map[string]string{"id": "2", "other": "x", "new": "y"}
+	if !reflect.DeepEqual(status.ProviderStatus, want) {
+		t.Errorf("ProviderStatus = %v, want %v", status.ProviderStatus, want)
+	}
+	if mergeProviderStatus(status, map[string]string{"id": "2", "missing": ""}) {
+		t.Errorf("mergeProviderStatus() = true, want false when no key changes")
+	}
+}
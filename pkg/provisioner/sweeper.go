@@ -0,0 +1,220 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+const (
+	defaultOrphanSweepInterval    = time.Minute * 10
+	defaultOrphanSweepGracePeriod = time.Hour
+	defaultOrphanSweepBatchSize   = 10
+)
+
+// OrphanSweepPolicy configures the sweeper deleting orphaned ObjectBuckets, ie. OBs whose claim no longer exists, eg.
+// because the claim's finalizer was removed by hand. The bucket of an orphan is deleted or its access revoked as if
+// its claim had been deleted, then the OB is deleted. Fields left at 0 take their default.
+type OrphanSweepPolicy struct {
+	// Interval is the time between scans for orphans. Defaults to 10 minutes.
+	Interval time.Duration
+	// GracePeriod is how long an OB must have been seen orphaned before it is deleted. Defaults to 1 hour.
+	GracePeriod time.Duration
+	// BatchSize is the most orphans deleted by a scan, the rest are left to the following scans. Defaults to 10.
+	BatchSize int
+}
+
+// withDefaults returns p with its unset fields defaulted.
+func (p OrphanSweepPolicy) withDefaults() OrphanSweepPolicy {
+	if p.Interval == 0 {
+		p.Interval = defaultOrphanSweepInterval
+	}
+	if p.GracePeriod == 0 {
+		p.GracePeriod = defaultOrphanSweepGracePeriod
+	}
+	if p.BatchSize == 0 {
+		p.BatchSize = defaultOrphanSweepBatchSize
+	}
+	return p
+}
+
+// orphanSweeper periodically deletes orphaned OBs of the controller.
+type orphanSweeper struct {
+	c      *obcController
+	policy OrphanSweepPolicy
+	// seen holds when each orphan was first seen, by OB UID. Only the sweeper's goroutine accesses it.
+	seen map[types.UID]time.Time
+
+	// the results of the last scan, exposed by the sweep collector
+	mu        sync.Mutex
+	found     int
+	deleted   int
+	lastSweep time.Time
+}
+
+func newOrphanSweeper(c *obcController, policy OrphanSweepPolicy) *orphanSweeper {
+	return &orphanSweeper{
+		c:      c,
+		policy: policy.withDefaults(),
+		seen:   make(map[types.UID]time.Time),
+	}
+}
+
+// sweep runs a scan, it is called every policy.Interval.
+func (s *orphanSweeper) sweep() {
+	s.sweepAt(time.Now())
+}
+
+// sweepAt deletes up to policy.BatchSize OBs which have been orphaned for policy.GracePeriod as of now.
+func (s *orphanSweeper) sweepAt(now time.Time) {
+	if s.c.ReconcilesPaused() {
+		log.Info("reconciles are paused, skipping orphaned ObjectBucket sweep")
+		return
+	}
+	orphans, err := s.c.orphanedObjectBuckets()
+	if err != nil {
+		log.Error(err, "error scanning for orphaned ObjectBuckets")
+		return
+	}
+	current := make(map[types.UID]time.Time, len(orphans))
+	deleted := 0
+	for _, ob := range orphans {
+		since, ok := s.seen[ob.UID]
+		if !ok {
+			since = now
+		}
+		current[ob.UID] = since
+		if ob.DeletionTimestamp != nil || now.Sub(since) < s.policy.GracePeriod || deleted >= s.policy.BatchSize {
+			continue
+		}
+		removed, err := s.c.deleteOrphanedObjectBucket(ob)
+		if err != nil {
+			log.Error(err, "error deleting orphaned ObjectBucket", "ob", ob.Name)
+			continue
+		}
+		// an OB whose claim exists was not an orphan, the cache was stale
+		delete(current, ob.UID)
+		if removed {
+			deleted++
+		}
+	}
+	// OBs no longer orphaned, eg. adopted by a new claim, start over if they are orphaned again
+	s.seen = current
+	log.Info("orphaned ObjectBucket sweep done", "found", len(orphans), "deleted", deleted)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.found, s.deleted, s.lastSweep = len(orphans), deleted, now
+}
+
+// deleteOrphanedObjectBucket releases the bucket of the orphaned OB and deletes the OB, returning true if it did. The
+// claim is checked against the API rather than the cache, which may be stale or not cover the claim's namespace: an OB
+// whose claim exists is left alone.
+func (c *obcController) deleteOrphanedObjectBucket(ob *v1alpha1.ObjectBucket) (bool, error) {
+	ref := ob.Spec.ClaimRef
+	_, err := c.libClientset.ObjectbucketV1alpha1().ObjectBucketClaims(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+	if err == nil {
+		logD.Info("claim of ObjectBucket exists, not an orphan", "ob", ob.Name)
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+	log.Info("deleting orphaned ObjectBucket", "ob", ob.Name, "claim", ref.Namespace+"/"+ref.Name)
+	p := c.provisionerForObjectBucket(ob)
+	if ob.Spec.ReclaimPolicy != nil && *ob.Spec.ReclaimPolicy == corev1.PersistentVolumeReclaimDelete &&
+		isNewBucketByObjectBucket(c.clientset, ob) {
+		err = p.Delete(ob)
+	} else {
+		err = p.Revoke(ob)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err = deleteObjectBucket(ob.DeepCopy(), c.libClientset, c.auditor); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+var (
+	orphansFoundDesc = prometheus.NewDesc(
+		"objectbucket_orphan_sweep_found",
+		"Number of orphaned ObjectBuckets found by the last sweep.",
+		nil, nil,
+	)
+	orphansDeletedDesc = prometheus.NewDesc(
+		"objectbucket_orphan_sweep_deleted",
+		"Number of orphaned ObjectBuckets deleted by the last sweep.",
+		nil, nil,
+	)
+	lastSweepDesc = prometheus.NewDesc(
+		"objectbucket_orphan_sweep_last_timestamp_seconds",
+		"Time of the last sweep for orphaned ObjectBuckets, in seconds since the epoch.",
+		nil, nil,
+	)
+)
+
+// sweepCollector is a prometheus.Collector exposing the results of the last sweep for orphaned OBs. It emits nothing
+// unless the sweeper is enabled and until its first sweep.
+type sweepCollector struct {
+	c *obcController
+}
+
+var _ prometheus.Collector = &sweepCollector{}
+
+// Describe implements prometheus.Collector
+func (sc *sweepCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- orphansFoundDesc
+	ch <- orphansDeletedDesc
+	ch <- lastSweepDesc
+}
+
+// Collect implements prometheus.Collector
+func (sc *sweepCollector) Collect(ch chan<- prometheus.Metric) {
+	s := sc.c.sweeper
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastSweep.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(orphansFoundDesc, prometheus.GaugeValue, float64(s.found))
+	ch <- prometheus.MustNewConstMetric(orphansDeletedDesc, prometheus.GaugeValue, float64(s.deleted))
+	ch <- prometheus.MustNewConstMetric(lastSweepDesc, prometheus.GaugeValue, float64(s.lastSweep.Unix()))
+}
+
+// register the sweep collector with r.
+func (c *obcController) RegisterSweepCollector(r prometheus.Registerer) error {
+	return r.Register(&sweepCollector{c: c})
+}
+
+// enable the sweeper deleting orphaned OBs.
+func (c *obcController) SetOrphanSweepPolicy(policy OrphanSweepPolicy) {
+	c.sweeper = newOrphanSweeper(c, policy)
+}
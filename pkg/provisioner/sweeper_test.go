@@ -0,0 +1,110 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	externalFake "github.com/kube-object-storage/lib-bucket-provisioner/pkg/client/clientset/versioned/fake"
+)
+
+func TestOrphanSweeper(t *testing.T) {
+	ownLabels := map[string]string{provisionerLabelKey: labelValue(provisionerName)}
+	newOB := func(name string) *v1alpha1.ObjectBucket {
+		return &v1alpha1.ObjectBucket{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       name,
+				UID:        types.UID(name + "-uid"),
+				Labels:     ownLabels,
+				Finalizers: []string{finalizer},
+			},
+			Spec: v1alpha1.ObjectBucketSpec{
+				ClaimRef: &corev1.ObjectReference{Namespace: testNamespace, Name: name + "-claim"},
+			},
+		}
+	}
+	obs := []*v1alpha1.ObjectBucket{newOB("orphan-1"), newOB("orphan-2")}
+
+	c := newTestController(nil, obs)
+	c.clientset = fake.NewSimpleClientset()
+	c.libClientset = externalFake.NewSimpleClientset(obs[0], obs[1])
+	c.SetOrphanSweepPolicy(OrphanSweepPolicy{GracePeriod: time.Minute, BatchSize: 1})
+	s := c.sweeper
+
+	start := time.Now()
+	s.sweepAt(start)
+	if s.found != 2 || s.deleted != 0 {
+		t.Fatalf("first sweep found %d and deleted %d orphans, want 2 found and none deleted within the grace period", s.found, s.deleted)
+	}
+	s.sweepAt(start.Add(time.Minute))
+	if s.found != 2 || s.deleted != 1 {
+		t.Fatalf("second sweep found %d and deleted %d orphans, want 2 found and 1 deleted per batch", s.found, s.deleted)
+	}
+
+	remaining := 0
+	for _, ob := range obs {
+		_, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{})
+		if err == nil {
+			remaining++
+		} else if !errors.IsNotFound(err) {
+			t.Fatalf("unexpected error getting ObjectBucket %q: %v", ob.Name, err)
+		}
+	}
+	if remaining != 1 {
+		t.Errorf("%d ObjectBuckets remain after the sweep, want 1", remaining)
+	}
+}
+
+func TestOrphanSweeperClaimExists(t *testing.T) {
+	ob := &v1alpha1.ObjectBucket{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "ob",
+			UID:        "ob-uid",
+			Labels:     map[string]string{provisionerLabelKey: labelValue(provisionerName)},
+			Finalizers: []string{finalizer},
+		},
+		Spec: v1alpha1.ObjectBucketSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: testNamespace, Name: testName},
+		},
+	}
+	// the claim is missing from the cache only
+	obc := &v1alpha1.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName}}
+
+	c := newTestController(nil, []*v1alpha1.ObjectBucket{ob})
+	c.clientset = fake.NewSimpleClientset()
+	c.libClientset = externalFake.NewSimpleClientset(ob, obc)
+	c.SetOrphanSweepPolicy(OrphanSweepPolicy{GracePeriod: time.Minute, BatchSize: 1})
+	s := c.sweeper
+
+	start := time.Now()
+	s.sweepAt(start)
+	s.sweepAt(start.Add(time.Minute))
+	if s.deleted != 0 {
+		t.Errorf("sweep deleted %d orphans, want none for an OB whose claim exists", s.deleted)
+	}
+	if _, err := c.libClientset.ObjectbucketV1alpha1().ObjectBuckets().Get(ob.Name, metav1.GetOptions{}); err != nil {
+		t.Errorf("unexpected error getting ObjectBucket: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"strings"
+
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+// ValidateUpdate returns an error if newObc changes a spec field of oldObc which cannot be honored once the claim is
+// bound. It is used by the reconciler and can be used by a validating admission webhook to reject such updates
+// before they are persisted. Claims which are not bound yet may be changed freely.
+func ValidateUpdate(oldObc, newObc *v1alpha1.ObjectBucketClaim) error {
+	if oldObc.Spec.ObjectBucketName == "" {
+		return nil
+	}
+
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+	if newObc.Spec.BucketName != oldObc.Spec.BucketName {
+		errs = append(errs, field.Forbidden(spec.Child("bucketName"), "field is immutable once the claim is bound"))
+	}
+	if newObc.Spec.StorageClassName != oldObc.Spec.StorageClassName {
+		errs = append(errs, field.Forbidden(spec.Child("storageClassName"), "field is immutable once the claim is bound"))
+	}
+	return errs.ToAggregate()
+}
+
+// ShouldProvision returns true if claims of the StorageClass sc are served by the provisioner named provisionerName, as
+// the CSI external-provisioner does: the claims of classes naming another provisioner are ignored, not failed. It is
+// used by the reconciler and can be used by an admission webhook to only handle the claims of its provisioner.
+func ShouldProvision(sc *storagev1.StorageClass, provisionerName string) bool {
+	return sc != nil && provisionerName != "" && sc.Provisioner == provisionerName
+}
+
+// provisionedClaim returns a copy of obc whose immutable spec fields are set to the values the bucket was provisioned
+// with, as recorded in its ObjectBucket. The bucket name is only known for new buckets provisioned with an explicit
+// name: generated names are not recorded in the spec, and the claim's name is ignored for existing buckets.
+func provisionedClaim(obc *v1alpha1.ObjectBucketClaim, ob *v1alpha1.ObjectBucket, isNewBucket bool) *v1alpha1.ObjectBucketClaim {
+	provisioned := obc.DeepCopy()
+	if ob.Spec.StorageClassName != "" {
+		provisioned.Spec.StorageClassName = ob.Spec.StorageClassName
+	}
+	if isNewBucket && obc.Spec.GenerateBucketName == "" && ob.Spec.Connection != nil && ob.Spec.Endpoint != nil {
+		provisioned.Spec.BucketName = ob.Spec.Endpoint.BucketName
+	}
+	return provisioned
+}
+
+// ConnectionValidation selects how suspicious connections returned by the provisioner are handled.
+type ConnectionValidation int
+
+const (
+	// ConnectionValidationWarn logs and records an event for suspicious connections but publishes them. This is the
+	// default.
+	ConnectionValidationWarn ConnectionValidation = iota
+	// ConnectionValidationStrict fails the claim's reconcile on suspicious connections.
+	ConnectionValidationStrict
+)
+
+// well-known ports and whether they are served over TLS
+var wellKnownPorts = map[int]bool{
+	80:  false,
+	443: true,
+}
+
+// ValidateConnection returns an error if the connection's endpoint is suspicious: a BucketHost with an https scheme
+// on a well-known plain-text port, or with an http scheme on a well-known TLS port. Such endpoints are usually
+// provisioner bugs which would otherwise only surface as failing applications. Hosts without a scheme and other
+// ports are not checked.
+func ValidateConnection(conn *v1alpha1.Connection) error {
+	if conn == nil || conn.Endpoint == nil {
+		return nil
+	}
+	ep := conn.Endpoint
+	i := strings.Index(ep.BucketHost, "://")
+	if i <= 0 {
+		return nil
+	}
+	var ssl bool
+	switch strings.ToLower(ep.BucketHost[:i]) {
+	case "https":
+		ssl = true
+	case "http":
+	default:
+		return nil
+	}
+	if tls, ok := wellKnownPorts[ep.BucketPort]; ok && tls != ssl {
+		return fmt.Errorf("bucket host %q does not match well-known port %d", ep.BucketHost, ep.BucketPort)
+	}
+	return nil
+}
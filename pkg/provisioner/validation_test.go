@@ -0,0 +1,142 @@
+/*
+Copyright 2019 Red Hat Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	storagev1 "k8s.io/api/storage/v1"
+
+	"github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+)
+
+func TestValidateUpdate(t *testing.T) {
+	bound := v1alpha1.ObjectBucketClaimSpec{
+		StorageClassName: "class",
+		BucketName:       "bucket",
+		ObjectBucketName: "obc-ns-name",
+	}
+
+	tests := []struct {
+		name    string
+		old     v1alpha1.ObjectBucketClaimSpec
+		mutate  func(*v1alpha1.ObjectBucketClaimSpec)
+		wantErr bool
+	}{
+		{
+			name:   "unchanged",
+			old:    bound,
+			mutate: func(*v1alpha1.ObjectBucketClaimSpec) {},
+		},
+		{
+			name:    "storage class changed",
+			old:     bound,
+			mutate:  func(s *v1alpha1.ObjectBucketClaimSpec) { s.StorageClassName = "other" },
+			wantErr: true,
+		},
+		{
+			name:    "bucket name changed",
+			old:     bound,
+			mutate:  func(s *v1alpha1.ObjectBucketClaimSpec) { s.BucketName = "other" },
+			wantErr: true,
+		},
+		{
+			name: "unbound claim",
+			old: v1alpha1.ObjectBucketClaimSpec{
+				StorageClassName: "class",
+				BucketName:       "bucket",
+			},
+			mutate: func(s *v1alpha1.ObjectBucketClaimSpec) { s.StorageClassName = "other" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldObc := &v1alpha1.ObjectBucketClaim{Spec: tt.old}
+			newObc := oldObc.DeepCopy()
+			tt.mutate(&newObc.Spec)
+			if err := ValidateUpdate(oldObc, newObc); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvisionedClaim(t *testing.T) {
+	obc := &v1alpha1.ObjectBucketClaim{
+		Spec: v1alpha1.ObjectBucketClaimSpec{
+			StorageClassName: "edited-class",
+			BucketName:       "edited-bucket",
+			ObjectBucketName: "obc-ns-name",
+		},
+	}
+	ob := &v1alpha1.ObjectBucket{
+		Spec: v1alpha1.ObjectBucketSpec{
+			StorageClassName: "class",
+			Connection: &v1alpha1.Connection{
+				Endpoint: &v1alpha1.Endpoint{BucketName: "bucket"},
+			},
+		},
+	}
+
+	got := provisionedClaim(obc, ob, true)
+	if got.Spec.StorageClassName != "class" || got.Spec.BucketName != "bucket" {
+		t.Errorf("provisionedClaim() spec = %+v, want the provisioned class and bucket", got.Spec)
+	}
+	if got = provisionedClaim(obc, ob, false); got.Spec.BucketName != "edited-bucket" {
+		t.Errorf("provisionedClaim() bucketName = %q, want the claim's for existing buckets", got.Spec.BucketName)
+	}
+}
+
+func TestValidateConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		ep      *v1alpha1.Endpoint
+		wantErr bool
+	}{
+		{"nil endpoint", nil, false},
+		{"https on 443", &v1alpha1.Endpoint{BucketHost: "https://s3.example.com", BucketPort: 443}, false},
+		{"http on 80", &v1alpha1.Endpoint{BucketHost: "http://s3.example.com", BucketPort: 80}, false},
+		{"https on 80", &v1alpha1.Endpoint{BucketHost: "https://s3.example.com", BucketPort: 80}, true},
+		{"http on 443", &v1alpha1.Endpoint{BucketHost: "HTTP://s3.example.com", BucketPort: 443}, true},
+		{"no scheme", &v1alpha1.Endpoint{BucketHost: "s3.example.com", BucketPort: 80}, false},
+		{"other port", &v1alpha1.Endpoint{BucketHost: "https://s3.example.com", BucketPort: 8080}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConnection(&v1alpha1.Connection{Endpoint: tt.ep})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConnection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestShouldProvision(t *testing.T) {
+	class := &storagev1.StorageClass{Provisioner: provisionerName}
+	if !ShouldProvision(class, provisionerName) {
+		t.Errorf("ShouldProvision() = false for the class's provisioner")
+	}
+	if ShouldProvision(class, "other-provisioner") {
+		t.Errorf("ShouldProvision() = true for another provisioner")
+	}
+	if ShouldProvision(nil, provisionerName) {
+		t.Errorf("ShouldProvision() = true without a class")
+	}
+	if ShouldProvision(&storagev1.StorageClass{}, "") {
+		t.Errorf("ShouldProvision() = true for an empty provisioner name")
+	}
+}